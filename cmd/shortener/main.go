@@ -1,11 +1,8 @@
 package main
 
 import (
-	"fmt"
-
 	"github.com/GevorkovG/go-shortener-tlp/internal/app"
-	"github.com/GevorkovG/go-shortener-tlp/internal/log"
-	"go.uber.org/zap"
+	"github.com/GevorkovG/go-shortener-tlp/internal/buildinfo"
 )
 
 var (
@@ -15,19 +12,7 @@ var (
 )
 
 func main() {
-
-	fmt.Printf("Build version: %s\n", buildVersion)
-	fmt.Printf("Build date: %s\n", buildDate)
-	fmt.Printf("Build commit: %s\n", buildCommit)
-
-	log.InitLogger()     // Инициализация логгера
-	defer zap.L().Sync() // Очистка буферов логгера при завершении программы
-
-	zap.L().Info("Application started",
-		zap.String("version", buildVersion),
-		zap.String("build_date", buildDate),
-		zap.String("commit", buildCommit),
-	)
-
-	app.Run()
+	// Сведения о сборке выводятся в app.Run при старте и по флагу -version;
+	// логгер настраивается там же по загруженной конфигурации
+	app.Run(buildinfo.New(buildVersion, buildDate, buildCommit))
 }
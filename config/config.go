@@ -2,22 +2,12 @@
 package config
 
 import (
-	"encoding/json"
+	"errors"
 	"flag"
-	"log"
+	"fmt"
 	"os"
-
-	"github.com/caarlos0/env"
-)
-
-/*
-const (
-	DBhost     = "localhost"
-	DBuser     = "postgres"
-	DBpassword = "6u8t3d804!"
-	DBdbname   = "videos"
+	"time"
 )
-*/
 
 // AppConfig содержит конфигурационные параметры приложения.
 // Поля структуры:
@@ -26,61 +16,129 @@ const (
 //   - FilePATH: путь к файлу хранилища (env:"FILE_STORAGE_PATH")
 //   - DataBaseString: строка подключения к БД (env:"DATABASE_DSN")
 //   - EnableHTTPS:    включить HTTPS (env:"ENABLE_HTTPS")
+//   - TLSCertFile: PEM-файл сертификата (env:"TLS_CERT_FILE")
+//   - TLSKeyFile: PEM-файл закрытого ключа (env:"TLS_KEY_FILE")
+//   - TLSMinVersion: минимальная версия TLS: 1.0, 1.1, 1.2 или 1.3 (env:"TLS_MIN_VERSION")
+//   - TLSCipherSuites: наборы шифров TLS 1.0–1.2 через запятую (env:"TLS_CIPHER_SUITES")
+//   - TLSSelfSigned: выпустить и кэшировать самоподписанный сертификат (env:"TLS_SELF_SIGNED")
+//   - HTTPRedirectAddr: адрес HTTP-сервера, перенаправляющего на HTTPS (env:"HTTP_REDIRECT_ADDRESS")
+//   - HSTSMaxAge: max-age заголовка Strict-Transport-Security в секундах, 0 — не отправлять (env:"HSTS_MAX_AGE")
+//   - HSTSIncludeSubdomains: директива HSTS includeSubDomains (env:"HSTS_INCLUDE_SUBDOMAINS")
+//   - HSTSPreload: директива HSTS preload (env:"HSTS_PRELOAD")
+//   - ReferrerPolicy: значение заголовка Referrer-Policy (env:"REFERRER_POLICY")
+//   - ContentSecurityPolicy: Content-Security-Policy для HTML-ответов (env:"CONTENT_SECURITY_POLICY")
+//   - CookieSecure: атрибут Secure cookie токена (env:"COOKIE_SECURE"),
+//     при включенном HTTPS выставляется всегда
+//   - CookieHTTPOnly: атрибут HttpOnly cookie токена (env:"COOKIE_HTTP_ONLY")
+//   - CookieSameSite: атрибут SameSite cookie токена: lax, strict или none (env:"COOKIE_SAME_SITE")
+//   - CookieMaxAge: Max-Age cookie токена в секундах, 0 — по времени жизни токена (env:"COOKIE_MAX_AGE")
+//   - CSRFMode: режим защиты от CSRF: off, origin или double-submit (env:"CSRF_MODE")
+//   - CSRFTrustedOrigins: дополнительные доверенные Origin через запятую (env:"CSRF_TRUSTED_ORIGINS")
+//   - AdminUsers: UserID администраторов через запятую (env:"ADMIN_USERS")
+//   - JWTSecret: ключ подписи JWT в cookie, обязателен, не короче 32 байт (env:"JWT_SECRET")
+//   - AuditFile: файл журнала аудита (env:"AUDIT_FILE")
+//   - AuditURL: HTTP-адрес приемника событий аудита (env:"AUDIT_URL")
+//   - WebhookAllowPrivate: разрешить вебхуки на адреса внутренних сетей (env:"WEBHOOK_ALLOW_PRIVATE")
+//   - LogLevel: уровень логирования: debug, info, warn, error (env:"LOG_LEVEL")
+//   - LogFormat: формат лога: json или console (env:"LOG_FORMAT")
+//   - LogFile: файл лога, пусто — stderr (env:"LOG_FILE")
+//   - LogMaxSizeMB: размер файла лога для ротации в мегабайтах (env:"LOG_MAX_SIZE_MB")
+//   - LogMaxBackups: число хранимых ротированных файлов (env:"LOG_MAX_BACKUPS")
+//   - LogMaxAgeDays: срок хранения ротированных файлов в днях (env:"LOG_MAX_AGE_DAYS")
+//   - LogCompress: сжатие ротированных файлов (env:"LOG_COMPRESS")
+//   - LogSampling: ограничение повторяющихся сообщений (env:"LOG_SAMPLING")
+//   - LogHashUserIDs: хэш вместо UserID в логе (env:"LOG_HASH_USER_IDS")
+//   - LogURLs: часть URL в логе: full, path или host (env:"LOG_URLS")
+//   - LogMaxItems: элементов массовой операции в записи лога (env:"LOG_MAX_ITEMS")
+//   - URLMaxLength: максимальная длина сокращаемого URL, 0 — без ограничения (env:"URL_MAX_LENGTH")
+//   - URLDropFragment: удалять фрагмент (#...) из сокращаемых URL (env:"URL_DROP_FRAGMENT")
+//   - URLStripParams: шаблоны удаляемых параметров запроса через запятую (env:"URL_STRIP_PARAMS")
+//   - URLKeepParams: шаблоны параметров, которые не удаляются, через запятую (env:"URL_KEEP_PARAMS")
+//   - URLSortQuery: упорядочивать параметры запроса по имени (env:"URL_SORT_QUERY")
+//   - LinkPolicyFile: JSON-файл правил допустимых адресов, перечитывается при изменении (env:"LINK_POLICY_FILE")
+//   - TrustedSubnet: доверенная подсеть в нотации CIDR для служебного сервера (env:"TRUSTED_SUBNET")
+//   - DiagEnabled: запускать служебный сервер с pprof (env:"DIAG_ENABLED")
+//   - DiagAddr: адрес служебного сервера (env:"DIAG_ADDRESS")
+//   - DiagToken: токен доступа к служебному серверу (env:"DIAG_TOKEN")
+//   - ShutdownDrainDelay: пауза перед остановкой сервера, пока /readyz отвечает 503 (env:"SHUTDOWN_DRAIN_DELAY")
+//   - TraceExporter: экспортер трассировки: otlp, stdout или пусто (env:"TRACE_EXPORTER")
+//   - TraceEndpoint: URL коллектора OTLP/HTTP (env:"OTEL_EXPORTER_OTLP_ENDPOINT")
+//   - TraceSampleRatio: доля трассируемых запросов от 0 до 1 (env:"TRACE_SAMPLE_RATIO")
+//   - ConfigJSON: путь к файлу конфигурации в формате JSON или YAML (env:"CONFIG")
+//
+// Теги flag, default и usage описывают флаг командной строки и значение
+// по умолчанию; тег secret помечает поля, значения которых не выводятся
+// в -print-config; тег reload — поля, которые применяются без перезапуска
+// по SIGHUP.
 type AppConfig struct {
-	Host           string `env:"SERVER_ADDRESS" json:"server_address"`
-	ResultURL      string `env:"BASE_URL" json:"base_url"`
-	FilePATH       string `env:"FILE_STORAGE_PATH" json:"file_storage_path"`
-	DataBaseString string `env:"DATABASE_DSN" json:"database_dsn"`
-	EnableHTTPS    bool   `env:"ENABLE_HTTPS" json:"enable_https"`
-	ConfigJSON     string `env:"CONFIG" json:"-"`
+	Host                  string        `env:"SERVER_ADDRESS" json:"server_address" flag:"a" default:"localhost:8080" usage:"server address"`
+	ResultURL             string        `env:"BASE_URL" json:"base_url" flag:"b" default:"http://localhost:8080" usage:"base URL of short links" reload:"true"`
+	FilePATH              string        `env:"FILE_STORAGE_PATH" json:"file_storage_path" flag:"f" usage:"file storage path"`
+	DataBaseString        string        `env:"DATABASE_DSN" json:"database_dsn" flag:"d" usage:"PostgreSQL connection string" secret:"dsn"`
+	EnableHTTPS           bool          `env:"ENABLE_HTTPS" json:"enable_https" flag:"s" usage:"serve HTTPS"`
+	TLSCertFile           string        `env:"TLS_CERT_FILE" json:"tls_cert_file" flag:"tls-cert" default:"./certs/cert.pem" usage:"TLS certificate file (PEM), reloaded on change"`
+	TLSKeyFile            string        `env:"TLS_KEY_FILE" json:"tls_key_file" flag:"tls-key" default:"./certs/key.pem" usage:"TLS private key file (PEM), reloaded on change"`
+	TLSMinVersion         string        `env:"TLS_MIN_VERSION" json:"tls_min_version" flag:"tls-min-version" default:"1.2" usage:"minimum TLS version: 1.0, 1.1, 1.2 or 1.3"`
+	TLSCipherSuites       string        `env:"TLS_CIPHER_SUITES" json:"tls_cipher_suites" flag:"tls-ciphers" usage:"comma separated TLS 1.0-1.2 cipher suites, empty means Go defaults"`
+	TLSSelfSigned         bool          `env:"TLS_SELF_SIGNED" json:"tls_self_signed" flag:"tls-self-signed" usage:"generate and cache a self-signed certificate if the files are missing"`
+	HTTPRedirectAddr      string        `env:"HTTP_REDIRECT_ADDRESS" json:"http_redirect_address" flag:"http-redirect" usage:"address of a plain HTTP listener redirecting to HTTPS, empty disables it"`
+	HSTSMaxAge            int           `env:"HSTS_MAX_AGE" json:"hsts_max_age" flag:"hsts-max-age" default:"31536000" usage:"Strict-Transport-Security max-age in seconds for HTTPS responses, 0 disables the header"`
+	HSTSIncludeSubdomains bool          `env:"HSTS_INCLUDE_SUBDOMAINS" json:"hsts_include_subdomains" flag:"hsts-include-subdomains" usage:"add includeSubDomains to Strict-Transport-Security"`
+	HSTSPreload           bool          `env:"HSTS_PRELOAD" json:"hsts_preload" flag:"hsts-preload" usage:"add preload to Strict-Transport-Security"`
+	ReferrerPolicy        string        `env:"REFERRER_POLICY" json:"referrer_policy" flag:"referrer-policy" default:"strict-origin-when-cross-origin" usage:"Referrer-Policy header value, empty disables the header"`
+	ContentSecurityPolicy string        `env:"CONTENT_SECURITY_POLICY" json:"content_security_policy" flag:"csp" default:"default-src 'none'; frame-ancestors 'none'" usage:"Content-Security-Policy for HTML responses, empty disables the header"`
+	CookieSecure          bool          `env:"COOKIE_SECURE" json:"cookie_secure" flag:"cookie-secure" usage:"set Secure attribute on the token cookie"`
+	CookieHTTPOnly        bool          `env:"COOKIE_HTTP_ONLY" json:"cookie_http_only" flag:"cookie-http-only" default:"true" usage:"set HttpOnly attribute on the token cookie"`
+	CookieSameSite        string        `env:"COOKIE_SAME_SITE" json:"cookie_same_site" flag:"cookie-same-site" default:"lax" usage:"SameSite attribute of the token cookie: lax, strict or none"`
+	CookieMaxAge          int           `env:"COOKIE_MAX_AGE" json:"cookie_max_age" flag:"cookie-max-age" usage:"Max-Age of the token cookie in seconds, 0 means token lifetime"`
+	CSRFMode              string        `env:"CSRF_MODE" json:"csrf_mode" flag:"csrf-mode" default:"origin" usage:"CSRF protection mode: off, origin or double-submit"`
+	CSRFTrustedOrigins    string        `env:"CSRF_TRUSTED_ORIGINS" json:"csrf_trusted_origins" flag:"csrf-origins" usage:"comma separated list of additional trusted origins"`
+	AdminUsers            string        `env:"ADMIN_USERS" json:"admin_users" flag:"admin-users" usage:"comma separated list of user IDs with the admin role" reload:"true"`
+	JWTSecret             string        `env:"JWT_SECRET" json:"jwt_secret" flag:"jwt-secret" usage:"key signing the token cookies (JWT), at least 32 bytes" secret:"token"`
+	AuditFile             string        `env:"AUDIT_FILE" json:"audit_file" flag:"audit-file" usage:"append-only audit log file"`
+	AuditURL              string        `env:"AUDIT_URL" json:"audit_url" flag:"audit-url" usage:"HTTP endpoint receiving audit events" secret:"url"`
+	WebhookAllowPrivate   bool          `env:"WEBHOOK_ALLOW_PRIVATE" json:"webhook_allow_private" flag:"webhook-allow-private" usage:"allow webhooks to loopback, private and link-local addresses"`
+	LogLevel              string        `env:"LOG_LEVEL" json:"log_level" flag:"log-level" default:"info" usage:"log level: debug, info, warn or error" reload:"true"`
+	LogFormat             string        `env:"LOG_FORMAT" json:"log_format" flag:"log-format" default:"json" usage:"log encoding: json or console"`
+	LogFile               string        `env:"LOG_FILE" json:"log_file" flag:"log-file" usage:"log file path, empty means stderr"`
+	LogMaxSizeMB          int           `env:"LOG_MAX_SIZE_MB" json:"log_max_size_mb" flag:"log-max-size" default:"100" usage:"size in megabytes at which the log file is rotated"`
+	LogMaxBackups         int           `env:"LOG_MAX_BACKUPS" json:"log_max_backups" flag:"log-max-backups" default:"7" usage:"rotated log files to keep, 0 keeps all"`
+	LogMaxAgeDays         int           `env:"LOG_MAX_AGE_DAYS" json:"log_max_age_days" flag:"log-max-age" default:"30" usage:"days to keep rotated log files, 0 keeps them forever"`
+	LogCompress           bool          `env:"LOG_COMPRESS" json:"log_compress" flag:"log-compress" usage:"gzip rotated log files"`
+	LogSampling           bool          `env:"LOG_SAMPLING" json:"log_sampling" flag:"log-sampling" default:"true" usage:"drop repeated log messages above 100 per second"`
+	LogHashUserIDs        bool          `env:"LOG_HASH_USER_IDS" json:"log_hash_user_ids" flag:"log-hash-user-ids" default:"true" usage:"log a hash instead of the user ID" reload:"true"`
+	LogURLs               string        `env:"LOG_URLS" json:"log_urls" flag:"log-urls" default:"path" usage:"how much of a URL to log: full, path (no query string) or host" reload:"true"`
+	LogMaxItems           int           `env:"LOG_MAX_ITEMS" json:"log_max_items" flag:"log-max-items" default:"10" usage:"items of a bulk operation listed in one log entry" reload:"true"`
+	URLMaxLength          int           `env:"URL_MAX_LENGTH" json:"url_max_length" flag:"url-max-length" default:"2048" usage:"maximum length of a URL to shorten in bytes, 0 disables the limit" reload:"true"`
+	URLDropFragment       bool          `env:"URL_DROP_FRAGMENT" json:"url_drop_fragment" flag:"url-drop-fragment" usage:"remove the #fragment from URLs before shortening" reload:"true"`
+	URLStripParams        string        `env:"URL_STRIP_PARAMS" json:"url_strip_params" flag:"url-strip-params" default:"utm_*,fbclid,gclid,dclid,msclkid,yclid,mc_cid,mc_eid,_hsenc,_hsmi" usage:"comma separated query parameter names or patterns (utm_*) removed before storing a URL" reload:"true"`
+	URLKeepParams         string        `env:"URL_KEEP_PARAMS" json:"url_keep_params" flag:"url-keep-params" usage:"comma separated query parameter names or patterns kept even if they match url-strip-params" reload:"true"`
+	URLSortQuery          bool          `env:"URL_SORT_QUERY" json:"url_sort_query" flag:"url-sort-query" usage:"sort query parameters by name before storing a URL" reload:"true"`
+	LinkPolicyFile        string        `env:"LINK_POLICY_FILE" json:"link_policy_file" flag:"link-policy" usage:"JSON file with domain allow/deny rules for shortened URLs, reloaded when it changes"`
+	TrustedSubnet         string        `env:"TRUSTED_SUBNET" json:"trusted_subnet" flag:"t" usage:"trusted subnet (CIDR) allowed to call the diagnostics listener" reload:"true"`
+	DiagEnabled           bool          `env:"DIAG_ENABLED" json:"diag_enabled" flag:"diag" default:"true" usage:"serve pprof and other operator endpoints on a separate listener"`
+	DiagAddr              string        `env:"DIAG_ADDRESS" json:"diag_address" flag:"diag-addr" default:"localhost:6060" usage:"address of the operator listener"`
+	DiagToken             string        `env:"DIAG_TOKEN" json:"diag_token" flag:"diag-token" usage:"bearer token required by the operator listener" secret:"token" reload:"true"`
+	ShutdownDrainDelay    time.Duration `env:"SHUTDOWN_DRAIN_DELAY" json:"shutdown_drain_delay" flag:"shutdown-drain-delay" default:"0s" usage:"time /readyz reports not ready before the server stops accepting connections"`
+	TraceExporter         string        `env:"TRACE_EXPORTER" json:"trace_exporter" flag:"trace-exporter" usage:"span exporter: otlp, stdout or empty to disable tracing"`
+	TraceEndpoint         string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT" json:"trace_endpoint" flag:"trace-endpoint" default:"http://localhost:4318" usage:"OTLP/HTTP collector URL"`
+	TraceSampleRatio      float64       `env:"TRACE_SAMPLE_RATIO" json:"trace_sample_ratio" flag:"trace-sample-ratio" default:"1" usage:"fraction of new traces to sample, from 0 to 1"`
+	ConfigJSON            string        `env:"CONFIG" json:"-"`
+
+	// PrintConfig — вывести итоговую конфигурацию и завершить работу (флаг -print-config)
+	PrintConfig bool `json:"-"`
+
+	// PrintVersion — вывести сведения о сборке и завершить работу (флаг -version)
+	PrintVersion bool `json:"-"`
+
+	sources map[string]string // имя поля (json) -> источник значения
 }
 
-// loadConfigFromFile загружает конфигурацию приложения из файла.
-func (a *AppConfig) loadConfigFromFile() error {
-	if a.ConfigJSON == "" {
-		return nil
-	}
-
-	data, err := os.ReadFile(a.ConfigJSON)
-	if err != nil {
-		return err
-	}
-
-	var fileConfig AppConfig
-	if err := json.Unmarshal(data, &fileConfig); err != nil {
-		return err
-	}
-
-	if a.Host == defaultServerAddress && fileConfig.Host != "" {
-		a.Host = fileConfig.Host
-	}
-	if a.ResultURL == defaultBaseURL && fileConfig.ResultURL != "" {
-		a.ResultURL = fileConfig.ResultURL
-	}
-	if a.FilePATH == "" && fileConfig.FilePATH != "" {
-		a.FilePATH = fileConfig.FilePATH
-	}
-	if a.DataBaseString == "" && fileConfig.DataBaseString != "" {
-		a.DataBaseString = fileConfig.DataBaseString
-	}
-	if !a.EnableHTTPS && fileConfig.EnableHTTPS {
-		a.EnableHTTPS = fileConfig.EnableHTTPS
-	}
-
-	return nil
-}
-
-const (
-	defaultServerAddress = "localhost:8080"
-
-	defaultBaseURL = "http://localhost:8080"
-)
-
 // NewCfg создает и инициализирует конфигурацию приложения.
 // Приоритеты источников конфигурации (от высшего к низшему):
 // 1. Флаги командной строки
 // 2. Переменные окружения
-// 3. JSON-файл конфигурации
+// 3. Файл конфигурации в формате JSON или YAML (флаг -c или CONFIG)
 // 4. Значения по умолчанию
 //
 // Поддерживаемые параметры конфигурации:
@@ -88,33 +146,77 @@ const (
 //   - ResultURL (флаг -b) - базовый URL для результатов (по умолчанию "http://localhost:8080")
 //   - FilePATH (флаг -f) - путь к файлу хранилища (по умолчанию "")
 //   - DataBaseString (флаг -d) - строка подключения к БД (по умолчанию "")
-//   - EnableHTTPS (флаг -s) - включить HTTPS (по умолчанию "")
+//   - EnableHTTPS (флаг -s) - включить HTTPS (по умолчанию false)
+//   - TLSCertFile (флаг -tls-cert) - файл сертификата (по умолчанию "./certs/cert.pem")
+//   - TLSKeyFile (флаг -tls-key) - файл ключа (по умолчанию "./certs/key.pem")
+//   - TLSMinVersion (флаг -tls-min-version) - минимальная версия TLS (по умолчанию "1.2")
+//   - TLSCipherSuites (флаг -tls-ciphers) - наборы шифров (по умолчанию набор Go)
+//   - TLSSelfSigned (флаг -tls-self-signed) - самоподписанный сертификат (по умолчанию false)
+//   - HTTPRedirectAddr (флаг -http-redirect) - адрес HTTP->HTTPS перенаправления (по умолчанию "")
+//   - HSTSMaxAge (флаг -hsts-max-age) - max-age HSTS в секундах (по умолчанию 31536000)
+//   - HSTSIncludeSubdomains (флаг -hsts-include-subdomains) - includeSubDomains (по умолчанию false)
+//   - HSTSPreload (флаг -hsts-preload) - preload (по умолчанию false)
+//   - ReferrerPolicy (флаг -referrer-policy) - Referrer-Policy (по умолчанию "strict-origin-when-cross-origin")
+//   - ContentSecurityPolicy (флаг -csp) - CSP для HTML (по умолчанию "default-src 'none'; frame-ancestors 'none'")
+//   - CookieSecure (флаг -cookie-secure) - атрибут Secure cookie (по умолчанию false)
+//   - CookieHTTPOnly (флаг -cookie-http-only) - атрибут HttpOnly cookie (по умолчанию true)
+//   - CookieSameSite (флаг -cookie-same-site) - атрибут SameSite cookie (по умолчанию "lax")
+//   - CookieMaxAge (флаг -cookie-max-age) - Max-Age cookie в секундах (по умолчанию 0)
+//   - CSRFMode (флаг -csrf-mode) - режим защиты от CSRF (по умолчанию "origin")
+//   - CSRFTrustedOrigins (флаг -csrf-origins) - доверенные Origin (по умолчанию "")
+//   - AdminUsers (флаг -admin-users) - UserID администраторов (по умолчанию "")
+//   - JWTSecret (флаг -jwt-secret) - ключ подписи JWT (обязателен)
+//   - AuditFile (флаг -audit-file) - файл журнала аудита (по умолчанию "")
+//   - AuditURL (флаг -audit-url) - HTTP-приемник событий аудита (по умолчанию "")
+//   - WebhookAllowPrivate (флаг -webhook-allow-private) - вебхуки во внутренние сети (по умолчанию false)
+//   - LogLevel (флаг -log-level) - уровень логирования (по умолчанию "info")
+//   - LogFormat (флаг -log-format) - формат лога (по умолчанию "json")
+//   - LogFile (флаг -log-file) - файл лога (по умолчанию "", stderr)
+//   - LogMaxSizeMB (флаг -log-max-size) - размер файла для ротации (по умолчанию 100)
+//   - LogMaxBackups (флаг -log-max-backups) - ротированных файлов (по умолчанию 7)
+//   - LogMaxAgeDays (флаг -log-max-age) - срок хранения в днях (по умолчанию 30)
+//   - LogCompress (флаг -log-compress) - сжатие ротированных файлов (по умолчанию false)
+//   - LogSampling (флаг -log-sampling) - сэмплирование сообщений (по умолчанию true)
+//   - LogHashUserIDs (флаг -log-hash-user-ids) - хэш вместо UserID (по умолчанию true)
+//   - LogURLs (флаг -log-urls) - часть URL в логе (по умолчанию "path")
+//   - LogMaxItems (флаг -log-max-items) - элементов массовой операции (по умолчанию 10)
+//   - URLMaxLength (флаг -url-max-length) - максимальная длина URL (по умолчанию 2048)
+//   - URLDropFragment (флаг -url-drop-fragment) - удаление фрагмента URL (по умолчанию false)
+//   - URLStripParams (флаг -url-strip-params) - удаляемые параметры запроса (по умолчанию utm_*, fbclid, gclid и другие)
+//   - URLKeepParams (флаг -url-keep-params) - сохраняемые параметры запроса (по умолчанию "")
+//   - URLSortQuery (флаг -url-sort-query) - упорядочивание параметров запроса (по умолчанию false)
+//   - LinkPolicyFile (флаг -link-policy) - файл правил допустимых адресов (по умолчанию "")
+//   - TrustedSubnet (флаг -t) - доверенная подсеть CIDR (по умолчанию "")
+//   - DiagEnabled (флаг -diag) - служебный сервер (по умолчанию true)
+//   - DiagAddr (флаг -diag-addr) - адрес служебного сервера (по умолчанию "localhost:6060")
+//   - DiagToken (флаг -diag-token) - токен служебного сервера (по умолчанию "")
+//   - ShutdownDrainDelay (флаг -shutdown-drain-delay) - пауза для вывода из балансировки (по умолчанию 0s)
+//   - TraceExporter (флаг -trace-exporter) - экспортер трассировки (по умолчанию "")
+//   - TraceEndpoint (флаг -trace-endpoint) - коллектор OTLP (по умолчанию "http://localhost:4318")
+//   - TraceSampleRatio (флаг -trace-sample-ratio) - доля трассировок (по умолчанию 1)
+//
+// Особенности:
+//   - При ошибке загрузки или проверки печатает все найденные ошибки
+//     и завершает процесс с кодом 2
+//   - С флагом -print-config печатает итоговые значения с источниками
+//     и завершает процесс с кодом 0
 func NewCfg() *AppConfig {
-
-	a := AppConfig{}
-
-	a.Host = defaultServerAddress
-	a.ResultURL = defaultBaseURL
-
-	flag.StringVar(&a.Host, "a", defaultServerAddress, "It's a Host")
-	flag.StringVar(&a.ResultURL, "b", defaultBaseURL, "It's a Result URL")
-	flag.StringVar(&a.FilePATH, "f", "", "It's a FilePATH")
-	flag.StringVar(&a.DataBaseString, "d", "", "it's conn string")
-	flag.BoolVar(&a.EnableHTTPS, "s", false, "using HTTPS")
-	flag.StringVar(&a.ConfigJSON, "c", "", "It's a ConfigJSON file")
-
-	flag.Parse()
-
-	err := env.Parse(&a)
+	a, err := Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
 	}
 
-	if a.ConfigJSON != "" {
-		if err := a.loadConfigFromFile(); err != nil {
-			log.Printf("Warning: failed to load config file: %v", err)
+	if a.PrintConfig {
+		if err := a.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		os.Exit(0)
 	}
 
-	return &a
+	return a
 }
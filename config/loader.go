@@ -0,0 +1,309 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Слои конфигурации в порядке применения
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// field описывает одно поле AppConfig, загружаемое из слоев
+type field struct {
+	name   string // ключ в файле конфигурации (тег json)
+	env    string // переменная окружения
+	flag   string // флаг командной строки
+	def    string // значение по умолчанию
+	usage  string // описание флага
+	secret string // способ сокрытия значения в -print-config
+	reload bool   // применяется без перезапуска
+	value  reflect.Value
+}
+
+// fields возвращает описания полей, участвующих в послойной загрузке
+func (a *AppConfig) fields() []field {
+	v := reflect.ValueOf(a).Elem()
+	t := v.Type()
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name := sf.Tag.Get("json")
+		if !sf.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, field{
+			name:   name,
+			env:    sf.Tag.Get("env"),
+			flag:   sf.Tag.Get("flag"),
+			def:    sf.Tag.Get("default"),
+			usage:  sf.Tag.Get("usage"),
+			secret: sf.Tag.Get("secret"),
+			reload: sf.Tag.Get("reload") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return fields
+}
+
+// set разбирает строковое значение и записывает его в поле
+func (f field) set(raw string) error {
+	switch f.value.Interface().(type) {
+	case string:
+		f.value.SetString(raw)
+	case bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false")
+		}
+		f.value.SetBool(b)
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as 30s or 5m")
+		}
+		f.value.SetInt(int64(d))
+	case int, int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected an integer")
+		}
+		f.value.SetInt(n)
+	case float64:
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number")
+		}
+		f.value.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type %s", f.value.Type())
+	}
+	return nil
+}
+
+// isBool сообщает, является ли поле логическим (флаг без значения)
+func (f field) isBool() bool {
+	return f.value.Kind() == reflect.Bool
+}
+
+// rawFlag сохраняет значение флага как строку; разбор выполняется
+// общим для всех слоев кодом field.set
+type rawFlag struct {
+	value  string
+	isBool bool
+}
+
+func (r *rawFlag) String() string     { return r.value }
+func (r *rawFlag) Set(s string) error { r.value = s; return nil }
+func (r *rawFlag) IsBoolFlag() bool   { return r.isBool }
+
+// Load загружает конфигурацию послойно: значения по умолчанию, затем файл
+// конфигурации (JSON или YAML), затем переменные окружения, затем флаги.
+// Каждый следующий слой переопределяет предыдущий, в том числе значением,
+// совпадающим со значением по умолчанию.
+//
+// Параметры:
+//   - args: аргументы командной строки без имени программы
+//   - lookupEnv: функция чтения окружения (обычно os.LookupEnv)
+//
+// Возвращает:
+//   - *AppConfig: итоговая конфигурация с информацией об источниках значений
+//   - error: все ошибки разбора и проверки, объединенные через errors.Join;
+//     flag.ErrHelp, если запрошена справка
+//
+// Особенности:
+//   - Формат файла определяется по расширению: .yaml и .yml — YAML, иначе JSON
+//   - Неизвестные ключи в файле считаются ошибкой
+//   - Пустые переменные окружения игнорируются
+//   - С флагом -version возвращается конфигурация со значениями по
+//     умолчанию без проверки: для вывода версии она не используется
+func Load(args []string, lookupEnv func(string) (string, bool)) (*AppConfig, error) {
+	a := &AppConfig{sources: make(map[string]string)}
+	fields := a.fields()
+	var errs []error
+
+	// Слой 1: значения по умолчанию
+	for _, f := range fields {
+		if err := f.set(f.def); err != nil && f.def != "" {
+			errs = append(errs, fmt.Errorf("%s: bad default %q: %w", f.name, f.def, err))
+		}
+		a.sources[f.name] = SourceDefault
+	}
+
+	// Флаги разбираются сразу, чтобы узнать путь к файлу, но применяются последними
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	flags := make(map[string]*rawFlag, len(fields))
+	for _, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		rf := &rawFlag{value: f.def, isBool: f.isBool()}
+		flags[f.flag] = rf
+		fs.Var(rf, f.flag, f.usage)
+	}
+	fs.StringVar(&a.ConfigJSON, "c", "", "configuration file (JSON or YAML)")
+	fs.BoolVar(&a.PrintConfig, "print-config", false, "print effective configuration with value sources and exit")
+	fs.BoolVar(&a.PrintVersion, "version", false, "print build information and exit")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	// Для вывода версии остальная конфигурация не нужна и не проверяется
+	if a.PrintVersion {
+		return a, nil
+	}
+
+	configSet := false
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == "c" {
+			configSet = true
+		}
+	})
+	if !configSet {
+		if path, ok := lookupEnv("CONFIG"); ok && path != "" {
+			a.ConfigJSON = path
+		}
+	}
+
+	// Слой 2: файл конфигурации
+	if a.ConfigJSON != "" {
+		values, err := readConfigFile(a.ConfigJSON)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %w", a.ConfigJSON, err))
+		}
+		byName := make(map[string]field, len(fields))
+		for _, f := range fields {
+			byName[f.name] = f
+		}
+		keys := make([]string, 0, len(values))
+		for k := range values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			f, ok := byName[k]
+			if !ok {
+				errs = append(errs, fmt.Errorf("config file %s: unknown key %q", a.ConfigJSON, k))
+				continue
+			}
+			if err := f.set(values[k]); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid value %q in config file %s: %w", k, values[k], a.ConfigJSON, err))
+				continue
+			}
+			a.sources[f.name] = SourceFile + " " + a.ConfigJSON
+		}
+	}
+
+	// Слой 3: переменные окружения
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		raw, ok := lookupEnv(f.env)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := f.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q in env %s: %w", f.name, raw, f.env, err))
+			continue
+		}
+		a.sources[f.name] = SourceEnv + " " + f.env
+	}
+
+	// Слой 4: флаги командной строки
+	byFlag := make(map[string]field, len(fields))
+	for _, f := range fields {
+		if f.flag != "" {
+			byFlag[f.flag] = f
+		}
+	}
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := byFlag[fl.Name]
+		if !ok {
+			return
+		}
+		raw := flags[fl.Name].value
+		if err := f.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q in flag -%s: %w", f.name, raw, fl.Name, err))
+			return
+		}
+		a.sources[f.name] = SourceFlag + " -" + fl.Name
+	})
+
+	if len(errs) == 0 {
+		errs = append(errs, a.Validate())
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// readConfigFile читает файл конфигурации и приводит значения к строкам
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+	}
+
+	values := make(map[string]string, len(raw))
+	for k, v := range raw {
+		switch val := v.(type) {
+		case nil:
+			values[k] = ""
+		case map[string]any:
+			return nil, fmt.Errorf("key %q: nested objects are not supported", k)
+		case []any:
+			items := make([]string, len(val))
+			for i, item := range val {
+				items[i] = fmt.Sprint(item)
+			}
+			values[k] = strings.Join(items, ",")
+		default:
+			values[k] = fmt.Sprint(val)
+		}
+	}
+	return values, nil
+}
+
+// Source возвращает источник значения поля: "default", "file <путь>",
+// "env <переменная>" или "flag -<имя>".
+//
+// Параметры:
+//   - name: имя поля, как в файле конфигурации (например, "base_url")
+func (a *AppConfig) Source(name string) string {
+	if a.sources == nil {
+		return ""
+	}
+	return a.sources[name]
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"text/tabwriter"
+)
+
+// redacted заменяет секретную часть значения в выводе конфигурации
+const redacted = "xxxxx"
+
+// passwordParam находит пароль в строке подключения формата key=value
+var passwordParam = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// Print выводит итоговое значение каждого поля и слой, из которого оно получено.
+// Пароли в строках подключения и URL, а также токены заменяются на "xxxxx".
+//
+// Параметры:
+//   - w: получатель вывода
+//
+// Пример вывода:
+//
+//	FIELD           VALUE                                      SOURCE
+//	server_address  :8080                                      flag -a
+//	database_dsn    postgres://app:xxxxx@db:5432/shortener     env DATABASE_DSN
+func (a *AppConfig) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tVALUE\tSOURCE")
+	for _, f := range a.fields() {
+		value := fmt.Sprint(f.value.Interface())
+		switch f.secret {
+		case "":
+		case "token":
+			if value != "" {
+				value = redacted
+			}
+		default:
+			value = redactCredentials(value)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.name, value, a.Source(f.name))
+	}
+	return tw.Flush()
+}
+
+// redactCredentials скрывает пароль в URL (user:password@host и параметр
+// password) и в строке подключения формата key=value
+func redactCredentials(s string) string {
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.Host != "" {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", redacted)
+			u.RawQuery = q.Encode()
+		}
+		return u.String()
+	}
+	return passwordParam.ReplaceAllString(s, "${1}"+redacted)
+}
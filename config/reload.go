@@ -0,0 +1,42 @@
+package config
+
+import (
+	"maps"
+	"reflect"
+)
+
+// ApplyReload переносит в копию текущей конфигурации значения полей,
+// которые можно менять без перезапуска (тег reload).
+//
+// Параметры:
+//   - next: заново загруженная и проверенная конфигурация
+//
+// Возвращает:
+//   - *AppConfig: новая конфигурация; исходная не изменяется
+//   - []string: имена примененных полей
+//   - []string: имена измененных полей, требующих перезапуска (их значения
+//     в новой конфигурации остаются прежними)
+func (a *AppConfig) ApplyReload(next *AppConfig) (*AppConfig, []string, []string) {
+	merged := *a
+	merged.sources = maps.Clone(a.sources)
+	if merged.sources == nil {
+		merged.sources = make(map[string]string)
+	}
+
+	nextFields := next.fields()
+	var applied, restart []string
+	for i, f := range merged.fields() {
+		nf := nextFields[i]
+		if reflect.DeepEqual(f.value.Interface(), nf.value.Interface()) {
+			continue
+		}
+		if !f.reload {
+			restart = append(restart, f.name)
+			continue
+		}
+		f.value.Set(nf.value)
+		merged.sources[f.name] = next.Source(f.name)
+		applied = append(applied, f.name)
+	}
+	return &merged, applied, restart
+}
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// defaultAdminPageSize - количество ссылок в ответе администратору по умолчанию
+const defaultAdminPageSize = 100
+
+// AdminLink представляет ссылку в ответах административного API.
+type AdminLink struct {
+	Short    string `json:"short_url"`
+	Original string `json:"original_url"`
+	UserID   string `json:"user_id"`
+	Deleted  bool   `json:"deleted"`
+	Disabled bool   `json:"disabled"`
+}
+
+// adminStorage возвращает хранилище административных операций, если бэкенд его поддерживает.
+func (a *App) adminStorage() (objects.AdminStorage, bool) {
+	admin, ok := a.Storage.(objects.AdminStorage)
+	return admin, ok
+}
+
+// AdminListURLs возвращает ссылки всех пользователей с поиском и постраничной выдачей.
+// Эндпоинт: GET /api/admin/urls
+//
+// Параметры запроса:
+//   - q: подстрока для поиска в коротком или оригинальном URL
+//   - user_id: только ссылки указанного пользователя
+//   - limit: размер страницы (по умолчанию 100)
+//   - offset: смещение
+//
+// Возможные ответы:
+//   - 200 OK: массив AdminLink
+//   - 400 Bad Request: некорректные limit или offset
+//   - 403 Forbidden: пользователь не администратор (см. cookies.RequireRole)
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) AdminListURLs(w http.ResponseWriter, r *http.Request) {
+	admin, ok := a.adminStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	filter := objects.LinkFilter{
+		Query:  strings.TrimSpace(r.URL.Query().Get("q")),
+		UserID: strings.TrimSpace(r.URL.Query().Get("user_id")),
+		Limit:  defaultAdminPageSize,
+	}
+
+	var err error
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil || filter.Offset < 0 {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "offset must be a non-negative integer")
+			return
+		}
+	}
+
+	links, err := admin.ListLinks(r.Context(), filter)
+	if err != nil {
+		problem.Internal(w, r, "Failed to list links", err)
+		return
+	}
+
+	resp := make([]AdminLink, 0, len(links))
+	for _, l := range links {
+		resp = append(resp, AdminLink{
+			Short:    strings.TrimSpace(a.GetConfig().ResultURL + "/" + l.Short),
+			Original: strings.TrimSpace(l.Original),
+			UserID:   l.UserID,
+			Deleted:  l.DeletedFlag || l.Original == "",
+			Disabled: l.Disabled,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// setLinkDisabled общая часть AdminDisableURL и AdminRestoreURL
+func (a *App) setLinkDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	admin, ok := a.adminStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := admin.SetLinkDisabled(r.Context(), id, disabled); err != nil {
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "")
+			return
+		}
+		problem.Internal(w, r, "Failed to update link", err, zap.String("id", id))
+		return
+	}
+
+	logg.FromContext(r.Context()).Info("Link state changed by admin",
+		zap.String("id", id),
+		zap.Bool("disabled", disabled))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminDisableURL отключает любую ссылку: переход по ней возвращает 410 Gone.
+// Эндпоинт: POST /api/admin/urls/{id}/disable
+//
+// Возможные ответы:
+//   - 204 No Content: ссылка отключена
+//   - 404 Not Found: ссылка не найдена
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) AdminDisableURL(w http.ResponseWriter, r *http.Request) {
+	a.setLinkDisabled(w, r, true)
+}
+
+// AdminRestoreURL восстанавливает ссылку, отключенную администратором.
+// Эндпоинт: POST /api/admin/urls/{id}/restore
+//
+// Возможные ответы:
+//   - 204 No Content: ссылка восстановлена
+//   - 404 Not Found: ссылка не найдена
+//   - 500 Internal Server Error: ошибка хранилища
+//
+// Особенности:
+//   - Ссылки, удаленные самим пользователем, не восстанавливаются
+func (a *App) AdminRestoreURL(w http.ResponseWriter, r *http.Request) {
+	a.setLinkDisabled(w, r, false)
+}
+
+// AdminListUsers возвращает пользователей и количество их ссылок.
+// Эндпоинт: GET /api/admin/users
+//
+// Возможные ответы:
+//   - 200 OK: массив objects.UserStat
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) AdminListUsers(w http.ResponseWriter, r *http.Request) {
+	admin, ok := a.adminStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	users, err := admin.ListUsers(r.Context())
+	if err != nil {
+		problem.Internal(w, r, "Failed to list users", err)
+		return
+	}
+	if users == nil {
+		users = []objects.UserStat{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
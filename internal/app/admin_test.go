@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminAPI(t *testing.T) {
+	app := NewApp(&config.AppConfig{
+		Host:      "localhost:8080",
+		ResultURL: "http://localhost:8080",
+	})
+
+	for _, l := range []*objects.Link{
+		{Short: "adm1", Original: "https://example.com/a", UserID: "user1"},
+		{Short: "adm2", Original: "https://example.com/b", UserID: "user1"},
+		{Short: "adm3", Original: "https://other.org/c", UserID: "user2"},
+	} {
+		require.NoError(t, app.Storage.Insert(context.Background(), l))
+	}
+
+	r := chi.NewRouter()
+	r.Use(cookies.NewAuth(cookies.AuthConfig{Admins: []string{"admin-1"}}).Middleware)
+	r.Get("/{id}", app.GetOriginalURL)
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(cookies.RequireRole(cookies.RoleAdmin))
+		r.Get("/urls", app.AdminListURLs)
+		r.Post("/urls/{id}/disable", app.AdminDisableURL)
+		r.Post("/urls/{id}/restore", app.AdminRestoreURL)
+		r.Get("/users", app.AdminListUsers)
+	})
+
+	adminToken, err := cookies.BuildRoleJWTString("admin-1", cookies.RoleAdmin)
+	require.NoError(t, err)
+	userToken, err := cookies.BuildJWTString("user1")
+	require.NoError(t, err)
+	forgedToken, err := cookies.BuildRoleJWTString("user1", cookies.RoleAdmin)
+	require.NoError(t, err)
+
+	do := func(method, target, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, nil)
+		req.AddCookie(&http.Cookie{Name: cookies.TokenCookieName, Value: token})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("normal user is forbidden", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/admin/urls", userToken)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `"code":"forbidden"`)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/admin/urls/adm1/disable", userToken).Code)
+	})
+
+	t.Run("role not in config is dropped", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/admin/users", forgedToken).Code)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/admin/urls?q=example.com&limit=1&offset=1", adminToken)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var links []AdminLink
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&links))
+		require.Len(t, links, 1)
+		assert.Equal(t, "http://localhost:8080/adm2", links[0].Short)
+		assert.Equal(t, "user1", links[0].UserID)
+	})
+
+	t.Run("disable and restore", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, do(http.MethodPost, "/api/admin/urls/adm3/disable", adminToken).Code)
+		assert.Equal(t, http.StatusGone, do(http.MethodGet, "/adm3", userToken).Code)
+
+		require.Equal(t, http.StatusNoContent, do(http.MethodPost, "/api/admin/urls/adm3/restore", adminToken).Code)
+		assert.Equal(t, http.StatusTemporaryRedirect, do(http.MethodGet, "/adm3", userToken).Code)
+
+		assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/api/admin/urls/missing/disable", adminToken).Code)
+	})
+
+	t.Run("users", func(t *testing.T) {
+		w := do(http.MethodGet, "/api/admin/users", adminToken)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var users []objects.UserStat
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&users))
+		assert.Equal(t, []objects.UserStat{{UserID: "user1", Links: 2}, {UserID: "user2", Links: 1}}, users)
+	})
+}
@@ -0,0 +1,215 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/services/apikey"
+	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ReqAPIKey представляет запрос на создание API-ключа.
+//
+// Поля:
+//   - Name string `json:"name"`: произвольное имя ключа (необязательно)
+//   - Role string `json:"role"`: роль ключа (необязательно, по умолчанию — роль
+//     создателя; роль выше собственной назначить нельзя)
+type ReqAPIKey struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// RespAPIKey представляет API-ключ в ответах сервера.
+// Поле Key заполняется только в ответе на создание ключа —
+// в открытом виде ключ показывается один раз.
+type RespAPIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Key       string    `json:"key,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Revoked   bool      `json:"revoked"`
+	Role      string    `json:"role"`
+}
+
+// apiKeys возвращает хранилище API-ключей, если бэкенд его поддерживает.
+func (a *App) apiKeys() (objects.APIKeyStorage, bool) {
+	keys, ok := a.Storage.(objects.APIKeyStorage)
+	return keys, ok
+}
+
+// APICreateKey создает новый API-ключ для текущего пользователя.
+// Эндпоинт: POST /api/user/keys
+//
+// Входные данные (необязательно):
+//
+//	{"name": "ci-runner"}
+//
+// Возможные ответы:
+//   - 201 Created: ключ создан, в теле RespAPIKey с полем key
+//   - 400 Bad Request: невалидный JSON
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 403 Forbidden: запрошена роль выше роли пользователя
+//   - 501 Not Implemented: хранилище не поддерживает API-ключи
+//   - 500 Internal Server Error: ошибка хранилища
+//
+// Особенности:
+//   - Ключ не имеет срока действия и действует до явного отзыва
+//   - Ключ аутентифицируется с ролью, сохраненной при создании
+//   - В хранилище сохраняется только SHA-256 хеш ключа
+func (a *App) APICreateKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	keys, ok := a.apiKeys()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	var req ReqAPIKey
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "request body must be a JSON object")
+		return
+	}
+
+	role := cookies.RoleFromContext(r.Context())
+	if req.Role != "" {
+		if cookies.NormalizeRole(req.Role) == cookies.RoleAdmin && role != cookies.RoleAdmin {
+			problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, "")
+			return
+		}
+		role = cookies.NormalizeRole(req.Role)
+	}
+
+	plain, hash, err := apikey.Generate()
+	if err != nil {
+		problem.Internal(w, r, "Failed to generate API key", err)
+		return
+	}
+
+	key := &objects.APIKey{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		Name:      req.Name,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC(),
+		Role:      role,
+	}
+
+	if err := keys.InsertAPIKey(r.Context(), key); err != nil {
+		problem.Internal(w, r, "Failed to insert API key", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(RespAPIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		Key:       plain,
+		CreatedAt: key.CreatedAt,
+		Role:      key.Role,
+	}); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// APIGetKeys возвращает список API-ключей текущего пользователя.
+// Эндпоинт: GET /api/user/keys
+//
+// Возможные ответы:
+//   - 200 OK: массив RespAPIKey (без самих ключей)
+//   - 204 No Content: у пользователя нет ключей
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) APIGetKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	keys, ok := a.apiKeys()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	userKeys, err := keys.GetAPIKeysByUserID(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get API keys", err)
+		return
+	}
+
+	if len(userKeys) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp := make([]RespAPIKey, 0, len(userKeys))
+	for _, k := range userKeys {
+		resp = append(resp, RespAPIKey{
+			ID:        k.ID,
+			Name:      k.Name,
+			CreatedAt: k.CreatedAt,
+			Revoked:   k.Revoked,
+			Role:      cookies.NormalizeRole(k.Role),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// APIRevokeKey отзывает API-ключ текущего пользователя.
+// Эндпоинт: DELETE /api/user/keys/{id}
+//
+// Возможные ответы:
+//   - 204 No Content: ключ отозван
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 404 Not Found: ключ не найден или принадлежит другому пользователю
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) APIRevokeKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	keys, ok := a.apiKeys()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := keys.RevokeAPIKey(r.Context(), userID, id); err != nil {
+		if errors.Is(err, storage.ErrAPIKeyNotFound) {
+			problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "")
+			return
+		}
+		problem.Internal(w, r, "Failed to revoke API key", err, zap.String("id", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
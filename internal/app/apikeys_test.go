@@ -0,0 +1,100 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeysLifecycle(t *testing.T) {
+	conf := &config.AppConfig{
+		Host:      "localhost:8080",
+		ResultURL: "http://localhost:8080",
+	}
+
+	app := NewApp(conf)
+	keys, ok := app.apiKeys()
+	require.True(t, ok)
+
+	userID := "key-owner"
+
+	r := chi.NewRouter()
+	r.Use(cookies.NewAuth(cookies.AuthConfig{Keys: keys}).Middleware)
+	r.Get("/api/user/keys", app.APIGetKeys)
+	r.Delete("/api/user/keys/{id}", app.APIRevokeKey)
+
+	// Создаем ключ от имени пользователя
+	req := httptest.NewRequest(http.MethodPost, "/api/user/keys", strings.NewReader(`{"name":"ci"}`))
+	req = req.WithContext(context.WithValue(req.Context(), cookies.SecretKey, userID))
+	w := httptest.NewRecorder()
+	app.APICreateKey(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created RespAPIKey
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.Equal(t, "ci", created.Name)
+	assert.True(t, strings.HasPrefix(created.Key, "sk_"))
+
+	// Ключ принимается middleware вместо cookie
+	t.Run("list with api key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+		req.Header.Set("Authorization", "Bearer "+created.Key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Result().Cookies(), "cookie не должна выдаваться при входе по ключу")
+
+		var list []RespAPIKey
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+		require.Len(t, list, 1)
+		assert.Equal(t, created.ID, list[0].ID)
+		assert.Empty(t, list[0].Key, "ключ не должен возвращаться повторно")
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+		req.Header.Set("X-API-Key", "sk_unknown")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		var p problem.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, problem.CodeUnauthorized, p.Code)
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/user/keys/"+created.ID, nil)
+		req.Header.Set("X-API-Key", created.Key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		require.Equal(t, http.StatusNoContent, w.Code)
+
+		// После отзыва ключ больше не принимается
+		req = httptest.NewRequest(http.MethodGet, "/api/user/keys", nil)
+		req.Header.Set("X-API-Key", created.Key)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("revoke foreign key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/user/keys/"+created.ID, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -4,21 +4,56 @@
 package app
 
 import (
-	"log"
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/audit"
+	"github.com/GevorkovG/go-shortener-tlp/internal/buildinfo"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
 	"github.com/GevorkovG/go-shortener-tlp/internal/database"
+	"github.com/GevorkovG/go-shortener-tlp/internal/linkpolicy"
+	"github.com/GevorkovG/go-shortener-tlp/internal/metrics"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
 	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
+	"github.com/GevorkovG/go-shortener-tlp/internal/tracing"
+	"github.com/GevorkovG/go-shortener-tlp/internal/webhook"
+	"go.uber.org/zap"
 )
 
 // App представляет основное приложение с его зависимостями.
 // Содержит:
 //   - Конфигурацию приложения
 //   - Хранилище данных
+//   - Журнал аудита (может быть nil)
+//   - Диспетчер исходящих вебхуков (nil, если хранилище их не поддерживает)
+//
+// Конфигурация хранится в атомарном указателе и заменяется целиком
+// при перезагрузке (см. Reload).
 type App struct {
-	cfg     *config.AppConfig
-	Storage objects.Storage
+	cfg      atomic.Pointer[config.AppConfig]
+	Storage  objects.Storage
+	audit    *audit.Auditor
+	webhooks *webhook.Dispatcher
+	policy   *linkpolicy.Engine
+	// csrf — защита от CSRF публичного роутера (nil до вызова Run)
+	csrf *cookies.CSRF
+	// auth — аутентификация публичного роутера (nil до вызова Run)
+	auth *cookies.Auth
+
+	// build — сведения о сборке для /api/version
+	build buildinfo.Info
+	// backend — имя хранилища: postgres, file или memory
+	backend string
+	// started — время запуска приложения
+	started time.Time
+
+	// shuttingDown выставляется при завершении работы, /readyz отвечает 503
+	shuttingDown atomic.Bool
+	// deletionBacklog — ссылки, принятые на удаление и еще не обработанные
+	deletionBacklog atomic.Int64
 }
 
 type contextKey string
@@ -27,6 +62,10 @@ type contextKey string
 // Используется в middleware и обработчиках для передачи токена между слоями приложения.
 const Token contextKey = "token"
 
+// detailUnsupported — пояснение ответа 501 для операций, которые текущее
+// хранилище не поддерживает (см. проверки возможностей хранилища в обработчиках)
+const detailUnsupported = "storage does not support this operation"
+
 // NewApp создает и инициализирует новый экземпляр приложения с заданной конфигурацией.
 // В зависимости от параметров конфигурации выбирается соответствующее хранилище:
 //   - Если указана строка подключения к БД (DataBaseString), используется PostgreSQL хранилище
@@ -52,32 +91,142 @@ const Token contextKey = "token"
 //
 // Примечания:
 //   - Функция логирует выбранный тип хранилища
+//   - Для PostgreSQL при старте создаются недостающие таблицы и столбцы
 //   - Приоритет выбора хранилища: БД > Файл > Память
+//   - Операции хранилища учитываются в метриках и трассировке (storage.Instrument)
+//   - Если указаны AuditFile или AuditURL, создается журнал аудита
+//   - Если указан LinkPolicyFile, загружается политика ссылок
 //   - Переданная конфигурация сохраняется по ссылке, изменения в cfg после создания
-//     приложения будут влиять на его работу
+//     приложения будут влиять на его работу; для изменения настроек во время
+//     работы используйте Reload
 func NewApp(cfg *config.AppConfig) *App {
 	var store objects.Storage
+	var backend string
 
 	switch {
 	case cfg.DataBaseString != "":
-		log.Printf("internal/app/app.go ValidationToken USE DataBase")
+		zap.L().Info("Using storage", zap.String("backend", "postgres"))
 		db := database.InitDB(cfg.DataBaseString)
-		store = storage.NewLinkStorage(db)
+		links := storage.NewLinkStorage(db)
+		if err := links.Migrate(context.Background()); err != nil {
+			zap.L().Fatal("Failed to migrate database", zap.Error(err))
+		}
+		store = links
+		backend = "postgres"
 	case cfg.FilePATH != "":
-		log.Printf("internal/app/app.go ValidationToken USE FilePATH ")
+		zap.L().Info("Using storage", zap.String("backend", "file"), zap.String("path", cfg.FilePATH))
 		store = storage.NewFileStorage(cfg.FilePATH)
+		backend = "file"
 	default:
-		log.Printf("internal/app/app.go ValidationToken USE NewInMemoryStorage ")
+		zap.L().Info("Using storage", zap.String("backend", "memory"))
 		store = storage.NewInMemoryStorage()
+		backend = "memory"
+	}
+	store = storage.Instrument(store, metrics.StorageObserver(backend), tracing.StorageObserver(backend))
+
+	var webhooks *webhook.Dispatcher
+	if hooks, ok := store.(objects.WebhookStorage); ok {
+		var opts []webhook.Option
+		if cfg.WebhookAllowPrivate {
+			opts = append(opts, webhook.WithPrivateNetworks())
+		}
+		webhooks = webhook.NewDispatcher(hooks, opts...)
+	}
+
+	a := &App{
+		Storage:  store,
+		audit:    newAuditor(cfg),
+		webhooks: webhooks,
+		policy:   newPolicy(cfg),
+		build:    buildinfo.New("", "", ""),
+		backend:  backend,
+		started:  time.Now(),
 	}
+	a.cfg.Store(cfg)
+	return a
+}
 
-	return &App{
-		cfg:     cfg,
-		Storage: store,
+// newAuditor создает журнал аудита с приемниками из конфигурации.
+// Возвращает nil, если ни один приемник не настроен.
+func newAuditor(cfg *config.AppConfig) *audit.Auditor {
+	var sinks []audit.Sink
+	if cfg.AuditFile != "" {
+		sink, err := audit.NewFileSink(cfg.AuditFile)
+		if err != nil {
+			zap.L().Fatal("Failed to open audit file", zap.Error(err))
+		}
+		sinks = append(sinks, sink)
 	}
+	if cfg.AuditURL != "" {
+		sinks = append(sinks, audit.NewHTTPSink(cfg.AuditURL))
+	}
+	return audit.New(sinks)
+}
+
+// newPolicy загружает политику ссылок из файла конфигурации.
+// Без файла политика разрешает все адреса.
+func newPolicy(cfg *config.AppConfig) *linkpolicy.Engine {
+	policy, err := linkpolicy.NewEngine(cfg.LinkPolicyFile)
+	if err != nil {
+		zap.L().Fatal("Failed to load link policy", zap.Error(err))
+	}
+	return policy
+}
+
+// Close освобождает ресурсы приложения: дожидается доставки
+// событий аудита и вебхуков (не дольше, чем позволяет ctx).
+func (a *App) Close(ctx context.Context) error {
+	err := a.webhooks.Close(ctx)
+	if aerr := a.audit.Close(ctx); aerr != nil && err == nil {
+		err = aerr
+	}
+	return err
 }
 
 // GetConfig возвращает текущую конфигурацию приложения.
+// Возвращаемое значение не должно изменяться: после перезагрузки
+// конфигурации метод вернет новый экземпляр.
 func (a *App) GetConfig() *config.AppConfig {
-	return a.cfg
+	return a.cfg.Load()
+}
+
+// contextUserID возвращает ID пользователя, установленный middleware аутентификации
+func contextUserID(r *http.Request) string {
+	userID, _ := r.Context().Value(cookies.SecretKey).(string)
+	return userID
+}
+
+// emitAudit отправляет событие аудита от имени пользователя из контекста запроса.
+//
+// Параметры:
+//   - r: HTTP-запрос (источник UserID и IP-адреса)
+//   - eventType: тип события (audit.EventCreate и т.д.)
+//   - shorts: короткие идентификаторы ссылок
+//   - originals: оригинальные URL
+func (a *App) emitAudit(r *http.Request, eventType string, shorts []string, originals []string) {
+	a.audit.Emit(audit.Event{
+		Type:     eventType,
+		UserID:   contextUserID(r),
+		IP:       audit.ClientIP(r),
+		Short:    shorts,
+		Original: originals,
+	})
+}
+
+// publishWebhook публикует событие для подписчиков владельца ссылок.
+//
+// Параметры:
+//   - userID: владелец ссылок
+//   - event: тип события (webhook.EventLinkCreated и т.д.)
+//   - shorts: короткие идентификаторы ссылок
+//   - originals: оригинальные URL (может быть короче shorts)
+func (a *App) publishWebhook(userID string, event string, shorts []string, originals []string) {
+	links := make([]webhook.Link, len(shorts))
+	for i, short := range shorts {
+		links[i].Short = a.GetConfig().ResultURL + "/" + short
+		if i < len(originals) {
+			links[i].Original = originals[i]
+		}
+	}
+	a.webhooks.Publish(userID, event, links)
 }
@@ -3,12 +3,13 @@ package app
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
-	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/audit"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
-	"github.com/GevorkovG/go-shortener-tlp/internal/services/usertoken"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/webhook"
 	"go.uber.org/zap"
 )
 
@@ -29,9 +30,14 @@ type Req struct {
 // Поля:
 //   - ID string `json:"correlation_id"`: оригинальный ID из запроса (для сопоставления)
 //   - Short string `json:"short_url"`: сокращенный URL
+//   - Submitted string `json:"submitted_url"`: URL в том виде, в каком его прислал клиент
+//   - Stored string `json:"stored_url"`: сохраненный URL после канонизации
+//     и удаления параметров отслеживания
 type Resp struct {
-	ID    string `json:"correlation_id"`
-	Short string `json:"short_url"`
+	ID        string `json:"correlation_id"`
+	Short     string `json:"short_url"`
+	Submitted string `json:"submitted_url"`
+	Stored    string `json:"stored_url"`
 }
 
 // APIshortBatch обрабатывает пакетный запрос на создание сокращенных URL.
@@ -57,12 +63,15 @@ type Resp struct {
 // Логика работы:
 //  1. Извлекает токен из контекста запроса
 //  2. Декодирует входящий JSON в массив Req
-//  3. Для каждого URL:
-//     - Генерирует уникальный ключ
+//  3. Проверяет все URL и приводит их к канонической форме; при ошибках
+//     отвечает 400 со списком ошибок по полям (/{индекс}/original_url)
+//  4. Для каждого URL:
+//     - Генерирует уникальный ключ; одинаковые в канонической форме URL
+//     пакета получают один ключ и сохраняются один раз
 //     - Формирует сокращенный URL
 //     - Создает объект Link для хранения
-//  4. Сохраняет все ссылки атомарной операцией
-//  5. Возвращает массив созданных сокращенных URL
+//  5. Сохраняет все ссылки атомарной операцией
+//  6. Возвращает массив созданных сокращенных URL
 //
 // Пример запроса:
 //
@@ -72,7 +81,7 @@ type Resp struct {
 //
 //	[{
 //	  "correlation_id": "1",
-//	  "original_url": "https://example.com"
+//	  "original_url": "https://example.com?utm_source=mail"
 //	}]
 //
 // Пример ответа:
@@ -82,7 +91,9 @@ type Resp struct {
 //
 //	[{
 //	  "correlation_id": "1",
-//	  "short_url": "http://short.ly/abc123"
+//	  "short_url": "http://short.ly/abc123",
+//	  "submitted_url": "https://example.com?utm_source=mail",
+//	  "stored_url": "https://example.com/"
 //	}]
 //
 // Особенности:
@@ -97,54 +108,77 @@ func (a *App) APIshortBatch(w http.ResponseWriter, r *http.Request) {
 
 	err := json.NewDecoder(r.Body).Decode(&originals)
 	if err != nil {
-		log.Println("didn't decode body")
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logg.FromContext(r.Context()).Debug("Failed to decode batch request", zap.Error(err))
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "request body must be a JSON array of {correlation_id, original_url}")
 		return
 	}
 
 	shorts := make([]Resp, 0, len(originals))
 	links := make([]*objects.Link, 0, len(originals))
 
-	token := r.Context().Value(cookies.SecretKey).(string)
+	// Middleware аутентификации кладет в контекст UserID; без него
+	// ссылки сохраняются без владельца
+	userID := contextUserID(r)
 
-	userID, err := usertoken.GetUserID(token)
-	if err != nil {
-		userID = ""
-	}
-
-	zap.L().Debug("internal/app/batsh.go",
-		zap.String("userID", userID),
-		zap.String("token", token),
+	logg.FromContext(r.Context()).Debug("internal/app/batsh.go",
+		logg.UserID(userID),
+		zap.Int("count", len(originals)),
 	)
 
-	for _, val := range originals {
-
-		key := generateID()
-		resp := Resp{
-			ID:    val.ID,
-			Short: fmt.Sprintf(a.cfg.ResultURL+"/%s", key),
+	canonical := make([]string, len(originals))
+	var fieldErrs []problem.FieldError
+	for i, val := range originals {
+		if canonical[i], err = a.canonicalURL(r.Context(), val.URL); err != nil {
+			fieldErrs = append(fieldErrs, problem.FieldError{Field: fmt.Sprintf("/%d/original_url", i), Message: err.Error()})
 		}
-		link := &objects.Link{
-			Short:    key,
-			Original: val.URL,
-			UserID:   userID,
-		}
-
-		shorts = append(shorts, resp)
-		links = append(links, link)
+	}
+	if len(fieldErrs) > 0 {
+		problem.Invalid(w, r, fieldErrs)
+		return
+	}
 
+	// Ключи по канонической форме: повтор URL в пакете не вставляется
+	// второй раз и не нарушает уникальность в хранилище
+	shortByURL := make(map[string]string, len(originals))
+	for i, val := range originals {
+		key, seen := shortByURL[canonical[i]]
+		if !seen {
+			key = generateID()
+			shortByURL[canonical[i]] = key
+			links = append(links, &objects.Link{
+				Short:    key,
+				Original: canonical[i],
+				UserID:   userID,
+			})
+		}
+		shorts = append(shorts, Resp{
+			ID:        val.ID,
+			Short:     fmt.Sprintf(a.GetConfig().ResultURL+"/%s", key),
+			Submitted: val.URL,
+			Stored:    canonical[i],
+		})
 	}
 
 	if err = a.Storage.InsertLinks(r.Context(), links); err != nil {
-		//		log.Println("Didn't insert to table")
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Internal(w, r, "Failed to insert links", err, logg.Links("links", links))
 		return
 	}
 
+	if len(links) > 0 {
+		keys := make([]string, 0, len(links))
+		urls := make([]string, 0, len(links))
+		for _, l := range links {
+			keys = append(keys, l.Short)
+			urls = append(urls, l.Original)
+		}
+		a.emitAudit(r, audit.EventBatchCreate, keys, urls)
+		a.publishWebhook(contextUserID(r), webhook.EventLinkCreated, keys, urls)
+	}
+
 	response, err := json.Marshal(shorts)
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Internal(w, r, "Failed to marshal response", err)
 		return
 	}
 
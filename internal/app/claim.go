@@ -0,0 +1,111 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"go.uber.org/zap"
+)
+
+// ReqClaim представляет запрос на перенос ссылок со старого токена.
+//
+// Поля:
+//   - Token string `json:"token"`: прежний JWT токен (может быть истекшим)
+type ReqClaim struct {
+	Token string `json:"token"`
+}
+
+// RespClaim представляет ответ на перенос ссылок.
+//
+// Поля:
+//   - Moved int `json:"moved"`: количество перенесенных ссылок
+type RespClaim struct {
+	Moved int `json:"moved"`
+}
+
+// APIClaimLinks переносит ссылки, созданные под прежним токеном, на текущего пользователя.
+// Эндпоинт: POST /api/user/claim
+//
+// Входные данные:
+//
+//	{"token": "<прежний JWT>"}
+//
+// Возможные ответы:
+//   - 200 OK: ссылки перенесены, {"moved": <количество>}
+//   - 400 Bad Request: невалидный JSON или пустой токен
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 403 Forbidden: подпись прежнего токена не прошла проверку, токен отозван
+//     или истек раньше чем cookies.ClaimMaxAge назад
+//   - 501 Not Implemented: хранилище не поддерживает перенос ссылок
+//   - 500 Internal Server Error: ошибка хранилища
+//
+// Особенности:
+//   - Истекший токен принимается в течение cookies.ClaimMaxAge после истечения;
+//     logout хранит отзыв токена столько же, поэтому отозванный токен
+//     не становится снова пригодным для переноса
+//   - Повторный перенос с того же токена безопасен и возвращает moved: 0
+func (a *App) APIClaimLinks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	owners, ok := a.Storage.(objects.OwnershipStorage)
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	var req ReqClaim
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "request body must be a JSON object")
+		return
+	}
+	if req.Token == "" {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "token is required")
+		return
+	}
+
+	claims, err := cookies.ParseClaimToken(req.Token)
+	if err != nil {
+		logg.FromContext(r.Context()).Warn("Rejected claim token", zap.Error(err))
+		problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, "")
+		return
+	}
+
+	// Токен, отозванный через logout, не может использоваться для переноса
+	if revocations, ok := a.Storage.(objects.RevocationStorage); ok && claims.ID != "" {
+		revoked, err := revocations.IsTokenRevoked(r.Context(), claims.ID)
+		if err != nil {
+			problem.Internal(w, r, "Failed to check token revocation", err)
+			return
+		}
+		if revoked {
+			problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, "")
+			return
+		}
+	}
+
+	moved := 0
+	if claims.UserID != userID {
+		moved, err = owners.TransferLinks(r.Context(), claims.UserID, userID)
+		if err != nil {
+			problem.Internal(w, r, "Failed to transfer links", err)
+			return
+		}
+	}
+
+	logg.FromContext(r.Context()).Info("Links claimed", logg.UserID(userID), zap.Int("moved", moved))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(RespClaim{Moved: moved}); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIClaimLinks(t *testing.T) {
+	conf := &config.AppConfig{
+		Host:      "localhost:8080",
+		ResultURL: "http://localhost:8080",
+	}
+	app := NewApp(conf)
+
+	require.NoError(t, app.Storage.Insert(context.Background(),
+		&objects.Link{Short: "old1", Original: "https://example.com/old", UserID: "old-user"}))
+
+	oldToken, err := cookies.BuildJWTString("old-user")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		body  string
+		code  int
+		moved int
+	}{
+		{name: "bad signature", body: `{"token":"` + oldToken + `x"}`, code: http.StatusForbidden},
+		{name: "empty token", body: `{}`, code: http.StatusBadRequest},
+		{name: "ok", body: `{"token":"` + oldToken + `"}`, code: http.StatusOK, moved: 1},
+		{name: "repeat", body: `{"token":"` + oldToken + `"}`, code: http.StatusOK, moved: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/user/claim", strings.NewReader(tt.body))
+			r = r.WithContext(context.WithValue(r.Context(), cookies.SecretKey, "new-user"))
+			w := httptest.NewRecorder()
+
+			app.APIClaimLinks(w, r)
+
+			require.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusOK {
+				var resp RespClaim
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, tt.moved, resp.Moved)
+			}
+		})
+	}
+
+	links, err := app.Storage.GetAllByUserID(context.Background(), "new-user")
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "old1", links[0].Short)
+}
+
+func TestAPIClaimLinks_RevokedExpiredToken(t *testing.T) {
+	app := NewApp(&config.AppConfig{Host: "localhost:8080", ResultURL: "http://localhost:8080"})
+
+	require.NoError(t, app.Storage.Insert(context.Background(),
+		&objects.Link{Short: "old1", Original: "https://example.com/old", UserID: "old-user"}))
+
+	// Токен истек час назад, но был отозван через logout до истечения
+	claims := cookies.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ID: "jti-old", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour))},
+		UserID:           "old-user",
+	}
+	oldToken, err := cookies.SignClaims(claims)
+	require.NoError(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/user/claim", strings.NewReader(`{"token":"`+oldToken+`"}`))
+	require.NoError(t, revokeClaims(r, app.Storage.(objects.RevocationStorage), &claims))
+
+	r = r.WithContext(context.WithValue(r.Context(), cookies.SecretKey, "new-user"))
+	w := httptest.NewRecorder()
+	app.APIClaimLinks(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	links, err := app.Storage.GetAllByUserID(context.Background(), "old-user")
+	require.NoError(t, err)
+	assert.Len(t, links, 1)
+}
@@ -0,0 +1,93 @@
+package app
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/metrics"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/go-chi/chi"
+	"go.uber.org/zap"
+)
+
+// diagAuth пропускает запросы к служебному серверу, если выполнено одно из условий:
+//   - заголовок Authorization: Bearer <DIAG_TOKEN> совпадает с токеном
+//   - адрес клиента входит в доверенную подсеть (TRUSTED_SUBNET)
+//   - токен и подсеть не заданы, а запрос пришел с loopback-адреса
+//
+// Адрес клиента берется из соединения, а не из заголовков: служебный
+// сервер не предназначен для работы за прокси.
+func (a *App) diagAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conf := a.GetConfig()
+
+		if conf.DiagToken != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(token), []byte(conf.DiagToken)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+
+		if conf.TrustedSubnet != "" {
+			if _, ipnet, err := net.ParseCIDR(conf.TrustedSubnet); err == nil && ip != nil && ipnet.Contains(ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if conf.DiagToken == "" && conf.TrustedSubnet == "" && ip != nil && ip.IsLoopback() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		zap.L().Warn("Diagnostics access denied", zap.String("remote", r.RemoteAddr), zap.String("path", r.URL.Path))
+		problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, "")
+	})
+}
+
+// DiagRouter возвращает обработчик служебного сервера для операторов.
+// Эти эндпоинты не публикуются в основном роутере.
+//
+// Роуты:
+//
+//	GET /metrics          - метрики в формате Prometheus
+//	GET /debug/pprof/...  - профилирование (net/http/pprof)
+//	GET /debug/config     - итоговая конфигурация с источниками, секреты скрыты
+//	GET /debug/stats      - количество ссылок и пользователей
+//
+// Доступ ограничивается diagAuth.
+func (a *App) DiagRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(a.diagAuth)
+
+	r.Method(http.MethodGet, "/metrics", metrics.Handler())
+
+	r.Get("/debug/pprof/cmdline", pprof.Cmdline)
+	r.Get("/debug/pprof/profile", pprof.Profile)
+	r.Get("/debug/pprof/symbol", pprof.Symbol)
+	r.Post("/debug/pprof/symbol", pprof.Symbol)
+	r.Get("/debug/pprof/trace", pprof.Trace)
+	r.Get("/debug/pprof/*", pprof.Index)
+
+	r.Get("/debug/config", a.diagConfig)
+	r.Get("/debug/stats", a.writeStats)
+	return r
+}
+
+// diagConfig выводит итоговую конфигурацию так же, как флаг -print-config
+func (a *App) diagConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if err := a.GetConfig().Print(w); err != nil {
+		zap.L().Error("Failed to write response", zap.Error(err))
+	}
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEnv - окружение для config.Load в тестах: задан только обязательный JWT_SECRET
+func testEnv(key string) (string, bool) {
+	if key == "JWT_SECRET" {
+		return "test-jwt-secret-0123456789abcdef0123", true
+	}
+	return "", false
+}
+
+func TestDiagRouter_Access(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		remote string
+		token  string
+		want   int
+	}{
+		{"loopback without protection", nil, "127.0.0.1:5000", "", http.StatusOK},
+		{"remote without protection", nil, "10.1.2.3:5000", "", http.StatusForbidden},
+		{"valid token", []string{"-diag-token", "s3cret"}, "10.1.2.3:5000", "s3cret", http.StatusOK},
+		{"wrong token", []string{"-diag-token", "s3cret"}, "10.1.2.3:5000", "guess", http.StatusForbidden},
+		{"loopback needs token once set", []string{"-diag-token", "s3cret"}, "127.0.0.1:5000", "", http.StatusForbidden},
+		{"trusted subnet", []string{"-t", "10.0.0.0/8"}, "10.1.2.3:5000", "", http.StatusOK},
+		{"outside trusted subnet", []string{"-t", "10.0.0.0/8"}, "192.168.0.1:5000", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := config.Load(tt.args, testEnv)
+			require.NoError(t, err)
+			app := NewApp(conf)
+
+			req := httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil)
+			req.RemoteAddr = tt.remote
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			app.DiagRouter().ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestDiagRouter_ConfigHidesToken(t *testing.T) {
+	conf, err := config.Load([]string{"-diag-token", "s3cret"}, testEnv)
+	require.NoError(t, err)
+	app := NewApp(conf)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/config", nil)
+	req.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	app.DiagRouter().ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "diag_token")
+	assert.False(t, strings.Contains(w.Body.String(), "s3cret"), "токен не должен выводиться")
+}
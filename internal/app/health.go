@@ -0,0 +1,208 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/metrics"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"go.uber.org/zap"
+)
+
+// Состояния проверок
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// healthCheckTimeout ограничивает время одной проверки компонента
+const healthCheckTimeout = 2 * time.Second
+
+// deletionBacklogLimit — число ссылок в очереди удаления, выше которого
+// сервис считается перегруженным и перестает принимать новые запросы (/readyz)
+const deletionBacklogLimit = 10000
+
+// errShuttingDown сообщает, что сервер завершает работу
+var errShuttingDown = errors.New("server is shutting down")
+
+// ComponentHealth — состояние одного компонента в ответе /healthz и /readyz.
+// Ошибка компонента пишется в лог, а в ответ попадает только ее краткое
+// описание, чтобы не раскрывать адреса и пути.
+type ComponentHealth struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration,omitempty"`
+	Backlog  *int64 `json:"backlog,omitempty"`
+}
+
+// HealthResponse — ответ /healthz и /readyz.
+type HealthResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentHealth `json:"components"`
+}
+
+// writableStorage реализуется хранилищами, пишущими в файлы
+type writableStorage interface {
+	CheckWritable() error
+}
+
+// migratedStorage реализуется хранилищами со схемой базы данных
+type migratedStorage interface {
+	CheckMigrations(ctx context.Context) error
+}
+
+// roundTripStorage реализуется хранилищами, которые умеют проверить
+// запись и чтение без изменения сохраненных данных
+type roundTripStorage interface {
+	CheckRoundTrip(ctx context.Context) error
+}
+
+// unwrapStorage возвращает хранилище без оберток (см. storage.Instrument)
+func unwrapStorage(s objects.Storage) objects.Storage {
+	for {
+		u, ok := s.(interface{ Unwrap() objects.Storage })
+		if !ok {
+			return s
+		}
+		s = u.Unwrap()
+	}
+}
+
+// queueDeletion меняет глубину очереди удаления на n (см. metrics.DeletionQueued)
+func (a *App) queueDeletion(n int) {
+	a.deletionBacklog.Add(int64(n))
+	metrics.DeletionQueued(n)
+}
+
+// SetShuttingDown переводит приложение в режим завершения: /readyz
+// начинает отвечать 503, чтобы балансировщик перестал направлять запросы.
+func (a *App) SetShuttingDown() {
+	a.shuttingDown.Store(true)
+}
+
+// checkComponent выполняет проверку компонента с ограничением по времени
+// и записывает ошибку в лог
+func checkComponent(ctx context.Context, name, public string, fn func(ctx context.Context) error) ComponentHealth {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	c := ComponentHealth{Status: healthOK, Duration: time.Since(start).String()}
+	if err != nil {
+		logg.FromContext(ctx).Warn("Health check failed", zap.String("component", name), zap.Error(err))
+		c.Status = healthFail
+		c.Error = public
+	}
+	return c
+}
+
+// liveness проверяет, что процесс отвечает. Нагрузка и внешние
+// зависимости сюда не входят: перезапуск процесса их не исправит.
+func (a *App) liveness() map[string]ComponentHealth {
+	return map[string]ComponentHealth{"process": {Status: healthOK}}
+}
+
+// deletionQueue сообщает размер очереди удаления; при перегрузке
+// экземпляр временно выводится из балансировки
+func (a *App) deletionQueue() ComponentHealth {
+	backlog := a.deletionBacklog.Load()
+	c := ComponentHealth{Status: healthOK, Backlog: &backlog}
+	if backlog > deletionBacklogLimit {
+		c.Status = healthFail
+		c.Error = "deletion queue is overloaded"
+	}
+	return c
+}
+
+// readiness проверяет все компоненты, от которых зависит обработка запросов
+func (a *App) readiness(ctx context.Context) map[string]ComponentHealth {
+	components := map[string]ComponentHealth{"deletion_queue": a.deletionQueue()}
+
+	inner := unwrapStorage(a.Storage)
+	check := a.Storage.Ping
+	if rt, ok := inner.(roundTripStorage); ok {
+		check = rt.CheckRoundTrip
+	}
+	components["storage"] = checkComponent(ctx, "storage", "storage is unavailable", check)
+
+	if w, ok := inner.(writableStorage); ok {
+		components["file"] = checkComponent(ctx, "file", "storage file is not writable", func(context.Context) error {
+			return w.CheckWritable()
+		})
+	}
+	if m, ok := inner.(migratedStorage); ok {
+		components["migrations"] = checkComponent(ctx, "migrations", "database schema is not migrated", m.CheckMigrations)
+	}
+
+	shutdown := ComponentHealth{Status: healthOK}
+	if a.shuttingDown.Load() {
+		shutdown = ComponentHealth{Status: healthFail, Error: errShuttingDown.Error()}
+	}
+	components["shutdown"] = shutdown
+
+	return components
+}
+
+// writeHealth отвечает 200, если все компоненты в порядке, иначе 503
+func writeHealth(w http.ResponseWriter, r *http.Request, components map[string]ComponentHealth) {
+	resp := HealthResponse{Status: healthOK, Components: components}
+	status := http.StatusOK
+	for _, c := range components {
+		if c.Status != healthOK {
+			resp.Status = healthFail
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// Healthz — проверка живости процесса для оркестратора.
+// Не обращается к хранилищу и не учитывает нагрузку: недоступная база
+// или длинная очередь удаления не должны приводить к перезапуску процесса.
+//
+// Пример запроса:
+//
+//	GET /healthz
+//
+// Пример ответа:
+//
+//	{"status":"ok","components":{"process":{"status":"ok"}}}
+//
+// Возвращает:
+//   - 200: процесс работает
+func (a *App) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, a.liveness())
+}
+
+// Readyz — проверка готовности принимать запросы для балансировщика.
+//
+// Компоненты:
+//   - storage: запись и чтение пробного значения без изменения данных
+//     (для хранилища в памяти — Ping)
+//   - file: файлы файлового хранилища доступны на запись
+//   - migrations: таблицы PostgreSQL созданы
+//   - deletion_queue: размер очереди удаления, не больше 10000 ссылок
+//   - shutdown: сервер не завершает работу
+//
+// Пример ответа:
+//
+//	{"status":"fail","components":{"shutdown":{"status":"fail","error":"server is shutting down"}, ...}}
+//
+// Возвращает:
+//   - 200: все компоненты в порядке
+//   - 503: хотя бы один компонент неисправен или идет завершение работы
+func (a *App) Readyz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, r, a.readiness(r.Context()))
+}
@@ -0,0 +1,100 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getHealth выполняет запрос к обработчику проверки и разбирает ответ
+func getHealth(t *testing.T, h http.HandlerFunc, path string) (int, HealthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var resp HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestReadyz_FileStorage(t *testing.T) {
+	dir := t.TempDir()
+	conf, err := config.Load([]string{"-f", filepath.Join(dir, "links.json")}, testEnv)
+	require.NoError(t, err)
+	app := NewApp(conf)
+
+	code, resp := getHealth(t, app.Readyz, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthOK, resp.Status)
+	for _, name := range []string{"storage", "file", "deletion_queue", "shutdown"} {
+		assert.Equal(t, healthOK, resp.Components[name].Status, name)
+	}
+	assert.NotContains(t, resp.Components, "migrations")
+
+	// Пробный файл удаляется после проверки
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		assert.NotContains(t, e.Name(), "healthcheck")
+	}
+
+	// Вместо файла переходов — каталог: открыть его на запись нельзя
+	// даже с правами root
+	clicks := filepath.Join(dir, "links.json.clicks")
+	require.NoError(t, os.Remove(clicks))
+	require.NoError(t, os.Mkdir(clicks, 0700))
+	code, resp = getHealth(t, app.Readyz, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthFail, resp.Components["file"].Status)
+	assert.NotContains(t, resp.Components["file"].Error, dir, "paths must not leak")
+
+	// Каталог хранилища удален: запись пробного файла невозможна
+	require.NoError(t, os.RemoveAll(dir))
+	code, resp = getHealth(t, app.Readyz, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthFail, resp.Components["storage"].Status)
+}
+
+func TestReadyz_ShuttingDown(t *testing.T) {
+	app := NewApp(&config.AppConfig{})
+
+	code, _ := getHealth(t, app.Readyz, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	app.SetShuttingDown()
+	code, resp := getHealth(t, app.Readyz, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthFail, resp.Components["shutdown"].Status)
+
+	// Живость от завершения работы не зависит
+	code, _ = getHealth(t, app.Healthz, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestReadyz_DeletionBacklog(t *testing.T) {
+	app := NewApp(&config.AppConfig{})
+
+	app.queueDeletion(3)
+	code, resp := getHealth(t, app.Readyz, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	require.NotNil(t, resp.Components["deletion_queue"].Backlog)
+	assert.EqualValues(t, 3, *resp.Components["deletion_queue"].Backlog)
+
+	app.queueDeletion(deletionBacklogLimit)
+	code, resp = getHealth(t, app.Readyz, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthFail, resp.Components["deletion_queue"].Status)
+
+	// Перегрузка не должна приводить к перезапуску процесса
+	code, _ = getHealth(t, app.Healthz, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	app.queueDeletion(-deletionBacklogLimit - 3)
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+)
+
+// revokeClaims добавляет токен с указанными claims в список отозванных.
+// Запись хранится cookies.ClaimMaxAge после истечения токена, пока
+// токен еще можно предъявить в POST /api/user/claim
+func revokeClaims(r *http.Request, revocations objects.RevocationStorage, claims *cookies.Claims) error {
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+	return revocations.RevokeToken(r.Context(), claims.ID, claims.ExpiresAt.Time.Add(cookies.ClaimMaxAge))
+}
+
+// APILogout завершает сессию пользователя.
+// Эндпоинт: POST /api/user/logout
+//
+// Возможные ответы:
+//   - 204 No Content: токен отозван, cookie удалена
+//   - 501 Not Implemented: хранилище не поддерживает отзыв токенов
+//   - 500 Internal Server Error: ошибка хранилища
+//
+// Особенности:
+//   - В список отозванных попадают и присланный токен, и токен,
+//     перевыпущенный middleware в рамках этого же запроса
+//   - Записи хранятся cookies.ClaimMaxAge после истечения срока действия
+//     токена, чтобы отозванный токен нельзя было использовать для переноса ссылок
+//   - Для запросов с API-ключом ничего не делает: ключ отзывается
+//     через DELETE /api/user/keys/{id}
+func (a *App) APILogout(w http.ResponseWriter, r *http.Request) {
+	revocations, ok := a.Storage.(objects.RevocationStorage)
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	if method, _ := r.Context().Value(cookies.AuthMethodKey).(string); method == cookies.AuthByAPIKey {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	current, _ := r.Context().Value(cookies.ClaimsKey).(*cookies.Claims)
+	if err := revokeClaims(r, revocations, current); err != nil {
+		problem.Internal(w, r, "Failed to revoke token", err)
+		return
+	}
+
+	if cookie, err := r.Cookie(cookies.TokenCookieName); err == nil {
+		if presented, err := cookies.ParseToken(cookie.Value); err == nil &&
+			(current == nil || presented.ID != current.ID) {
+			if err := revokeClaims(r, revocations, presented); err != nil {
+				problem.Internal(w, r, "Failed to revoke token", err)
+				return
+			}
+		}
+	}
+
+	cookieCfg, err := cookieConfig(a.GetConfig())
+	if err != nil {
+		cookieCfg = cookies.DefaultCookieConfig()
+	}
+	cookieCfg.ClearTokenCookie(w)
+
+	w.WriteHeader(http.StatusNoContent)
+}
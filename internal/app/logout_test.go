@@ -0,0 +1,55 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPILogout(t *testing.T) {
+	app := NewApp(&config.AppConfig{
+		Host:      "localhost:8080",
+		ResultURL: "http://localhost:8080",
+	})
+	revocations, ok := app.Storage.(objects.RevocationStorage)
+	require.True(t, ok)
+
+	var seenUserID string
+	r := chi.NewRouter()
+	r.Use(cookies.NewAuth(cookies.AuthConfig{Revocations: revocations, Cookie: cookies.DefaultCookieConfig()}).Middleware)
+	r.Get("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		seenUserID, _ = r.Context().Value(cookies.SecretKey).(string)
+	})
+	r.Post("/api/user/logout", app.APILogout)
+
+	// Получаем токен
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whoami", nil))
+	require.Len(t, w.Result().Cookies(), 1)
+	token := w.Result().Cookies()[0]
+	userID := seenUserID
+
+	// Выходим
+	req := httptest.NewRequest(http.MethodPost, "/api/user/logout", nil)
+	req.AddCookie(token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNoContent, w.Code)
+
+	cleared := w.Result().Cookies()
+	require.NotEmpty(t, cleared)
+	assert.Equal(t, -1, cleared[len(cleared)-1].MaxAge, "cookie должна быть удалена")
+
+	// Старый токен больше не принимается
+	req = httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.AddCookie(token)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.NotEqual(t, userID, seenUserID)
+}
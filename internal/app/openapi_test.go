@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/openapi"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testUserHeader — заголовок, из которого тестовый роутер берет UserID
+// вместо проверки токена
+const testUserHeader = "X-Test-User"
+
+// schemaRouter собирает описанные в OpenAPI маршруты с проверкой запросов,
+// как в Run
+func schemaRouter(a *App) http.Handler {
+	r := chi.NewRouter()
+	r.Use(openapi.Default().Middleware, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if id := r.Header.Get(testUserHeader); id != "" {
+				r = r.WithContext(context.WithValue(r.Context(), cookies.SecretKey, id))
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Post("/api/shorten", a.JSONGetShortURL)
+	r.Post("/api/shorten/batch", a.APIshortBatch)
+	r.Get("/api/user/urls", a.APIGetUserURLs)
+	r.Delete("/api/user/urls", a.APIDeleteUserURLs)
+	return r
+}
+
+// Ответы обработчиков должны соответствовать описанию API: код ответа
+// описан, тип содержимого и тело совпадают со схемой
+func TestHandlers_MatchOpenAPI(t *testing.T) {
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080"})
+	h := schemaRouter(app)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		user   string
+		body   string
+		status int
+	}{
+		{"shorten", http.MethodPost, "/api/shorten", "", `{"url":"https://example.com/a"}`, http.StatusCreated},
+		{"shorten invalid", http.MethodPost, "/api/shorten", "", `{"url":""}`, http.StatusBadRequest},
+		{"shorten broken json", http.MethodPost, "/api/shorten", "", `{`, http.StatusBadRequest},
+		{"batch", http.MethodPost, "/api/shorten/batch", "user-1",
+			`[{"correlation_id":"1","original_url":"https://example.com/b"},{"correlation_id":"2","original_url":"https://example.com/c"}]`,
+			http.StatusCreated},
+		{"batch invalid", http.MethodPost, "/api/shorten/batch", "", `[{"correlation_id":"1"}]`, http.StatusBadRequest},
+		{"user urls", http.MethodGet, "/api/user/urls", "user-1", "", http.StatusOK},
+		{"user urls empty", http.MethodGet, "/api/user/urls", "user-2", "", http.StatusNoContent},
+		{"user urls unauthorized", http.MethodGet, "/api/user/urls", "", "", http.StatusUnauthorized},
+		{"delete", http.MethodDelete, "/api/user/urls", "user-1", `["abc"]`, http.StatusAccepted},
+		{"delete unauthorized", http.MethodDelete, "/api/user/urls", "", `["abc"]`, http.StatusUnauthorized},
+		{"delete invalid", http.MethodDelete, "/api/user/urls", "user-1", `[1]`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.user != "" {
+				r.Header.Set(testUserHeader, tt.user)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.NoError(t, openapi.Default().ValidateResponse(tt.method, tt.path, w.Code, w.Header(), w.Body.Bytes()))
+		})
+	}
+}
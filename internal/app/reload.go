@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"go.uber.org/zap"
+)
+
+// Reload применяет заново загруженную конфигурацию к работающему приложению.
+//
+// Параметры:
+//   - next: новая конфигурация, уже прошедшая проверку (config.Load)
+//
+// Возвращает:
+//   - []string: имена примененных полей
+//   - []string: имена измененных полей, которые вступят в силу только
+//     после перезапуска (например, server_address)
+//
+// Особенности:
+//   - Без перезапуска меняются уровень логирования, политика скрытия
+//     данных в логе, базовый URL (в том числе как доверенный Origin
+//     для CSRF-проверки), список администраторов и доверенная подсеть
+//     служебного сервера
+//   - Файл политики ссылок перечитывается, если изменился; при ошибке
+//     продолжает действовать прежняя политика
+//   - Новая конфигурация подменяется атомарно: обработчик видит либо
+//     старые, либо новые значения целиком
+func (a *App) Reload(next *config.AppConfig) (applied []string, restart []string) {
+	merged, applied, restart := a.GetConfig().ApplyReload(next)
+
+	if err := logg.SetLevel(merged.LogLevel); err != nil {
+		zap.L().Error("Failed to set log level", zap.String("level", merged.LogLevel), zap.Error(err))
+	}
+	logg.SetRedactPolicy(redactPolicy(merged))
+	if a.csrf != nil {
+		if err := a.csrf.SetTrustedOrigins(csrfOrigins(merged)); err != nil {
+			zap.L().Error("Failed to update CSRF trusted origins, keeping the previous ones", zap.Error(err))
+		}
+	}
+	if a.auth != nil {
+		a.auth.SetAdmins(strings.Split(merged.AdminUsers, ","))
+	}
+	if _, err := a.policy.Reload(); err != nil {
+		zap.L().Error("Failed to reload link policy, keeping the previous one", zap.Error(err))
+	}
+	a.cfg.Store(merged)
+
+	return applied, restart
+}
+
+// redactPolicy возвращает политику скрытия данных в логе из конфигурации
+func redactPolicy(conf *config.AppConfig) logg.RedactPolicy {
+	return logg.RedactPolicy{
+		HashUserIDs: conf.LogHashUserIDs,
+		URLs:        conf.LogURLs,
+		MaxItems:    conf.LogMaxItems,
+	}
+}
+
+// reloadOnSIGHUP перечитывает конфигурацию (файл, окружение и исходные флаги)
+// при получении SIGHUP, пока не отменен ctx.
+//
+// Особенности:
+//   - Если новая конфигурация не проходит проверку, работа продолжается
+//     со старой, ошибки записываются в лог
+//   - Измененные поля, требующие перезапуска, перечисляются в логе
+func (a *App) reloadOnSIGHUP(ctx context.Context, args []string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		}
+
+		next, err := config.Load(args, os.LookupEnv)
+		if err != nil {
+			zap.L().Error("Configuration reload rejected, keeping current settings", zap.Error(err))
+			continue
+		}
+
+		applied, restart := a.Reload(next)
+		zap.L().Info("Configuration reloaded", zap.Strings("applied", applied))
+		if len(restart) > 0 {
+			zap.L().Warn("Changed settings require a restart to take effect", zap.Strings("fields", restart))
+		}
+	}
+}
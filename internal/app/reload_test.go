@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApp_Reload(t *testing.T) {
+	conf, err := config.Load([]string{"-a", ":8080", "-diag-token", "secret"}, testEnv)
+	require.NoError(t, err)
+	app := NewApp(conf)
+	diag := app.DiagRouter()
+	app.csrf, err = cookies.NewCSRF(conf.CSRFMode, csrfOrigins(conf), cookies.DefaultCookieConfig())
+	require.NoError(t, err)
+	csrf := func(origin string) int {
+		h := app.csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest(http.MethodPost, "http://api.internal/", nil)
+		req.Header.Set("Origin", origin)
+		req = req.WithContext(context.WithValue(req.Context(), cookies.AuthMethodKey, cookies.AuthByCookie))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusForbidden, csrf("https://sho.rt"))
+
+	// Доверенная подсеть проверяется по адресу соединения
+	stats := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/debug/stats", nil)
+		req.RemoteAddr = "10.1.2.3:40000"
+		w := httptest.NewRecorder()
+		diag.ServeHTTP(w, req)
+		return w.Code
+	}
+	require.Equal(t, http.StatusForbidden, stats(), "без доверенной подсети доступ закрыт")
+
+	app.auth = cookies.NewAuth(cookies.AuthConfig{Cookie: cookies.DefaultCookieConfig()})
+	token, err := cookies.BuildJWTString("user")
+	require.NoError(t, err)
+	role := func() string {
+		var got string
+		h := app.auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = cookies.RoleFromContext(r.Context())
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: cookies.TokenCookieName, Value: token})
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+	require.Equal(t, cookies.RoleUser, role())
+
+	next, err := config.Load([]string{"-a", ":9090", "-diag-token", "secret", "-b", "https://sho.rt", "-t", "10.0.0.0/8", "-admin-users", "user"}, testEnv)
+	require.NoError(t, err)
+
+	applied, restart := app.Reload(next)
+	assert.ElementsMatch(t, []string{"base_url", "trusted_subnet", "admin_users"}, applied)
+	assert.Equal(t, []string{"server_address"}, restart)
+	assert.Equal(t, ":8080", app.GetConfig().Host)
+
+	assert.Equal(t, http.StatusOK, stats())
+
+	// Новый список администраторов применяется без перезапуска
+	assert.Equal(t, cookies.RoleAdmin, role())
+
+	// Новый базовый URL становится доверенным Origin
+	assert.Equal(t, http.StatusOK, csrf("https://sho.rt"))
+
+	// Новый базовый URL используется в ответах
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/reload"))
+	req = req.WithContext(context.WithValue(req.Context(), cookies.SecretKey, "user"))
+	w := httptest.NewRecorder()
+	app.GetShortURL(w, req)
+	require.Equal(t, http.StatusCreated, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "https://sho.rt/"), w.Body.String())
+}
@@ -5,18 +5,30 @@ import (
 	"context"
 	"io"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"os/signal"
 	"strings"
 	"sync"
 	"syscall"
 	"time"
 
-	_ "net/http/pprof"
-
 	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/buildinfo"
 	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/linkpolicy"
 	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/metrics"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/openapi"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/recovery"
+	"github.com/GevorkovG/go-shortener-tlp/internal/requestid"
+	"github.com/GevorkovG/go-shortener-tlp/internal/security"
+	"github.com/GevorkovG/go-shortener-tlp/internal/tlsconfig"
+	"github.com/GevorkovG/go-shortener-tlp/internal/tracing"
 	"github.com/go-chi/chi"
 	"go.uber.org/zap"
 )
@@ -147,6 +159,76 @@ func (c *compressReader) Close() error {
 	return c.zr.Close()
 }
 
+// cookieConfig собирает атрибуты cookie токена из конфигурации.
+// При включенном HTTPS атрибут Secure выставляется всегда.
+func cookieConfig(conf *config.AppConfig) (cookies.CookieConfig, error) {
+	sameSite, err := cookies.ParseSameSite(conf.CookieSameSite)
+	if err != nil {
+		return cookies.CookieConfig{}, err
+	}
+
+	return cookies.CookieConfig{
+		Secure:   conf.CookieSecure || conf.EnableHTTPS,
+		HTTPOnly: conf.CookieHTTPOnly,
+		SameSite: sameSite,
+		MaxAge:   conf.CookieMaxAge,
+	}, nil
+}
+
+// csrfOrigins возвращает доверенные Origin: базовый URL сервиса
+// и дополнительные значения из конфигурации.
+func csrfOrigins(conf *config.AppConfig) []string {
+	origins := []string{conf.ResultURL}
+	if conf.CSRFTrustedOrigins != "" {
+		origins = append(origins, strings.Split(conf.CSRFTrustedOrigins, ",")...)
+	}
+	return origins
+}
+
+// tlsOptions собирает настройки TLS из конфигурации. Самоподписанный
+// сертификат выписывается на хост адреса сервера и хост базового URL.
+func tlsOptions(conf *config.AppConfig) tlsconfig.Options {
+	var hosts []string
+	seen := make(map[string]bool)
+	add := func(h string) {
+		if h != "" && !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+	if host, _, err := net.SplitHostPort(conf.Host); err == nil {
+		add(host)
+	}
+	if u, err := url.Parse(conf.ResultURL); err == nil {
+		add(u.Hostname())
+	}
+
+	var ciphers []string
+	if conf.TLSCipherSuites != "" {
+		ciphers = strings.Split(conf.TLSCipherSuites, ",")
+	}
+
+	return tlsconfig.Options{
+		CertFile:     conf.TLSCertFile,
+		KeyFile:      conf.TLSKeyFile,
+		MinVersion:   conf.TLSMinVersion,
+		CipherSuites: ciphers,
+		SelfSigned:   conf.TLSSelfSigned,
+		Hosts:        hosts,
+	}
+}
+
+// httpsRedirect возвращает обработчик перенаправления на HTTPS-сервер.
+// Если базовый URL использует https, перенаправление ведет на его хост,
+// иначе — на хост из запроса и порт адреса сервера.
+func httpsRedirect(conf *config.AppConfig) http.Handler {
+	if u, err := url.Parse(conf.ResultURL); err == nil && u.Scheme == "https" {
+		return security.RedirectToHTTPS(u.Hostname(), u.Port())
+	}
+	_, port, _ := net.SplitHostPort(conf.Host)
+	return security.RedirectToHTTPS("", port)
+}
+
 func gzipMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// по умолчанию устанавливаем оригинальный http.ResponseWriter как тот,
@@ -162,11 +244,10 @@ func gzipMiddleware(h http.Handler) http.Handler {
 			// меняем оригинальный http.ResponseWriter на новый
 			ow = cw
 			// не забываем отправить клиенту все сжатые данные после завершения middleware
+			// ответ к этому моменту уже отправлен, поэтому ошибку можно только записать в лог
 			defer func(cw *compressWriter) {
-				err := cw.Close()
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					return
+				if err := cw.Close(); err != nil {
+					logg.FromContext(r.Context()).Error("Failed to close gzip writer", zap.Error(err))
 				}
 			}(cw)
 		}
@@ -178,16 +259,15 @@ func gzipMiddleware(h http.Handler) http.Handler {
 			// оборачиваем тело запроса в io.Reader с поддержкой декомпрессии
 			cr, err := newCompressReader(r.Body)
 			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
+				logg.FromContext(r.Context()).Warn("Failed to read gzip request body", zap.Error(err))
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "request body is not valid gzip")
 				return
 			}
 			// меняем тело запроса на новое
 			r.Body = cr
 			defer func(cr *compressReader) {
-				err := cr.Close()
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					return
+				if err := cr.Close(); err != nil {
+					logg.FromContext(r.Context()).Error("Failed to close gzip reader", zap.Error(err))
 				}
 			}(cr)
 		}
@@ -203,7 +283,7 @@ func gzipMiddleware(h http.Handler) http.Handler {
 //   - Инициализацию логгера
 //   - Настройку роутера с middleware
 //   - Запуск основного сервера
-//   - Запуск pprof сервера для профилирования
+//   - Запуск служебного сервера с pprof для профилирования
 //
 // Конфигурация:
 //   - Использует флаги командной строки и переменные окружения
@@ -211,23 +291,82 @@ func gzipMiddleware(h http.Handler) http.Handler {
 //
 // Middleware:
 //   - Логирование запросов (LoggerMiddleware)
+//   - Метрики запросов по шаблонам маршрутов (metrics.Middleware)
+//   - Заголовки безопасности: HSTS, Referrer-Policy, X-Content-Type-Options,
+//     CSP для HTML-ответов (security.Headers)
 //   - Поддержка gzip сжатия (gzipMiddleware)
-//   - Обработка cookies (cookies.Cookies)
+//   - Аутентификация по cookie или API-ключу (cookies.Auth)
+//   - Защита от CSRF для изменяющих запросов (cookies.CSRF)
+//   - Проверка тела запросов по описанию OpenAPI (openapi.Spec.Middleware)
 //
 // Роуты:
 //
 //	POST /api/shorten       - Сокращение URL через JSON API (JSONGetShortURL)
 //	GET  /{id}              - Получение оригинального URL (GetOriginalURL)
 //	GET  /ping              - Проверка доступности сервера (Ping)
+//	GET  /api/version       - Версия сборки, хранилище и время работы (APIVersion)
+//	GET  /api/openapi.json  - Описание API в формате OpenAPI 3 (openapi.Handler)
+//	GET  /healthz           - Проверка живости процесса (Healthz)
+//	GET  /readyz            - Проверка готовности по компонентам (Readyz)
 //	POST /                  - Сокращение URL через форму (GetShortURL)
 //	POST /api/shorten/batch - Пакетное сокращение URL (APIshortBatch)
 //	GET  /api/user/urls     - Получение URL пользователя (APIGetUserURLs)
 //	DELETE /api/user/urls   - Удаление URL пользователя (APIDeleteUserURLs)
+//	POST /api/user/keys     - Создание API-ключа (APICreateKey)
+//	GET  /api/user/keys     - Список API-ключей пользователя (APIGetKeys)
+//	DELETE /api/user/keys/{id} - Отзыв API-ключа (APIRevokeKey)
+//	POST /api/user/claim    - Перенос ссылок с прежнего токена (APIClaimLinks)
+//	POST /api/user/logout   - Завершение сессии и отзыв токена (APILogout)
+//	POST /api/user/webhooks - Подписка на события ссылок (APICreateWebhook)
+//	GET  /api/user/webhooks - Список подписок пользователя (APIGetWebhooks)
+//	DELETE /api/user/webhooks/{id} - Удаление подписки (APIDeleteWebhook)
+//	GET  /api/user/webhooks/{id}/deliveries - История доставок (APIGetWebhookDeliveries)
+//	POST /api/user/webhooks/{id}/deliveries/{deliveryID}/redeliver - Повторная доставка (APIRedeliverWebhook)
+//
+// Административные роуты (только для роли admin, иначе 403):
+//
+//	GET  /api/admin/urls               - Поиск по всем ссылкам (AdminListURLs)
+//	POST /api/admin/urls/{id}/disable  - Отключение ссылки (AdminDisableURL)
+//	POST /api/admin/urls/{id}/restore  - Восстановление ссылки (AdminRestoreURL)
+//	GET  /api/admin/users              - Пользователи и число их ссылок (AdminListUsers)
 //
 // Особенности:
-//   - Запускает параллельный pprof сервер на :6060
+//   - Выводит сведения о сборке при старте; с флагом -version выводит их
+//     и завершает работу, не запуская сервер
+//   - Запускает служебный сервер (по умолчанию localhost:6060) с pprof,
+//     метриками Prometheus (/metrics), конфигурацией и статистикой; доступ по токену (-diag-token),
+//     из доверенной подсети или, если ни то ни другое не задано, только
+//     с loopback-адресов; отключается флагом -diag=false
+//   - С флагом -s обслуживает HTTPS: пути к сертификату и ключу, минимальная
+//     версия TLS и наборы шифров настраиваются; с -tls-self-signed сертификат
+//     выпускается и кэшируется автоматически; при изменении файлов
+//     сертификат перечитывается без перезапуска
+//   - С -http-redirect дополнительно слушает HTTP и перенаправляет
+//     запросы на HTTPS с сохранением пути короткой ссылки
+//   - По SIGHUP перечитывает конфигурацию и применяет уровень логирования,
+//     базовый URL и доверенную подсеть без перезапуска
+//   - С -trace-exporter отправляет трассировку OpenTelemetry по OTLP
+//     (-trace-endpoint) или в stdout: спан запроса с учетом входящего
+//     traceparent, спаны middleware авторизации и CSRF, обработчика,
+//     операций хранилища и SQL-запросов
+//   - Каждому запросу присваивается X-Request-ID (принимается от клиента
+//     или создается), он возвращается в ответе и добавляется ко всем
+//     записям лога запроса, включая записи хранилища
+//   - Персональные данные в логе скрываются: UserID хэшируется, у URL
+//     удаляется строка запроса, токены не пишутся (-log-hash-user-ids,
+//     -log-urls, -log-max-items; меняются по SIGHUP)
+//   - Паника в обработчике не обрывает соединение: стек пишется в лог
+//     с X-Request-ID, клиент получает JSON с кодом 500
+//   - Перед сохранением из URL удаляются параметры отслеживания (utm_*,
+//     fbclid, gclid и другие; -url-strip-params, -url-keep-params),
+//     с -url-sort-query параметры упорядочиваются по имени
+//   - С -link-policy проверяет адреса по правилам из файла при сокращении
+//     и при перенаправлении (403, код url_denied); файл перечитывается
+//     при изменении и по SIGHUP
 //   - Детально логирует параметры старта
-//   - Использует zap для структурированного логгирования
+//   - Использует zap для структурированного логгирования: уровень, формат
+//     json или console, файл с ротацией и сэмплирование настраиваются
+//     (-log-level, -log-format, -log-file, -log-max-size, -log-sampling)
 //
 // Пример запуска:
 //
@@ -236,40 +375,146 @@ func gzipMiddleware(h http.Handler) http.Handler {
 // Для профилирования:
 //
 //	go tool pprof http://localhost:6060/debug/pprof/profile
-func Run() {
+//
+// С токеном:
+//
+//	curl -H "Authorization: Bearer $DIAG_TOKEN" http://host:6060/debug/pprof/heap > heap.pprof
+func Run(build buildinfo.Info) {
 
 	conf := config.NewCfg()
-	logg.InitLogger() // Инициализация логгера
-	defer func() {
-		// Принудительно сбрасываем буфер логов
-		if err := zap.L().Sync(); err != nil {
-			// Sync может возвращать ошибку для stderr (это нормально)
-			log.Printf("Failed to sync zap logs: %v", err)
+	if conf.PrintVersion {
+		if err := build.Print(os.Stdout); err != nil {
+			log.Fatalf("Failed to print version: %v", err)
 		}
-		log.Println("Server shutdown completed")
+		return
+	}
+	if err := build.Print(os.Stdout); err != nil {
+		log.Printf("Failed to print build information: %v", err)
+	}
+
+	// Логгер создается до остальных компонентов и устанавливается
+	// глобально; до этого момента zap.L() ничего не пишет
+	if err := logg.Init(logg.Options{
+		Level:      conf.LogLevel,
+		Format:     conf.LogFormat,
+		File:       conf.LogFile,
+		MaxSizeMB:  conf.LogMaxSizeMB,
+		MaxBackups: conf.LogMaxBackups,
+		MaxAgeDays: conf.LogMaxAgeDays,
+		Compress:   conf.LogCompress,
+		Sampling:   conf.LogSampling,
+		Redact:     redactPolicy(conf),
+	}); err != nil {
+		log.Fatalf("Invalid logging configuration: %v", err)
+	}
+	defer func() {
+		zap.L().Info("Server shutdown completed")
+		// Принудительно сбрасываем буфер логов; для stderr Sync
+		// может вернуть ошибку, это нормально
+		_ = zap.L().Sync()
 	}()
 
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Options{
+		Exporter:    conf.TraceExporter,
+		Endpoint:    conf.TraceEndpoint,
+		SampleRatio: conf.TraceSampleRatio,
+		ServiceName: "shortener",
+	})
+	if err != nil {
+		zap.L().Fatal("Invalid tracing configuration", zap.Error(err))
+	}
+
+	// Пустой или общеизвестный ключ отклоняет config.Validate; проверка
+	// повторяется здесь, чтобы сервер не запустился с ключом по умолчанию
+	if conf.JWTSecret == "" {
+		zap.L().Fatal("jwt_secret is required to sign user tokens")
+	}
+	cookies.SetSigningKey(conf.JWTSecret)
+
 	newApp := NewApp(conf)
+	newApp.build = build
+	keys, _ := newApp.apiKeys()
+	revocations, _ := newApp.Storage.(objects.RevocationStorage)
+
+	cookieCfg, err := cookieConfig(conf)
+	if err != nil {
+		zap.L().Fatal("Invalid cookie configuration", zap.Error(err))
+	}
+
+	csrf, err := cookies.NewCSRF(conf.CSRFMode, csrfOrigins(conf), cookieCfg)
+	if err != nil {
+		zap.L().Fatal("Invalid CSRF configuration", zap.Error(err))
+	}
+	newApp.csrf = csrf
+	newApp.auth = cookies.NewAuth(cookies.AuthConfig{
+		Keys:        keys,
+		Revocations: revocations,
+		Cookie:      cookieCfg,
+		Admins:      strings.Split(conf.AdminUsers, ","),
+	})
+
+	headers, err := security.NewHeaders(security.HeadersConfig{
+		HSTSMaxAge:            conf.HSTSMaxAge,
+		HSTSIncludeSubdomains: conf.HSTSIncludeSubdomains,
+		HSTSPreload:           conf.HSTSPreload,
+		ReferrerPolicy:        conf.ReferrerPolicy,
+		ContentSecurityPolicy: conf.ContentSecurityPolicy,
+	})
+	if err != nil {
+		zap.L().Fatal("Invalid security headers configuration", zap.Error(err))
+	}
+
 	r := chi.NewRouter()
-	r.Use(logg.LoggerMiddleware,
+	r.Use(tracing.Middleware,
+		requestid.Middleware,
+		logg.LoggerMiddleware,
+		metrics.Middleware,
+		recovery.Middleware,
+		headers.Middleware,
 		gzipMiddleware,
-		cookies.Cookies,
+		tracing.Step("auth", newApp.auth.Middleware),
+		tracing.Step("csrf", csrf.Middleware),
+		tracing.Step("validate", openapi.Default().Middleware),
+		tracing.Handler,
 	)
 
 	// Логируем информацию о запуске сервера
-	logg.Logger.Info("Starting server",
+	zap.L().Info("Starting server",
 		zap.String("host", conf.Host),
-		zap.String("pprof_host", "localhost:6060"),
+		zap.String("diag_host", conf.DiagAddr),
+		zap.Bool("diag_enabled", conf.DiagEnabled),
 		zap.String("base_url", conf.ResultURL),
 	)
 
 	r.Post("/api/shorten", newApp.JSONGetShortURL)
 	r.Get("/{id}", newApp.GetOriginalURL)
 	r.Get("/ping", newApp.Ping)
+	r.Get("/healthz", newApp.Healthz)
+	r.Get("/api/version", newApp.APIVersion)
+	r.Get("/api/openapi.json", openapi.Handler)
+	r.Get("/readyz", newApp.Readyz)
 	r.Post("/", newApp.GetShortURL)
 	r.Post("/api/shorten/batch", newApp.APIshortBatch)
 	r.Get("/api/user/urls", newApp.APIGetUserURLs)
 	r.Delete("/api/user/urls", newApp.APIDeleteUserURLs)
+	r.Post("/api/user/keys", newApp.APICreateKey)
+	r.Get("/api/user/keys", newApp.APIGetKeys)
+	r.Delete("/api/user/keys/{id}", newApp.APIRevokeKey)
+	r.Post("/api/user/claim", newApp.APIClaimLinks)
+	r.Post("/api/user/logout", newApp.APILogout)
+	r.Post("/api/user/webhooks", newApp.APICreateWebhook)
+	r.Get("/api/user/webhooks", newApp.APIGetWebhooks)
+	r.Delete("/api/user/webhooks/{id}", newApp.APIDeleteWebhook)
+	r.Get("/api/user/webhooks/{id}/deliveries", newApp.APIGetWebhookDeliveries)
+	r.Post("/api/user/webhooks/{id}/deliveries/{deliveryID}/redeliver", newApp.APIRedeliverWebhook)
+
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(cookies.RequireRole(cookies.RoleAdmin))
+		r.Get("/urls", newApp.AdminListURLs)
+		r.Post("/urls/{id}/disable", newApp.AdminDisableURL)
+		r.Post("/urls/{id}/restore", newApp.AdminRestoreURL)
+		r.Get("/users", newApp.AdminListUsers)
+	})
 
 	// Создание HTTP сервера с таймаутами
 	srv := &http.Server{
@@ -280,17 +525,57 @@ func Run() {
 		IdleTimeout:  30 * time.Second,
 	}
 
-	pprofServer := &http.Server{Addr: ":6060"}
+	var certs *tlsconfig.Reloader
+	if conf.EnableHTTPS {
+		srv.TLSConfig, certs, err = tlsconfig.New(tlsOptions(conf))
+		if err != nil {
+			zap.L().Fatal("Invalid TLS configuration", zap.Error(err))
+		}
+	}
+
+	// Служебный сервер с pprof и эндпоинтами для операторов
+	var diagServer *http.Server
+	if conf.DiagEnabled {
+		diagServer = &http.Server{
+			Addr:        conf.DiagAddr,
+			Handler:     newApp.DiagRouter(),
+			ReadTimeout: 10 * time.Second,
+			IdleTimeout: 30 * time.Second,
+		}
+	}
+
+	// Перенаправление с HTTP на HTTPS
+	var redirectServer *http.Server
+	if conf.EnableHTTPS && conf.HTTPRedirectAddr != "" {
+		redirectServer = &http.Server{
+			Addr:         conf.HTTPRedirectAddr,
+			Handler:      httpsRedirect(conf),
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+			IdleTimeout:  30 * time.Second,
+		}
+	}
 
 	// Создаем WaitGroup для ожидания завершения серверов
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(1)
 
 	// Создаем контекст для graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(),
 		syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
 	defer stop()
 
+	// Перезагрузка конфигурации по SIGHUP
+	go newApp.reloadOnSIGHUP(ctx, os.Args[1:])
+
+	// Перечитывание сертификата при изменении файлов
+	if certs != nil {
+		go certs.Watch(ctx, tlsconfig.DefaultReloadInterval)
+	}
+
+	// Перечитывание политики ссылок при изменении файла
+	go newApp.policy.Watch(ctx, linkpolicy.DefaultReloadInterval)
+
 	// Запуск основного сервера
 	go func() {
 		defer wg.Done()
@@ -302,7 +587,8 @@ func Run() {
 
 		var err error
 		if conf.EnableHTTPS {
-			err = srv.ListenAndServeTLS("./certs/cert.pem", "./certs/key.pem")
+			// Сертификат берется из srv.TLSConfig.GetCertificate
+			err = srv.ListenAndServeTLS("", "")
 		} else {
 			err = srv.ListenAndServe()
 		}
@@ -313,20 +599,44 @@ func Run() {
 		}
 	}()
 
-	// Запуск pprof сервера
-	go func() {
-		defer wg.Done()
+	// Запуск сервера перенаправления
+	if redirectServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-		zap.L().Info("Starting pprof server", zap.String("address", ":6060"))
-		if err := pprofServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			zap.L().Error("Pprof server error", zap.Error(err))
-		}
-	}()
+			zap.L().Info("Starting HTTP to HTTPS redirect server", zap.String("address", redirectServer.Addr))
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				zap.L().Error("Redirect server error", zap.Error(err))
+			}
+		}()
+	}
+
+	// Запуск служебного сервера
+	if diagServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			zap.L().Info("Starting diagnostics server", zap.String("address", diagServer.Addr))
+			if err := diagServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				zap.L().Error("Diagnostics server error", zap.Error(err))
+			}
+		}()
+	}
 
 	// Ожидаем сигнал завершения или ошибку сервера
 	<-ctx.Done()
 	zap.L().Info("Received shutdown signal")
 
+	// Сначала сообщаем балансировщику о неготовности и даем ему время
+	// перестать направлять запросы, затем закрываем слушатели
+	newApp.SetShuttingDown()
+	if conf.ShutdownDrainDelay > 0 {
+		zap.L().Info("Draining traffic before shutdown", zap.Duration("delay", conf.ShutdownDrainDelay))
+		time.Sleep(conf.ShutdownDrainDelay)
+	}
+
 	// Graceful shutdown с таймаутом
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -336,12 +646,31 @@ func Run() {
 		zap.L().Error("Main server shutdown error", zap.Error(err))
 	}
 
-	// Останавливаем pprof сервер
-	if err := pprofServer.Shutdown(shutdownCtx); err != nil {
-		zap.L().Error("Pprof server shutdown error", zap.Error(err))
+	// Останавливаем сервер перенаправления
+	if redirectServer != nil {
+		if err := redirectServer.Shutdown(shutdownCtx); err != nil {
+			zap.L().Error("Redirect server shutdown error", zap.Error(err))
+		}
+	}
+
+	// Останавливаем служебный сервер
+	if diagServer != nil {
+		if err := diagServer.Shutdown(shutdownCtx); err != nil {
+			zap.L().Error("Diagnostics server shutdown error", zap.Error(err))
+		}
+	}
+
+	// Дожидаемся доставки событий аудита и вебхуков
+	if err := newApp.Close(shutdownCtx); err != nil {
+		zap.L().Error("App shutdown error", zap.Error(err))
 	}
 
 	// Ждем завершения всех горутин
 	wg.Wait()
+
+	// Выгружаем оставшиеся спаны
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		zap.L().Error("Tracing shutdown error", zap.Error(err))
+	}
 	zap.L().Info("Server stopped gracefully")
 }
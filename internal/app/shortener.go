@@ -1,19 +1,25 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"math/rand"
 	"net/http"
 	"strings"
 
+	"github.com/GevorkovG/go-shortener-tlp/internal/audit"
 	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/linkpolicy"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/metrics"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
-	"github.com/GevorkovG/go-shortener-tlp/internal/services/usertoken"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
 	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
+	"github.com/GevorkovG/go-shortener-tlp/internal/urlnorm"
+	"github.com/GevorkovG/go-shortener-tlp/internal/webhook"
 	"go.uber.org/zap"
 
 	"github.com/go-chi/chi"
@@ -32,6 +38,42 @@ func generateID() string {
 	return encodedBuilder.String()
 }
 
+// canonicalURL проверяет URL для сокращения и приводит его к канонической
+// форме по настройкам url_max_length, url_drop_fragment, url_strip_params,
+// url_keep_params и url_sort_query (см. urlnorm), затем проверяет
+// каноническую форму по политике ссылок (см. linkpolicy).
+// Сохраняется и сравнивается при поиске дубликатов каноническая форма.
+func (a *App) canonicalURL(ctx context.Context, raw string) (string, error) {
+	cfg := a.GetConfig()
+	// Шаблоны проверены при загрузке конфигурации (config.Validate)
+	strip, _ := urlnorm.ParsePatterns(cfg.URLStripParams)
+	keep, _ := urlnorm.ParsePatterns(cfg.URLKeepParams)
+	canonical, err := urlnorm.Canonicalize(raw, urlnorm.Options{
+		MaxLength:    cfg.URLMaxLength,
+		DropFragment: cfg.URLDropFragment,
+		StripParams:  strip,
+		KeepParams:   keep,
+		SortQuery:    cfg.URLSortQuery,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := a.policy.Check(ctx, canonical); err != nil {
+		return "", err
+	}
+	return canonical, nil
+}
+
+// rejectURL отвечает на ошибку canonicalURL: 403 с кодом url_denied,
+// если адрес запрещен политикой, иначе 400 с кодом invalid_url
+func rejectURL(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, linkpolicy.ErrDenied) {
+		problem.Write(w, r, http.StatusForbidden, problem.CodeURLDenied, err.Error())
+		return
+	}
+	problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidURL, err.Error())
+}
+
 // Request представляет входящий запрос на сокращение URL.
 // Используется в JSON API эндпоинтах.
 //
@@ -53,14 +95,21 @@ type Request struct {
 // Поля:
 //   - Result string `json:"result"`: сокращенный URL в формате:
 //     http(s)://<домен>/<короткий-идентификатор>
+//   - Submitted string `json:"submitted_url"`: URL в том виде, в каком его прислал клиент
+//   - Stored string `json:"stored_url"`: сохраненный URL после канонизации
+//     и удаления параметров отслеживания; на него ведет перенаправление
 //
 // Пример:
 //
 //	{
-//	  "result": "http://short.ly/abc123"
+//	  "result": "http://short.ly/abc123",
+//	  "submitted_url": "https://Example.com/page?utm_source=mail&id=7",
+//	  "stored_url": "https://example.com/page?id=7"
 //	}
 type Response struct {
-	Result string `json:"result"`
+	Result    string `json:"result"`
+	Submitted string `json:"submitted_url"`
+	Stored    string `json:"stored_url"`
 }
 
 // JSONGetShortURL обрабатывает запрос на сокращение URL в JSON формате.
@@ -83,11 +132,13 @@ type Response struct {
 //
 // Логика работы:
 //  1. Извлекает UserID из токена аутентификации (если есть)
-//  2. Проверяет валидность входного JSON
+//  2. Проверяет валидность входного JSON и URL, приводит URL
+//     к канонической форме (см. canonicalURL)
 //  3. Генерирует уникальный идентификатор для URL
 //  4. Сохраняет связь URL-идентификатор в хранилище:
 //     - Если URL уже существует, возвращает существующий сокращенный URL
-//  5. Возвращает сокращенный URL в формате: {базовый_URL}/{идентификатор}
+//  5. Возвращает сокращенный URL в формате: {базовый_URL}/{идентификатор},
+//     присланный и сохраненный URL
 //
 // Пример запроса:
 //
@@ -95,14 +146,18 @@ type Response struct {
 //	Content-Type: application/json
 //	Authorization: Bearer <token>
 //
-//	{"url": "https://example.com/very/long/path"}
+//	{"url": "https://example.com/very/long/path?utm_source=mail"}
 //
 // Пример ответа:
 //
 //	HTTP/1.1 201 Created
 //	Content-Type: application/json
 //
-//	{"result": "http://short.ly/abc123"}
+//	{
+//	  "result": "http://short.ly/abc123",
+//	  "submitted_url": "https://example.com/very/long/path?utm_source=mail",
+//	  "stored_url": "https://example.com/very/long/path"
+//	}
 //
 // Особенности:
 //   - Поддерживает аутентификацию через токен
@@ -112,61 +167,58 @@ type Response struct {
 func (a *App) JSONGetShortURL(w http.ResponseWriter, r *http.Request) {
 	var req Request
 	var status = http.StatusCreated
-	var UserID string
-
-	// Извлекаем UserID из контекста
-	if token, ok := r.Context().Value(cookies.SecretKey).(string); ok {
-		var err error
-		if UserID, err = usertoken.GetUserID(token); err != nil {
-			zap.L().Warn("Failed to get UserID from token", zap.Error(err))
-			UserID = ""
-		}
-	} else {
-		zap.L().Warn("Token not found or not a string",
-			zap.Any("token", r.Context().Value(cookies.SecretKey)))
-		UserID = ""
-	}
 
 	// Декодируем тело запроса
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, `request body must be {"url": "..."}`)
+		return
+	}
+
+	original, err := a.canonicalURL(r.Context(), req.URL)
+	if err != nil {
+		rejectURL(w, r, err)
 		return
 	}
 
 	// Создаем объект Link
 	link := &objects.Link{
 		Short:    generateID(),
-		Original: req.URL,
-		UserID:   UserID, // Устанавливаем UserID
+		Original: original,
+		UserID:   contextUserID(r), // Владелец из cookie или API-ключа
 	}
 
 	// Сохраняем ссылку в хранилище
 	if err = a.Storage.Insert(r.Context(), link); err != nil {
 		if errors.Is(err, storage.ErrConflict) {
 			// Если URL уже существует, получаем существующий короткий URL
-			link, err = a.Storage.GetShort(link.Original)
+			link, err = a.Storage.GetShort(r.Context(), link.Original)
 			if err != nil {
-				zap.L().Error("Failed to get short URL", zap.Error(err))
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				problem.Internal(w, r, "Failed to get short URL", err)
 				return
 			}
 			status = http.StatusConflict
 		} else {
-			zap.L().Error("Failed to insert URL", zap.Error(err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, "Failed to insert URL", err)
 			return
 		}
 	}
 
+	if status == http.StatusCreated {
+		a.emitAudit(r, audit.EventCreate, []string{link.Short}, []string{link.Original})
+		a.publishWebhook(contextUserID(r), webhook.EventLinkCreated, []string{link.Short}, []string{link.Original})
+	}
+
 	// Формируем ответ
 	result := Response{
-		Result: strings.TrimSpace(fmt.Sprintf("%s/%s", a.cfg.ResultURL, link.Short)),
+		Result:    strings.TrimSpace(fmt.Sprintf("%s/%s", a.GetConfig().ResultURL, link.Short)),
+		Submitted: req.URL,
+		Stored:    link.Original,
 	}
 
 	response, err := json.Marshal(result)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		problem.Internal(w, r, "Failed to marshal response", err)
 		return
 	}
 
@@ -175,7 +227,7 @@ func (a *App) JSONGetShortURL(w http.ResponseWriter, r *http.Request) {
 
 	_, err = w.Write(response)
 	if err != nil {
-		zap.L().Error("Failed to write response", zap.Error(err))
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
 		return
 	}
 }
@@ -197,7 +249,8 @@ func (a *App) JSONGetShortURL(w http.ResponseWriter, r *http.Request) {
 //	"http://short.url/abc123" (Статус 201 или 409)
 //
 // Возможные ошибки:
-//   - 400: Неверный формат запроса или пустое тело
+//   - 400: Пустое тело или некорректный URL (код invalid_url, см. canonicalURL)
+//   - 403: Адрес запрещен политикой ссылок (код url_denied)
 //   - 500: Внутренняя ошибка сервера
 func (a *App) GetShortURL(w http.ResponseWriter, r *http.Request) {
 	var status = http.StatusCreated
@@ -206,59 +259,69 @@ func (a *App) GetShortURL(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(cookies.SecretKey).(string)
 	if !ok || userID == "" {
 		// Если UserID не найден в контексте, логируем ошибку и продолжаем без него
-		zap.L().Error("UserID not found in context")
+		logg.FromContext(r.Context()).Error("UserID not found in context")
 		userID = "" // Устанавливаем пустой UserID
 	}
 
-	zap.L().Debug("internal/app/shortener.go ",
-		zap.String("userID", userID),
+	logg.FromContext(r.Context()).Debug("internal/app/shortener.go ",
+		logg.UserID(userID),
 	)
 
 	responseData, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("cannot read request body: %s", err), http.StatusBadRequest)
+		logg.FromContext(r.Context()).Warn("Failed to read request body", zap.Error(err))
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "cannot read request body")
 		return
 	}
 	if string(responseData) == "" {
-		http.Error(w, "Empty POST request body!", http.StatusBadRequest)
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "request body must contain a URL")
+		return
+	}
+
+	original, err := a.canonicalURL(r.Context(), string(responseData))
+	if err != nil {
+		rejectURL(w, r, err)
 		return
 	}
 
 	link := &objects.Link{
 		Short:    generateID(),
-		Original: string(responseData),
+		Original: original,
 		UserID:   userID, // Устанавливаем UserID
 	}
 
-	zap.L().Debug("internal/app/shortener.go GetShortURL",
-		zap.String("userID", link.UserID),
+	logg.FromContext(r.Context()).Debug("internal/app/shortener.go GetShortURL",
+		logg.UserID(link.UserID),
 		zap.String("short", link.Short),
-		zap.String("original", link.Original),
+		logg.URL("original", link.Original),
 	)
 
 	if err = a.Storage.Insert(r.Context(), link); err != nil {
 		if errors.Is(err, storage.ErrConflict) {
-			link, err = a.Storage.GetShort(link.Original)
+			link, err = a.Storage.GetShort(r.Context(), link.Original)
 			if err != nil {
-				zap.L().Error("Don't get short URL", zap.Error(err))
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+				problem.Internal(w, r, "Don't get short URL", err)
 				return
 			}
 			status = http.StatusConflict
 		} else {
-			zap.L().Error("Don't insert URL", zap.Error(err))
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			problem.Internal(w, r, "Don't insert URL", err)
 			return
 		}
 	}
 
-	response := strings.TrimSpace(fmt.Sprintf("%s/%s", a.cfg.ResultURL, link.Short))
+	if status == http.StatusCreated {
+		a.emitAudit(r, audit.EventCreate, []string{link.Short}, []string{link.Original})
+		a.publishWebhook(contextUserID(r), webhook.EventLinkCreated, []string{link.Short}, []string{link.Original})
+	}
+
+	response := strings.TrimSpace(fmt.Sprintf("%s/%s", a.GetConfig().ResultURL, link.Short))
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(status)
 
 	_, err = io.WriteString(w, response)
 	if err != nil {
-		zap.L().Error("Didn't write response", zap.Error(err))
+		logg.FromContext(r.Context()).Error("Didn't write response", zap.Error(err))
 		return
 	}
 }
@@ -266,7 +329,7 @@ func (a *App) GetShortURL(w http.ResponseWriter, r *http.Request) {
 // GetOriginalURL обрабатывает запросы на перенаправление по короткому URL.
 //
 // При успешном выполнении возвращает 307 (Temporary Redirect) с Location на оригинальный URL.
-// Если URL помечен как удаленный, возвращает 410 (Gone).
+// Если URL помечен как удаленный или отключен администратором, возвращает 410 (Gone).
 // Если URL не найден, возвращает 400 (Bad Request).
 //
 // Пример запроса:
@@ -280,24 +343,46 @@ func (a *App) GetShortURL(w http.ResponseWriter, r *http.Request) {
 //
 // Возможные ошибки:
 //   - 400: Неверный идентификатор короткого URL
+//   - 403: Адрес запрещен политикой ссылок, которая изменилась после
+//     создания ссылки (код url_denied)
 //   - 410: URL был удален
 func (a *App) GetOriginalURL(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	link, err := a.Storage.GetOriginal(id)
-	log.Printf("GetOriginalURL short:%s %t", link.Short, link.DeletedFlag)
+	link, err := a.Storage.GetOriginal(r.Context(), id)
 	if err != nil {
-		zap.L().Error("Failed to get original URL", zap.String("id", id), zap.Error(err))
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
+		logg.FromContext(r.Context()).Error("Failed to get original URL", zap.String("id", id), zap.Error(err))
+		metrics.Redirect(metrics.RedirectNotFound)
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeNotFound, "short URL not found")
+		return
+	}
+
+	logg.FromContext(r.Context()).Debug("GetOriginalURL",
+		zap.String("short", link.Short),
+		zap.Bool("deleted", link.DeletedFlag))
+
+	// Проверяем, удален ли URL или отключен администратором
+	if link.DeletedFlag || link.Disabled || link.Original == "" {
+		metrics.Redirect(metrics.RedirectGone)
+		problem.Write(w, r, http.StatusGone, problem.CodeGone, "")
 		return
 	}
 
-	// Проверяем, удален ли URL
-	if link.DeletedFlag || link.Original == "" {
-		w.WriteHeader(http.StatusGone)
+	// Политика могла измениться после создания ссылки. Имя хоста через DNS
+	// не разрешается: это сделано при создании, а перенаправление не должно
+	// ждать ответа DNS-сервера
+	if err := a.policy.CheckRules(link.Original); err != nil {
+		logg.FromContext(r.Context()).Info("Redirect blocked by link policy",
+			zap.String("short", link.Short), zap.Error(err))
+		metrics.Redirect(metrics.RedirectBlocked)
+		problem.Write(w, r, http.StatusForbidden, problem.CodeURLDenied, err.Error())
 		return
 	}
 
+	a.emitAudit(r, audit.EventRedirect, []string{link.Short}, []string{link.Original})
+	a.publishWebhook(link.UserID, webhook.EventLinkFirstClicked, []string{link.Short}, []string{link.Original})
+
+	metrics.Redirect(metrics.RedirectOK)
 	w.Header().Set("Location", link.Original)
 	w.WriteHeader(http.StatusTemporaryRedirect)
 }
@@ -313,10 +398,9 @@ func (a *App) GetOriginalURL(w http.ResponseWriter, r *http.Request) {
 // Пример ответа:
 //
 //	Статус: 200 OK или 500 Internal Server Error
-func (a *App) Ping(w http.ResponseWriter, _ *http.Request) {
-	if err := a.Storage.Ping(); err != nil {
-		log.Println("Storage ping failed:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+func (a *App) Ping(w http.ResponseWriter, r *http.Request) {
+	if err := a.Storage.Ping(r.Context()); err != nil {
+		problem.Internal(w, r, "Storage ping failed", err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
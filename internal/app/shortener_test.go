@@ -4,15 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/audit"
 	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
 	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
 	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/assert"
@@ -112,7 +117,7 @@ func Test_JSONGetShortURL(t *testing.T) {
 			body:   "sdfqwed",
 			want: want{
 				code:        400,
-				contentType: "text/plain; charset=utf-8",
+				contentType: problem.ContentType,
 			},
 		},
 	}
@@ -173,7 +178,7 @@ func Test_GetShortURL(t *testing.T) {
 			body:   "",
 			want: want{
 				code:        400,
-				contentType: "text/plain; charset=utf-8",
+				contentType: problem.ContentType,
 			},
 		},
 	}
@@ -251,7 +256,7 @@ func TestAPIDeleteUserURLs(t *testing.T) {
 
 		// Проверяем, что URL действительно удалены
 		for _, short := range shortURLs {
-			link, err := app.Storage.GetOriginal(short)
+			link, err := app.Storage.GetOriginal(context.Background(), short)
 			assert.NoError(t, err, "Ошибка при получении URL")
 			assert.True(t, storage.IsDeleted(link), "URL не был удален")
 		}
@@ -277,7 +282,7 @@ func TestAPIDeleteUserURLs(t *testing.T) {
 		assert.Equal(t, http.StatusAccepted, w.Code, "Код ответа не совпадает с ожидаемым")
 
 		// Проверяем, что URL не был удален
-		link, err := app.Storage.GetOriginal("short3")
+		link, err := app.Storage.GetOriginal(context.Background(), "short3")
 		assert.NoError(t, err, "Ошибка при получении URL")
 		assert.False(t, storage.IsDeleted(link), "URL был удален, хотя не должен был")
 	})
@@ -303,6 +308,33 @@ func TestAPIDeleteUserURLs(t *testing.T) {
 	})
 }
 
+func TestAPIDeleteUserURLs_AuditOnlyDeleted(t *testing.T) {
+	auditFile := filepath.Join(t.TempDir(), "audit.log")
+	app := NewApp(&config.AppConfig{
+		Host:      "localhost:8080",
+		ResultURL: "http://localhost:8080",
+		AuditFile: auditFile,
+	})
+
+	ctx := context.Background()
+	require.NoError(t, app.Storage.Insert(ctx, &objects.Link{Short: "own", Original: "http://example.com/own", UserID: "owner"}))
+	require.NoError(t, app.Storage.Insert(ctx, &objects.Link{Short: "foreign", Original: "http://example.com/foreign", UserID: "other"}))
+
+	r := httptest.NewRequest(http.MethodDelete, "/api/user/urls", strings.NewReader(`["own","foreign","missing"]`))
+	r = r.WithContext(context.WithValue(r.Context(), cookies.SecretKey, "owner"))
+	w := httptest.NewRecorder()
+	app.APIDeleteUserURLs(w, r)
+	require.Equal(t, http.StatusAccepted, w.Code)
+	require.NoError(t, app.Close(ctx))
+
+	data, err := os.ReadFile(auditFile)
+	require.NoError(t, err)
+	var event audit.Event
+	require.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, audit.EventDelete, event.Type)
+	assert.Equal(t, []string{"own"}, event.Short)
+}
+
 func TestAPIshortBatch(t *testing.T) {
 	type want struct {
 		contentType string
@@ -342,7 +374,7 @@ func TestAPIshortBatch(t *testing.T) {
 			body:   `invalid json`,
 			want: want{
 				code:        400,
-				contentType: "text/plain; charset=utf-8",
+				contentType: problem.ContentType,
 			},
 		},
 		{
@@ -351,7 +383,7 @@ func TestAPIshortBatch(t *testing.T) {
 			body:   "",
 			want: want{
 				code:        400,
-				contentType: "text/plain; charset=utf-8",
+				contentType: problem.ContentType,
 			},
 		},
 	}
@@ -453,7 +485,7 @@ func TestAPIGetUserURLs(t *testing.T) {
 			},
 			want: want{
 				code:        http.StatusUnauthorized,
-				contentType: "application/json",
+				contentType: problem.ContentType,
 				responseLen: 0,
 			},
 		},
@@ -485,8 +517,8 @@ func TestAPIGetUserURLs(t *testing.T) {
 			storage := storage.NewInMemoryStorage()
 			app := &App{
 				Storage: storage,
-				cfg:     conf,
 			}
+			app.cfg.Store(conf)
 
 			r := httptest.NewRequest(tt.method, "/api/user/urls", nil)
 			r.Header.Set("Content-Type", "application/json")
@@ -539,10 +571,10 @@ func TestAPIGetUserURLs(t *testing.T) {
 
 			// Проверяем что для 401 Unauthorized есть сообщение об ошибке
 			if tt.want.code == http.StatusUnauthorized {
-				var errorResponse map[string]string
+				var errorResponse problem.Problem
 				err := json.NewDecoder(w.Body).Decode(&errorResponse)
 				require.NoError(t, err, "Failed to decode error response")
-				assert.Equal(t, "Unauthorized", errorResponse["error"], "Unexpected error message")
+				assert.Equal(t, problem.CodeUnauthorized, errorResponse.Code, "Unexpected error code")
 			}
 		})
 	}
@@ -595,7 +627,7 @@ func TestAPIGetUserURLsWithFileStorage(t *testing.T) {
 			},
 			want: want{
 				code:        http.StatusUnauthorized,
-				contentType: "application/json",
+				contentType: problem.ContentType,
 				responseLen: 0,
 			},
 		},
@@ -618,8 +650,8 @@ func TestAPIGetUserURLsWithFileStorage(t *testing.T) {
 			storage := storage.NewFileStorage(conf.FilePATH)
 			app := &App{
 				Storage: storage,
-				cfg:     conf,
 			}
+			app.cfg.Store(conf)
 
 			// Вставляем тестовые данные
 			for _, link := range tt.prepopData {
@@ -647,10 +679,237 @@ func TestAPIGetUserURLsWithFileStorage(t *testing.T) {
 			} else if tt.want.code == http.StatusNoContent {
 				assert.Empty(t, w.Body.Bytes())
 			} else if tt.want.code == http.StatusUnauthorized {
-				var errorResponse map[string]string
+				var errorResponse problem.Problem
 				require.NoError(t, json.NewDecoder(w.Body).Decode(&errorResponse))
-				assert.Equal(t, "Unauthorized", errorResponse["error"])
+				assert.Equal(t, problem.CodeUnauthorized, errorResponse.Code)
 			}
 		})
 	}
 }
+
+func Test_GetOriginalURL_NotFound(t *testing.T) {
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080"})
+
+	r := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	router := chi.NewRouteContext()
+	router.URLParams.Add("id", "missing")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, router))
+	w := httptest.NewRecorder()
+
+	require.NotPanics(t, func() { app.GetOriginalURL(w, r) })
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPIshortBatch_WithoutUser(t *testing.T) {
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080"})
+
+	// Запрос без UserID в контексте, например если middleware аутентификации не подключен
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten/batch",
+		strings.NewReader(`[{"correlation_id": "1", "original_url": "https://example.com"}]`))
+	w := httptest.NewRecorder()
+
+	require.NotPanics(t, func() { app.APIshortBatch(w, r) })
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+// failingStorage возвращает ошибку с текстом SQL на любую вставку
+type failingStorage struct {
+	objects.Storage
+}
+
+func (failingStorage) Insert(context.Context, *objects.Link) error {
+	return errors.New(`ERROR: relation "links" does not exist (SQLSTATE 42P01)`)
+}
+
+func Test_JSONGetShortURL_SetsOwner(t *testing.T) {
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080"})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com/owned"}`))
+	r = r.WithContext(context.WithValue(r.Context(), cookies.SecretKey, "owner"))
+	w := httptest.NewRecorder()
+	app.JSONGetShortURL(w, r)
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	links, err := app.Storage.GetAllByUserID(context.Background(), "owner")
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://example.com/owned", links[0].Original)
+}
+
+func Test_JSONGetShortURL_HidesStorageError(t *testing.T) {
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080"})
+	app.Storage = failingStorage{Storage: app.Storage}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://example.com"}`))
+	w := httptest.NewRecorder()
+	app.JSONGetShortURL(w, r)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "SQLSTATE")
+
+	var resp problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, problem.CodeInternal, resp.Code)
+	assert.Equal(t, "/api/shorten", resp.Instance)
+}
+
+func Test_ShortenCanonicalizesURL(t *testing.T) {
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080", URLMaxLength: 100, URLDropFragment: true})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		status  int
+		code    string
+		stored  string
+	}{
+		{"text", app.GetShortURL, "  HTTPS://Example.COM:443/Path#top\n", http.StatusCreated, "", "https://example.com/Path"},
+		{"json", app.JSONGetShortURL, `{"url":"http://пример.рф"}`, http.StatusCreated, "", "http://xn--e1afmkfd.xn--p1ai/"},
+		{"javascript", app.GetShortURL, "javascript:alert(1)", http.StatusBadRequest, problem.CodeInvalidURL, ""},
+		{"relative", app.GetShortURL, "/local/path", http.StatusBadRequest, problem.CodeInvalidURL, ""},
+		{"empty json url", app.JSONGetShortURL, `{"url":""}`, http.StatusBadRequest, problem.CodeInvalidURL, ""},
+		{"too long", app.JSONGetShortURL, `{"url":"https://example.com/` + strings.Repeat("a", 100) + `"}`, http.StatusBadRequest, problem.CodeInvalidURL, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+
+			if tt.code != "" {
+				var p problem.Problem
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+				assert.Equal(t, tt.code, p.Code)
+				return
+			}
+			short := w.Body.String()
+			if w.Header().Get("Content-Type") == "application/json" {
+				var resp Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				short = resp.Result
+			}
+			link, err := app.Storage.GetOriginal(context.Background(), strings.TrimPrefix(short, "http://localhost:8080/"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.stored, link.Original)
+		})
+	}
+}
+
+func TestAPIshortBatch_Canonical(t *testing.T) {
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080"})
+
+	body := `[{"correlation_id":"1","original_url":"https://Example.com"},{"correlation_id":"2","original_url":"https://example.com:443/"}]`
+	w := httptest.NewRecorder()
+	app.APIshortBatch(w, httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	var resp []Resp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+	assert.Equal(t, resp[0].Short, resp[1].Short, "same canonical URL must get one short link")
+
+	body = `[{"correlation_id":"1","original_url":"https://example.com"},{"correlation_id":"2","original_url":"ftp://example.com"},{"correlation_id":"3","original_url":" "}]`
+	w = httptest.NewRecorder()
+	app.APIshortBatch(w, httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(body)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, problem.CodeValidation, p.Code)
+	assert.Equal(t, []problem.FieldError{
+		{Field: "/1/original_url", Message: "url scheme must be http or https"},
+		{Field: "/2/original_url", Message: "url is empty"},
+	}, p.Errors)
+}
+
+func Test_LinkPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":[".evil.example"],"block_private_networks":true}`), 0o644))
+	app := NewApp(&config.AppConfig{ResultURL: "http://localhost:8080", LinkPolicyFile: path})
+
+	// Сокращение запрещенного адреса отклоняется
+	for _, body := range []string{"https://login.evil.example/", "http://10.0.0.1/admin"} {
+		w := httptest.NewRecorder()
+		app.GetShortURL(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		require.Equal(t, http.StatusForbidden, w.Code, body)
+		var p problem.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		assert.Equal(t, problem.CodeURLDenied, p.Code)
+		assert.Contains(t, p.Detail, "is not allowed")
+	}
+
+	w := httptest.NewRecorder()
+	app.APIshortBatch(w, httptest.NewRequest(http.MethodPost, "/api/shorten/batch",
+		strings.NewReader(`[{"correlation_id":"1","original_url":"https://ok.example"},{"correlation_id":"2","original_url":"https://evil.example"}]`)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, []problem.FieldError{
+		{Field: "/1/original_url", Message: `host "evil.example" is not allowed: domain is on the deny list`},
+	}, p.Errors)
+
+	// Существующая ссылка перестает перенаправлять после изменения правил
+	require.NoError(t, app.Storage.Insert(context.Background(), &objects.Link{Short: "okLink", Original: "https://ok.example/"}))
+	redirect := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/okLink", nil)
+		router := chi.NewRouteContext()
+		router.URLParams.Add("id", "okLink")
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, router))
+		w := httptest.NewRecorder()
+		app.GetOriginalURL(w, r)
+		return w
+	}
+	assert.Equal(t, http.StatusTemporaryRedirect, redirect().Code)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":["ok.example"]}`), 0o644))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, future, future))
+	applied, _ := app.Reload(app.GetConfig())
+	assert.Empty(t, applied)
+
+	w = redirect()
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, problem.CodeURLDenied, p.Code)
+}
+
+func Test_ShortenStripsTrackingParams(t *testing.T) {
+	app := NewApp(&config.AppConfig{
+		ResultURL:      "http://localhost:8080",
+		URLStripParams: "utm_*,fbclid,gclid",
+		URLKeepParams:  "utm_campaign",
+		URLSortQuery:   true,
+	})
+
+	submitted := "https://Example.com/page?utm_source=mail&id=7&fbclid=abc&utm_campaign=spring&a=1"
+	body, err := json.Marshal(Request{URL: submitted})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	app.JSONGetShortURL(w, httptest.NewRequest(http.MethodPost, "/api/shorten", bytes.NewReader(body)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var resp Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, submitted, resp.Submitted)
+	assert.Equal(t, "https://example.com/page?a=1&id=7&utm_campaign=spring", resp.Stored)
+
+	link, err := app.Storage.GetOriginal(context.Background(), strings.TrimPrefix(resp.Result, "http://localhost:8080/"))
+	require.NoError(t, err)
+	assert.Equal(t, resp.Stored, link.Original)
+
+	// Варианты одной страницы с разными параметрами отслеживания получают одну ссылку
+	batch := `[{"correlation_id":"1","original_url":"https://example.com/p?id=1&utm_source=a"},` +
+		`{"correlation_id":"2","original_url":"https://example.com/p?gclid=x&id=1&fbclid=y"}]`
+	w = httptest.NewRecorder()
+	app.APIshortBatch(w, httptest.NewRequest(http.MethodPost, "/api/shorten/batch", strings.NewReader(batch)))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	var items []Resp
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &items))
+	require.Len(t, items, 2)
+	assert.Equal(t, items[0].Short, items[1].Short)
+	assert.Equal(t, "https://example.com/p?gclid=x&id=1&fbclid=y", items[1].Submitted)
+	assert.Equal(t, "https://example.com/p?id=1", items[1].Stored)
+}
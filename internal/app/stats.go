@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"go.uber.org/zap"
+)
+
+// RespStats представляет статистику сервиса.
+type RespStats struct {
+	URLs  int `json:"urls"`
+	Users int `json:"users"`
+}
+
+// writeStats подсчитывает ссылки и пользователей и пишет RespStats в ответ.
+// Эндпоинт служебного сервера: GET /debug/stats
+//
+// Возможные ответы:
+//   - 200 OK: {"urls": <количество>, "users": <количество>}
+//   - 501 Not Implemented: хранилище не поддерживает подсчет
+//   - 500 Internal Server Error: ошибка хранилища
+//
+// Проверка доступа выполняется служебным сервером (diagAuth).
+func (a *App) writeStats(w http.ResponseWriter, r *http.Request) {
+	admin, ok := a.adminStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	users, err := admin.ListUsers(r.Context())
+	if err != nil {
+		problem.Internal(w, r, "Failed to count users", err)
+		return
+	}
+
+	var stats RespStats
+	for _, u := range users {
+		stats.URLs += u.Links
+		if u.UserID != "" {
+			stats.Users++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
@@ -1,14 +1,18 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"strings"
 	"sync"
 
+	"github.com/GevorkovG/go-shortener-tlp/internal/audit"
 	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/webhook"
 	"go.uber.org/zap"
 )
 
@@ -40,40 +44,35 @@ func (a *App) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 	// Извлекаем userID из контекста
 	userID, ok := r.Context().Value(cookies.SecretKey).(string)
 
-	zap.L().Debug("internal/app/urls.go APIGetUserURLs",
+	logg.FromContext(r.Context()).Debug("internal/app/urls.go APIGetUserURLs",
 		zap.Bool("empty UserID?", userID == ""),
-		zap.String("userID", userID),
+		logg.UserID(userID),
 	)
 
 	if !ok || userID == "" {
-		zap.L().Warn("Unauthorized access attempt")
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized) // Устанавиваем статус-код
-		json.NewEncoder(w).Encode(map[string]string{"error": "Unauthorized"})
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
 		return
 	}
-	zap.L().Info("UserID extracted from context", zap.String("userID", userID))
+	logg.FromContext(r.Context()).Info("UserID extracted from context", logg.UserID(userID))
 
 	// Получаем URL-адреса пользователя
-	userURLs, err := a.Storage.GetAllByUserID(userID)
+	userURLs, err := a.Storage.GetAllByUserID(r.Context(), userID)
 	if err != nil {
-		zap.L().Error("Failed to get user URLs", zap.String("userID", userID), zap.Error(err))
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError) // Устанавиваем статус-код
-		json.NewEncoder(w).Encode(map[string]string{"error": "Internal Server Error"})
+		problem.Internal(w, r, "Failed to get user URLs", err, logg.UserID(userID))
 		return
 	}
 
 	// Логируем количество найденных URL
-	zap.L().Info("Number of URLs found", zap.Int("count", len(userURLs)))
+	logg.FromContext(r.Context()).Info("Number of URLs found", zap.Int("count", len(userURLs)))
 
-	zap.L().Debug("internal/app/urls.go APIGetUserURLs",
-		zap.String("userID", userID),
+	logg.FromContext(r.Context()).Debug("internal/app/urls.go APIGetUserURLs",
+		logg.UserID(userID),
 		zap.Int("Number of URLs found", len(userURLs)),
 	)
 
 	if len(userURLs) == 0 {
-		zap.L().Info("No URLs found for user", zap.String("userID", userID))
+		logg.FromContext(r.Context()).Info("No URLs found for user", logg.UserID(userID))
 		w.WriteHeader(http.StatusNoContent) // Возвращаем 204, если данных нет
 		return
 	}
@@ -82,7 +81,7 @@ func (a *App) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 	links := make([]RespURLs, 0, len(userURLs))
 	for _, val := range userURLs {
 		links = append(links, RespURLs{
-			Short:    strings.TrimSpace(a.cfg.ResultURL + "/" + val.Short),
+			Short:    strings.TrimSpace(a.GetConfig().ResultURL + "/" + val.Short),
 			Original: strings.TrimSpace(val.Original),
 		})
 	}
@@ -90,7 +89,7 @@ func (a *App) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(links); err != nil {
-		zap.L().Error("Failed to write response", zap.Error(err))
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
 	}
 }
 
@@ -107,83 +106,99 @@ func (a *App) APIGetUserURLs(w http.ResponseWriter, r *http.Request) {
 // - URL удаляются в нескольких горутинах для повышения производительности
 // - Ответ возвращается сразу после принятия запроса, не дожидаясь завершения удаления
 // - Результаты удаления логируются
+// - События аудита и вебхуков отправляются только для действительно удаленных ссылок
 func (a *App) APIDeleteUserURLs(w http.ResponseWriter, r *http.Request) {
 	var shortURLs []string
 	userID, ok := r.Context().Value(cookies.SecretKey).(string)
 
 	if !ok || userID == "" {
-		zap.L().Warn("Unauthorized access attempt")
-		w.WriteHeader(http.StatusUnauthorized)
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
 		return
 	}
 
 	err := json.NewDecoder(r.Body).Decode(&shortURLs)
 	if err != nil {
-		zap.L().Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logg.FromContext(r.Context()).Warn("Failed to decode request body", zap.Error(err))
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "request body must be a JSON array of short URL IDs")
 		return
 	}
 
+	// Ссылки в очереди удаления до завершения обработки
+	a.queueDeletion(len(shortURLs))
+	defer a.queueDeletion(-len(shortURLs))
+
 	// Канал для завершения работы горутин
 	doneCh := make(chan struct{})
 	defer close(doneCh)
 
 	// Создаем несколько горутин для обработки URL (FanOut)
-	channels := fanOut(doneCh, userID, shortURLs, a.Storage)
+	channels := fanOut(r.Context(), doneCh, userID, shortURLs, a.Storage)
 
 	// Объединяем результаты из всех горутин (FanIn)
 	finalCh := fanIn(doneCh, channels...)
 
 	// Ожидаем завершения всех горутин
-	for success := range finalCh {
-		if !success {
-			zap.L().Warn("Failed to delete some URLs")
+	deleted := make([]string, 0, len(shortURLs))
+	for res := range finalCh {
+		if res.err != nil {
+			logg.FromContext(r.Context()).Warn("Failed to delete some URLs")
+			continue
 		}
+		deleted = append(deleted, res.short)
+	}
+
+	if len(deleted) > 0 {
+		a.emitAudit(r, audit.EventDelete, deleted, nil)
+		a.publishWebhook(userID, webhook.EventLinkDeleted, deleted, nil)
 	}
 
 	w.WriteHeader(http.StatusAccepted)
 }
 
-// fanOut создает несколько горутин для обработки каждого URL.
-func fanOut(doneCh chan struct{}, userID string, shortURLs []string, storage objects.Storage) []chan bool {
+// deleteResult — результат удаления одного URL
+type deleteResult struct {
+	short string
+	err   error
+}
+
+// fanOut создает несколько горутин и распределяет между ними URL.
+func fanOut(ctx context.Context, doneCh chan struct{}, userID string, shortURLs []string, storage objects.Storage) []chan deleteResult {
 	// Количество горутин (можно настроить в зависимости от нагрузки)
 	numWorkers := 5
-	channels := make([]chan bool, numWorkers)
+	channels := make([]chan deleteResult, numWorkers)
 
 	for i := 0; i < numWorkers; i++ {
 		// Создаем канал для результатов
-		resultCh := make(chan bool, 1)
+		resultCh := make(chan deleteResult, 1)
 		channels[i] = resultCh
 
-		// Запускаем горутину для обработки URL
-		go func(resultCh chan bool) {
+		// Запускаем горутину для обработки каждого numWorkers-го URL
+		go func(first int, resultCh chan deleteResult) {
 			defer close(resultCh)
 
-			for _, short := range shortURLs {
-				log.Printf("fanOUT short: %s", short)
+			for j := first; j < len(shortURLs); j += numWorkers {
+				short := shortURLs[j]
 				select {
 				case <-doneCh: // Проверяем сигнал завершения
 					return
 				default:
-					err := storage.MarkAsDeleted(userID, short)
+					err := storage.MarkAsDeleted(ctx, userID, short)
 					if err != nil {
-						log.Printf("fanOUT short: %s", err)
-						zap.L().Error("Failed to mark URL as deleted", zap.String("short", short), zap.Error(err))
-						resultCh <- false
-					} else {
-						resultCh <- true
+						logg.FromContext(ctx).Error("Failed to mark URL as deleted", zap.String("short", short), zap.Error(err))
 					}
+					resultCh <- deleteResult{short: short, err: err}
 				}
 			}
-		}(resultCh)
+		}(i, resultCh)
 	}
 
 	return channels
 }
 
 // fanIn объединяет несколько каналов resultChs в один.
-func fanIn(doneCh chan struct{}, resultChs ...chan bool) chan bool {
-	finalCh := make(chan bool)
+func fanIn(doneCh chan struct{}, resultChs ...chan deleteResult) chan deleteResult {
+	finalCh := make(chan deleteResult)
 
 	// понадобится для ожидания всех горутин
 	var wg sync.WaitGroup
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/buildinfo"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"go.uber.org/zap"
+)
+
+// VersionResponse — ответ /api/version.
+type VersionResponse struct {
+	buildinfo.Info
+	Storage       string    `json:"storage"`
+	StartedAt     time.Time `json:"started_at"`
+	Uptime        string    `json:"uptime"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+}
+
+// APIVersion возвращает сведения о сборке, активном хранилище и времени работы.
+//
+// Пример запроса:
+//
+//	GET /api/version
+//
+// Пример ответа:
+//
+//	{"version":"v1.2.0","build_date":"2024-05-01","commit":"abc123","go_version":"go1.23.4",
+//	 "storage":"postgres","started_at":"2024-05-01T10:00:00Z","uptime":"1h2m3s","uptime_seconds":3723}
+func (a *App) APIVersion(w http.ResponseWriter, r *http.Request) {
+	uptime := time.Since(a.started).Truncate(time.Second)
+	resp := VersionResponse{
+		Info:          a.build,
+		Storage:       a.backend,
+		StartedAt:     a.started.UTC().Truncate(time.Second),
+		Uptime:        uptime.String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
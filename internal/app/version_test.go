@@ -0,0 +1,36 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/buildinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIVersion(t *testing.T) {
+	app := NewApp(&config.AppConfig{})
+	app.build = buildinfo.New("v1.2.0", "2024-05-01", "abc123")
+	app.started = time.Now().Add(-90 * time.Second)
+
+	w := httptest.NewRecorder()
+	app.APIVersion(w, httptest.NewRequest(http.MethodGet, "/api/version", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "v1.2.0", resp["version"])
+	assert.Equal(t, "2024-05-01", resp["build_date"])
+	assert.Equal(t, "abc123", resp["commit"])
+	assert.Equal(t, runtime.Version(), resp["go_version"])
+	assert.Equal(t, "memory", resp["storage"])
+	assert.Equal(t, "1m30s", resp["uptime"])
+	assert.EqualValues(t, 90, resp["uptime_seconds"])
+}
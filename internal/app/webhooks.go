@@ -0,0 +1,322 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
+	"github.com/GevorkovG/go-shortener-tlp/internal/webhook"
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ReqWebhook представляет запрос на создание подписки на вебхуки.
+//
+// Поля:
+//   - URL string `json:"url"`: адрес получателя (http или https)
+//   - Events []string `json:"events"`: типы событий (необязательно, по умолчанию все)
+type ReqWebhook struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// RespWebhook представляет подписку в ответах сервера.
+// Поле Secret заполняется только в ответе на создание подписки.
+type RespWebhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// webhookStorage возвращает хранилище подписок, если бэкенд его поддерживает.
+func (a *App) webhookStorage() (objects.WebhookStorage, bool) {
+	hooks, ok := a.Storage.(objects.WebhookStorage)
+	return hooks, ok && a.webhooks != nil
+}
+
+// APICreateWebhook создает подписку текущего пользователя на события его ссылок.
+// Эндпоинт: POST /api/user/webhooks
+//
+// Входные данные:
+//
+//	{"url": "https://example.com/hook", "events": ["link.created", "link.deleted"]}
+//
+// Возможные ответы:
+//   - 201 Created: подписка создана, в теле RespWebhook с полем secret
+//   - 400 Bad Request: невалидный JSON, URL или тип события; URL, хост
+//     которого разрешается во внутреннюю сеть (если не задан webhook_allow_private)
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 501 Not Implemented: хранилище не поддерживает вебхуки
+//   - 500 Internal Server Error: ошибка хранилища
+//
+// Особенности:
+//   - Поддерживаемые события: link.created, link.deleted, link.first_clicked
+//   - Тело каждого запроса подписывается секретом подписки,
+//     подпись передается в заголовке X-Webhook-Signature
+//   - Секрет показывается один раз
+func (a *App) APICreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	hooks, ok := a.webhookStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	var req ReqWebhook
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "request body must be a JSON object")
+		return
+	}
+	// Ошибки проверки формируются пакетом webhook и не содержат внутренних данных
+	if err := webhook.ValidateURL(r.Context(), req.URL, a.GetConfig().WebhookAllowPrivate); err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+	events, err := webhook.NormalizeEvents(req.Events)
+	if err != nil {
+		problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		problem.Internal(w, r, "Failed to generate webhook secret", err)
+		return
+	}
+
+	hook := &objects.Webhook{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := hooks.InsertWebhook(r.Context(), hook); err != nil {
+		problem.Internal(w, r, "Failed to insert webhook", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(RespWebhook{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    hook.Events,
+		Secret:    hook.Secret,
+		CreatedAt: hook.CreatedAt,
+	}); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// APIGetWebhooks возвращает подписки текущего пользователя.
+// Эндпоинт: GET /api/user/webhooks
+//
+// Возможные ответы:
+//   - 200 OK: массив RespWebhook (без секретов)
+//   - 204 No Content: у пользователя нет подписок
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) APIGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	hooks, ok := a.webhookStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	userHooks, err := hooks.GetWebhooksByUserID(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get webhooks", err)
+		return
+	}
+
+	if len(userHooks) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	resp := make([]RespWebhook, 0, len(userHooks))
+	for _, h := range userHooks {
+		resp = append(resp, RespWebhook{
+			ID:        h.ID,
+			URL:       h.URL,
+			Events:    h.Events,
+			CreatedAt: h.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// APIDeleteWebhook удаляет подписку текущего пользователя вместе с историей доставок.
+// Эндпоинт: DELETE /api/user/webhooks/{id}
+//
+// Возможные ответы:
+//   - 204 No Content: подписка удалена
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 404 Not Found: подписка не найдена или принадлежит другому пользователю
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) APIDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	hooks, ok := a.webhookStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if err := hooks.DeleteWebhook(r.Context(), userID, id); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "")
+			return
+		}
+		problem.Internal(w, r, "Failed to delete webhook", err, zap.String("id", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// APIGetWebhookDeliveries возвращает историю доставок по подписке.
+// Эндпоинт: GET /api/user/webhooks/{id}/deliveries
+//
+// Параметры запроса:
+//   - status: только доставки с указанным статусом (pending, delivered, dead);
+//     status=dead возвращает содержимое dead-letter хранилища
+//
+// Возможные ответы:
+//   - 200 OK: массив objects.WebhookDelivery, начиная с самых новых;
+//     хранятся последние objects.MaxDeliveriesPerWebhook доставок
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 404 Not Found: подписка не найдена или принадлежит другому пользователю
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) APIGetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	hooks, ok := a.webhookStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	deliveries, err := hooks.GetDeliveries(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "")
+			return
+		}
+		problem.Internal(w, r, "Failed to get webhook deliveries", err, zap.String("id", id))
+		return
+	}
+
+	result := make([]objects.WebhookDelivery, 0, len(deliveries))
+	status := r.URL.Query().Get("status")
+	for _, d := range deliveries {
+		if status == "" || d.Status == status {
+			result = append(result, d)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// APIRedeliverWebhook повторно отправляет доставку, например из dead-letter.
+// Эндпоинт: POST /api/user/webhooks/{id}/deliveries/{deliveryID}/redeliver
+//
+// Возможные ответы:
+//   - 202 Accepted: доставка поставлена в очередь
+//   - 401 Unauthorized: пользователь не аутентифицирован
+//   - 404 Not Found: подписка или доставка не найдена
+//   - 500 Internal Server Error: ошибка хранилища
+func (a *App) APIRedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(cookies.SecretKey).(string)
+	if !ok || userID == "" {
+		logg.FromContext(r.Context()).Warn("Unauthorized access attempt")
+		problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "")
+		return
+	}
+
+	hooks, ok := a.webhookStorage()
+	if !ok {
+		problem.Write(w, r, http.StatusNotImplemented, problem.CodeNotImplemented, detailUnsupported)
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	deliveryID := chi.URLParam(r, "deliveryID")
+
+	userHooks, err := hooks.GetWebhooksByUserID(r.Context(), userID)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get webhooks", err)
+		return
+	}
+	var hook *objects.Webhook
+	for i := range userHooks {
+		if userHooks[i].ID == id {
+			hook = &userHooks[i]
+			break
+		}
+	}
+	if hook == nil {
+		problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "")
+		return
+	}
+
+	deliveries, err := hooks.GetDeliveries(r.Context(), userID, id)
+	if err != nil {
+		problem.Internal(w, r, "Failed to get webhook deliveries", err, zap.String("id", id))
+		return
+	}
+	for _, d := range deliveries {
+		if d.ID == deliveryID {
+			a.webhooks.Redeliver(*hook, d)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+
+	problem.Write(w, r, http.StatusNotFound, problem.CodeNotFound, "")
+}
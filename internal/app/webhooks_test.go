@@ -0,0 +1,119 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/config"
+	"github.com/GevorkovG/go-shortener-tlp/internal/cookies"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/webhook"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooksLifecycle(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	signatures := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signatures <- r.Header.Get(webhook.HeaderSignature)
+		bodies <- body
+	}))
+	defer receiver.Close()
+
+	// Получатель слушает loopback-адрес
+	app := NewApp(&config.AppConfig{
+		Host:                "localhost:8080",
+		ResultURL:           "http://localhost:8080",
+		WebhookAllowPrivate: true,
+	})
+	defer app.Close(context.Background())
+
+	userID := "hook-owner"
+	withUser := func(req *http.Request) *http.Request {
+		return req.WithContext(context.WithValue(req.Context(), cookies.SecretKey, userID))
+	}
+
+	r := chi.NewRouter()
+	r.Post("/", app.GetShortURL)
+	r.Post("/api/user/webhooks", app.APICreateWebhook)
+	r.Get("/api/user/webhooks", app.APIGetWebhooks)
+	r.Delete("/api/user/webhooks/{id}", app.APIDeleteWebhook)
+	r.Get("/api/user/webhooks/{id}/deliveries", app.APIGetWebhookDeliveries)
+
+	t.Run("invalid url", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodPost, "/api/user/webhooks", strings.NewReader(`{"url":"ftp://x"}`))))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	// Подписываемся на создание ссылок
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodPost, "/api/user/webhooks",
+		strings.NewReader(`{"url":"`+receiver.URL+`","events":["link.created"]}`))))
+	require.Equal(t, http.StatusCreated, w.Code)
+	var created RespWebhook
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&created))
+	assert.True(t, strings.HasPrefix(created.Secret, webhook.SecretPrefix))
+	assert.Equal(t, []string{webhook.EventLinkCreated}, created.Events)
+
+	// Секрет не возвращается повторно
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/user/webhooks", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []RespWebhook
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&list))
+	require.Len(t, list, 1)
+	assert.Empty(t, list[0].Secret)
+
+	// Создание ссылки приводит к подписанной доставке
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://example.com/webhook"))))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	select {
+	case body := <-bodies:
+		assert.Equal(t, webhook.Sign(created.Secret, body), <-signatures)
+		var p webhook.Payload
+		require.NoError(t, json.Unmarshal(body, &p))
+		assert.Equal(t, webhook.EventLinkCreated, p.Event)
+		require.Len(t, p.Links, 1)
+		assert.Equal(t, "https://example.com/webhook", p.Links[0].Original)
+	case <-time.After(5 * time.Second):
+		t.Fatal("вебхук не доставлен")
+	}
+
+	// История доставок
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/user/webhooks/"+created.ID+"/deliveries", nil)))
+		var deliveries []objects.WebhookDelivery
+		if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&deliveries) != nil {
+			return false
+		}
+		return len(deliveries) == 1 && deliveries[0].Status == objects.DeliveryDelivered
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Чужая подписка недоступна
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/user/webhooks/"+created.ID+"/deliveries", nil)
+	r.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), cookies.SecretKey, "stranger")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Удаление
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodDelete, "/api/user/webhooks/"+created.ID, nil)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, withUser(httptest.NewRequest(http.MethodGet, "/api/user/webhooks", nil)))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
@@ -0,0 +1,244 @@
+// Package audit реализует журнал аудита жизненного цикла ссылок:
+// кто, когда и с какого адреса создал, удалил или открыл ссылку.
+// События доставляются в настраиваемые приемники (sinks) асинхронно,
+// не блокируя обработчики HTTP-запросов, с повторными попытками.
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Типы событий аудита
+const (
+	EventCreate      = "create"
+	EventBatchCreate = "batch_create"
+	EventDelete      = "delete"
+	EventRedirect    = "redirect"
+)
+
+// Event представляет одно событие аудита.
+type Event struct {
+	Type      string    `json:"type"`                    //Тип события
+	Timestamp time.Time `json:"ts"`                      //Время события (UTC)
+	UserID    string    `json:"user_id,omitempty"`       //ID пользователя
+	IP        string    `json:"ip,omitempty"`            //IP-адрес клиента
+	Short     []string  `json:"short_urls,omitempty"`    //Короткие идентификаторы
+	Original  []string  `json:"original_urls,omitempty"` //Оригинальные URL
+}
+
+// Sink определяет приемник событий аудита.
+// Send вызывается из фоновой горутины; ошибка приводит к повторной попытке.
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, ev Event) error
+	Close() error
+}
+
+// Настройки доставки по умолчанию
+const (
+	defaultQueueSize   = 1024
+	defaultMaxAttempts = 5
+	defaultBackoff     = 100 * time.Millisecond
+	maxBackoff         = 5 * time.Second
+)
+
+// Auditor рассылает события по приемникам.
+// У каждого приемника своя очередь и своя горутина доставки,
+// поэтому медленный приемник не задерживает остальные.
+//
+// Нулевой указатель *Auditor допустим: Emit и Close ничего не делают.
+type Auditor struct {
+	workers []*worker
+	wg      sync.WaitGroup
+
+	mu     sync.RWMutex // защищает closed
+	closed bool
+}
+
+// worker доставляет события в один приемник
+type worker struct {
+	sink        Sink
+	queue       chan Event
+	maxAttempts int
+	backoff     time.Duration
+	done        chan struct{}
+}
+
+// Option настраивает Auditor
+type Option func(*Auditor)
+
+// WithRetry задает количество попыток доставки и начальную задержку между ними.
+// Задержка удваивается после каждой неудачи (но не больше 5 секунд).
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(a *Auditor) {
+		for _, w := range a.workers {
+			w.maxAttempts = attempts
+			w.backoff = backoff
+		}
+	}
+}
+
+// New создает Auditor и запускает горутины доставки.
+//
+// Параметры:
+//   - sinks: приемники событий; без приемников возвращается nil
+//   - opts: дополнительные настройки
+//
+// Возвращает:
+//   - *Auditor: готовый к работе журнал аудита или nil
+func New(sinks []Sink, opts ...Option) *Auditor {
+	if len(sinks) == 0 {
+		return nil
+	}
+
+	a := &Auditor{}
+	for _, s := range sinks {
+		a.workers = append(a.workers, &worker{
+			sink:        s,
+			queue:       make(chan Event, defaultQueueSize),
+			maxAttempts: defaultMaxAttempts,
+			backoff:     defaultBackoff,
+			done:        make(chan struct{}),
+		})
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	for _, w := range a.workers {
+		a.wg.Add(1)
+		go func(w *worker) {
+			defer a.wg.Done()
+			w.run()
+		}(w)
+	}
+	return a
+}
+
+// Emit ставит событие в очереди всех приемников.
+// Никогда не блокирует: если очередь приемника переполнена,
+// событие для него отбрасывается с записью в лог.
+func (a *Auditor) Emit(ev Event) {
+	if a == nil {
+		return
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = time.Now().UTC()
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return
+	}
+
+	for _, w := range a.workers {
+		select {
+		case w.queue <- ev:
+		default:
+			zap.L().Warn("Audit queue is full, event dropped",
+				zap.String("sink", w.sink.Name()),
+				zap.String("type", ev.Type))
+		}
+	}
+}
+
+// Close прекращает прием событий, дожидается доставки очередей
+// (не дольше, чем позволяет ctx) и закрывает приемники.
+func (a *Auditor) Close(ctx context.Context) error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	for _, w := range a.workers {
+		close(w.queue)
+	}
+	a.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(finished)
+	}()
+
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		for _, w := range a.workers {
+			close(w.done)
+		}
+		err = ctx.Err()
+	}
+
+	for _, w := range a.workers {
+		if cerr := w.sink.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// run обрабатывает очередь приемника до ее закрытия
+func (w *worker) run() {
+	for ev := range w.queue {
+		w.deliver(ev)
+	}
+}
+
+// deliver отправляет событие с повторными попытками и экспоненциальной задержкой
+func (w *worker) deliver(ev Event) {
+	backoff := w.backoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := w.sink.Send(ctx, ev)
+		cancel()
+		if err == nil {
+			return
+		}
+
+		if attempt >= w.maxAttempts {
+			zap.L().Error("Audit event lost after retries",
+				zap.String("sink", w.sink.Name()),
+				zap.String("type", ev.Type),
+				zap.Int("attempts", attempt),
+				zap.Error(err))
+			return
+		}
+
+		zap.L().Warn("Audit delivery failed, retrying",
+			zap.String("sink", w.sink.Name()),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+
+		select {
+		case <-time.After(backoff):
+		case <-w.done:
+			return
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// ClientIP возвращает IP-адрес клиента из RemoteAddr запроса
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
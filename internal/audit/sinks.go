@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileSink записывает события в локальный файл в формате JSON Lines.
+// Файл открывается только на дозапись.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileSink открывает (или создает) файл журнала аудита.
+//
+// Параметры:
+//   - path: путь к файлу
+//
+// Возвращает:
+//   - *FileSink: приемник
+//   - error: ошибка открытия файла
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{file: f}, nil
+}
+
+// Name возвращает имя приемника для логов
+func (s *FileSink) Name() string {
+	return "file:" + s.file.Name()
+}
+
+// Send дописывает событие в файл
+func (s *FileSink) Send(_ context.Context, ev Event) error {
+	line, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(line)
+	return err
+}
+
+// Close закрывает файл
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink отправляет каждое событие POST-запросом с JSON-телом.
+// Любой ответ, кроме 2xx, считается ошибкой доставки.
+type HTTPSink struct {
+	url    string
+	name   string // схема и хост без учетных данных, пути и запроса
+	client *http.Client
+}
+
+// NewHTTPSink создает приемник, отправляющий события на указанный URL.
+func NewHTTPSink(rawURL string) *HTTPSink {
+	name := "http"
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		name = "http:" + u.Scheme + "://" + u.Host
+	}
+	return &HTTPSink{
+		url:    rawURL,
+		name:   name,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Name возвращает имя приемника для логов.
+// Содержит только схему и хост: токены в URL (audit_url) в лог не попадают.
+func (s *HTTPSink) Name() string {
+	return s.name
+}
+
+// Send отправляет событие
+func (s *HTTPSink) Send(ctx context.Context, ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Ошибка клиента содержит полный URL; в лог попадает только причина
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("audit endpoint %s: %w", urlErr.Op, urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("audit endpoint responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// Close ничего не делает: соединения закрываются клиентом
+func (s *HTTPSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,49 @@
+// Package buildinfo описывает сборку сервиса: версию, дату и коммит,
+// переданные через -ldflags, и версию Go.
+package buildinfo
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// unknown — значение для полей, не заданных при сборке
+const unknown = "N/A"
+
+// Info — сведения о сборке.
+type Info struct {
+	Version   string `json:"version"`
+	Date      string `json:"build_date"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+// New создает сведения о сборке; пустые значения заменяются на "N/A".
+//
+// Пример сборки:
+//
+//	go build -ldflags "-X main.buildVersion=v1.2.0 -X 'main.buildDate=$(date -u)' -X main.buildCommit=$(git rev-parse --short HEAD)" ./cmd/shortener
+func New(version, date, commit string) Info {
+	return Info{
+		Version:   orUnknown(version),
+		Date:      orUnknown(date),
+		Commit:    orUnknown(commit),
+		GoVersion: runtime.Version(),
+	}
+}
+
+// orUnknown возвращает "N/A" для пустой строки
+func orUnknown(s string) string {
+	if s == "" {
+		return unknown
+	}
+	return s
+}
+
+// Print выводит сведения о сборке по одному полю на строку.
+func (i Info) Print(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Build version: %s\nBuild date: %s\nBuild commit: %s\nGo version: %s\n",
+		i.Version, i.Date, i.Commit, i.GoVersion)
+	return err
+}
@@ -0,0 +1,21 @@
+package buildinfo
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	info := New("v1.2.0", "", "abc123")
+	assert.Equal(t, Info{Version: "v1.2.0", Date: "N/A", Commit: "abc123", GoVersion: runtime.Version()}, info)
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, New("v1.2.0", "2024-05-01", "abc123").Print(&buf))
+	assert.Equal(t, "Build version: v1.2.0\nBuild date: 2024-05-01\nBuild commit: abc123\nGo version: "+runtime.Version()+"\n", buf.String())
+}
@@ -1,12 +1,21 @@
 // Package cookies предоставляет функционал для работы с JWT-аутентификацией
-// через HTTP cookies.
+// через HTTP cookies, а также с аутентификацией по долгоживущим API-ключам.
 package cookies
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/services/apikey"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -20,6 +29,7 @@ type contextKey string
 type Claims struct {
 	jwt.RegisteredClaims
 	UserID string // Уникальный идентификатор пользователя
+	Role   string `json:",omitempty"` // Роль пользователя (RoleUser или RoleAdmin)
 }
 
 // Константы
@@ -27,11 +37,113 @@ const (
 	// TokenExp - время жизни JWT токена (3 часа)
 	TokenExp = time.Hour * 3
 
+	// TokenRefreshWindow - если до истечения токена осталось меньше этого
+	// времени, middleware перевыпускает токен с тем же UserID
+	TokenRefreshWindow = time.Hour
+
+	// ClaimMaxAge - сколько времени после истечения токен еще можно
+	// предъявить для переноса ссылок (POST /api/user/claim)
+	ClaimMaxAge = time.Hour * 24 * 30
+
 	// SecretKey ключ для доступа к данным в контексте
 	SecretKey contextKey = "supersecretkey"
+
+	// AuthMethodKey ключ контекста со способом аутентификации запроса
+	// (AuthByCookie или AuthByAPIKey)
+	AuthMethodKey contextKey = "auth_method"
+
+	// ClaimsKey ключ контекста с *Claims действующего токена
+	// (в том числе только что выпущенного); только для AuthByCookie
+	ClaimsKey contextKey = "claims"
+
+	// TokenCookieName - имя cookie с JWT токеном
+	TokenCookieName = "token"
 )
 
+// Способы аутентификации запроса, сохраняемые в контексте по ключу AuthMethodKey
+const (
+	AuthByCookie = "cookie"
+	AuthByAPIKey = "api_key"
+)
+
+// CookieConfig содержит атрибуты cookie с токеном.
+type CookieConfig struct {
+	Secure   bool          // атрибут Secure
+	HTTPOnly bool          // атрибут HttpOnly
+	SameSite http.SameSite // атрибут SameSite
+	MaxAge   int           // Max-Age в секундах; 0 — по времени жизни токена
+	Domain   string        // атрибут Domain (пусто — текущий хост)
+}
+
+// DefaultCookieConfig возвращает безопасные атрибуты cookie по умолчанию:
+// HttpOnly, SameSite=Lax и Max-Age по времени жизни токена.
+func DefaultCookieConfig() CookieConfig {
+	return CookieConfig{
+		HTTPOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// ParseSameSite преобразует строковое значение атрибута SameSite.
+//
+// Параметры:
+//   - s: "lax", "strict", "none" или пустая строка (означает "lax")
+//
+// Возвращает:
+//   - http.SameSite: значение атрибута
+//   - error: ошибка для неизвестного значения
+func ParseSameSite(s string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return http.SameSiteDefaultMode, fmt.Errorf("unknown SameSite value %q", s)
+	}
+}
+
+// signingKey - ключ подписи JWT. До вызова SetSigningKey используется
+// случайный ключ, поэтому токены, подписанные общеизвестным ключом,
+// не принимаются
+var signingKey atomic.Pointer[[]byte]
+
+func init() {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("cookies: generate signing key: %v", err))
+	}
+	signingKey.Store(&key)
+}
+
+// SetSigningKey задает ключ подписи и проверки JWT токенов.
+// Вызывается при старте сервера со значением jwt_secret из конфигурации;
+// токены, подписанные прежним ключом, после этого не принимаются.
+//
+// Параметры:
+//   - key: секретный ключ подписи
+func SetSigningKey(key string) {
+	k := []byte(key)
+	signingKey.Store(&k)
+}
+
+// SignClaims подписывает claims текущим ключом подписи (HS256).
+//
+// Параметры:
+//   - claims: claims токена
+//
+// Возвращает:
+//   - string: подписанный JWT токен
+//   - error: ошибка если не удалось подписать токен
+func SignClaims(claims Claims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(*signingKey.Load())
+}
+
 // BuildJWTString создает JWT токен для указанного пользователя.
+// Каждый токен получает уникальный идентификатор (jti), по которому
+// его можно отозвать до истечения срока действия.
 //
 // Параметры:
 //   - userID: строка с идентификатором пользователя
@@ -44,19 +156,115 @@ const (
 //
 //	token, err := BuildJWTString("123e4567-e89b-12d3-a456-426614174000")
 func BuildJWTString(userID string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+	return BuildRoleJWTString(userID, RoleUser)
+}
+
+// BuildRoleJWTString создает JWT токен для пользователя с указанной ролью.
+//
+// Параметры:
+//   - userID: строка с идентификатором пользователя
+//   - role: роль пользователя (RoleUser или RoleAdmin)
+//
+// Возвращает:
+//   - string: подписанный JWT токен
+//   - error: ошибка если не удалось подписать токен
+func BuildRoleJWTString(userID string, role string) (string, error) {
+	now := time.Now()
+	return SignClaims(Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExp)),
+			ID:        uuid.New().String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(TokenExp)),
 		},
 		UserID: userID,
+		Role:   NormalizeRole(role),
 	})
+}
 
-	tokenString, err := token.SignedString([]byte(SecretKey))
+// keyFunc проверяет метод подписи и возвращает ключ для проверки токена
+func keyFunc(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+	}
+	return *signingKey.Load(), nil
+}
+
+// ErrInvalidToken возвращается для токена с неверной подписью или без UserID
+var ErrInvalidToken = errors.New("invalid token")
+
+// ParseToken разбирает JWT токен и проверяет его подпись и срок действия.
+//
+// Параметры:
+//   - tokenString: строка с JWT токеном
+//
+// Возвращает:
+//   - *Claims: claims токена
+//   - error: ошибка если токен невалидный, просрочен или не содержит UserID
+//
+// Особенности:
+//   - Токены, выпущенные до появления jti, принимаются: их невозможно
+//     отозвать, поэтому middleware сразу перевыпускает их с тем же UserID
+func ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyFunc)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if !token.Valid || claims.UserID == "" {
+		return nil, ErrInvalidToken
 	}
+	return claims, nil
+}
 
-	return tokenString, nil
+// ParseExpiredToken разбирает JWT токен, проверяя только подпись.
+// Срок действия не проверяется — функция предназначена для переноса
+// данных со старого (в том числе истекшего) токена на текущего пользователя.
+//
+// Параметры:
+//   - tokenString: строка с JWT токеном
+//
+// Возвращает:
+//   - *Claims: claims токена
+//   - error: ошибка если подпись неверна или токен не содержит UserID
+func ParseExpiredToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(jwt.WithoutClaimsValidation())
+	token, err := parser.ParseWithClaims(tokenString, claims, keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || claims.UserID == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ErrClaimTokenTooOld возвращается для токена, истекшего раньше чем ClaimMaxAge назад
+var ErrClaimTokenTooOld = errors.New("claim token is too old")
+
+// ParseClaimToken разбирает JWT токен, предъявленный для переноса ссылок.
+// В отличие от ParseExpiredToken, принимает только токены, истекшие
+// не раньше чем ClaimMaxAge назад.
+//
+// Параметры:
+//   - tokenString: строка с JWT токеном
+//
+// Возвращает:
+//   - *Claims: claims токена
+//   - error: ошибка если подпись неверна, токен не содержит UserID
+//     или срока действия, либо истек раньше чем ClaimMaxAge назад
+func ParseClaimToken(tokenString string) (*Claims, error) {
+	claims, err := ParseExpiredToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Since(claims.ExpiresAt.Time) > ClaimMaxAge {
+		return nil, ErrClaimTokenTooOld
+	}
+	return claims, nil
 }
 
 // GetUserID извлекает идентификатор пользователя из JWT токена.
@@ -72,63 +280,239 @@ func BuildJWTString(userID string) (string, error) {
 //
 //	userID, err := GetUserID("eyJhbGciOiJIUzI1NiIsI...")
 func GetUserID(tokenString string) (string, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(SecretKey), nil
-	})
-	if err != nil || !token.Valid {
+	claims, err := ParseToken(tokenString)
+	if err != nil {
 		return "", err
 	}
-
 	return claims.UserID, nil
 }
 
+// needsRefresh сообщает, пора ли перевыпустить токен с указанными claims
+func needsRefresh(claims *Claims) bool {
+	if claims.ExpiresAt == nil {
+		return true
+	}
+	return time.Until(claims.ExpiresAt.Time) < TokenRefreshWindow
+}
+
+// setTokenCookie выставляет cookie "token" с указанным JWT
+//
+// Особенности:
+//   - SameSite=None без Secure браузеры отвергают, поэтому в этом
+//     случае Secure выставляется принудительно
+func (c CookieConfig) setTokenCookie(w http.ResponseWriter, tokenString string) {
+	maxAge := c.MaxAge
+	if maxAge == 0 {
+		maxAge = int(TokenExp / time.Second)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     TokenCookieName,
+		Value:    tokenString,
+		Path:     "/",
+		Domain:   c.Domain,
+		MaxAge:   maxAge,
+		Secure:   c.Secure || c.SameSite == http.SameSiteNoneMode,
+		HttpOnly: c.HTTPOnly,
+		SameSite: c.SameSite,
+	})
+}
+
+// Auth middleware аутентификации запросов.
+// Принимает JWT в cookie "token" либо API-ключ в заголовке
+// Authorization (схема Bearer) или X-API-Key.
+type Auth struct {
+	keys    objects.APIKeyStorage
+	revoked objects.RevocationStorage
+	cookie  CookieConfig
+	admins  atomic.Pointer[map[string]struct{}]
+}
+
+// AuthConfig содержит зависимости и настройки middleware аутентификации.
+type AuthConfig struct {
+	Keys        objects.APIKeyStorage     // хранилище API-ключей; nil отключает вход по ключам
+	Revocations objects.RevocationStorage // список отозванных токенов; nil отключает проверку
+	Cookie      CookieConfig              // атрибуты cookie с токеном
+	Admins      []string                  // UserID, получающие роль RoleAdmin в токене (см. SetAdmins)
+}
+
+// NewAuth создает middleware аутентификации.
+//
+// Параметры:
+//   - cfg: зависимости и настройки middleware
+func NewAuth(cfg AuthConfig) *Auth {
+	a := &Auth{
+		keys:    cfg.Keys,
+		revoked: cfg.Revocations,
+		cookie:  cfg.Cookie,
+	}
+	a.SetAdmins(cfg.Admins)
+	return a
+}
+
+// SetAdmins заменяет список администраторов, например после перезагрузки
+// конфигурации. Безопасен для вызова во время обработки запросов;
+// токены с устаревшей ролью перевыпускаются при следующем запросе.
+//
+// Параметры:
+//   - ids: UserID, получающие роль RoleAdmin (пустые значения пропускаются)
+func (a *Auth) SetAdmins(ids []string) {
+	admins := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			admins[id] = struct{}{}
+		}
+	}
+	a.admins.Store(&admins)
+}
+
+// roleFor возвращает роль, которую получает пользователь при выпуске токена
+func (a *Auth) roleFor(userID string) string {
+	if _, ok := (*a.admins.Load())[userID]; ok {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+// apiKeyFromRequest извлекает API-ключ из заголовков запроса.
+// Возвращает пустую строку, если ключ не передан.
+func apiKeyFromRequest(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	auth := r.Header.Get("Authorization")
+	if token, ok := strings.CutPrefix(auth, "Bearer "); ok && apikey.IsAPIKey(token) {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// Middleware возвращает обработчик, выполняющий аутентификацию.
+//
+// Функционал:
+//   - Если передан API-ключ — проверяет его по хешу в хранилище и
+//     добавляет UserID владельца и роль в контекст; cookie не выставляется.
+//     Роль ключа ограничивается текущей ролью владельца: ключ администратора,
+//     исключенного из AdminUsers, работает с ролью RoleUser
+//   - Невалидный или отозванный API-ключ — 401 Unauthorized (код unauthorized)
+//   - Иначе работает как Cookies: проверяет JWT в cookie "token" и
+//     при необходимости выдает новый
+func (a *Auth) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := apiKeyFromRequest(r); key != "" && a.keys != nil {
+			k, err := a.keys.GetAPIKeyByHash(r.Context(), apikey.Hash(key))
+			if err != nil {
+				logg.FromContext(r.Context()).Info("Rejected API key", zap.Error(err))
+				problem.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "invalid or revoked API key")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), SecretKey, k.UserID)
+			ctx = context.WithValue(ctx, AuthMethodKey, AuthByAPIKey)
+			ctx = context.WithValue(ctx, RoleKey, lowerRole(k.Role, a.roleFor(k.UserID)))
+			h.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		a.serveWithCookie(w, r, h)
+	})
+}
+
 // Cookies middleware для обработки аутентификации через JWT в cookies.
 //
 // Функционал:
 //   - Проверяет наличие валидного токена в cookie "token"
-//   - Если токен валиден - извлекает UserID
+//   - Если токен валиден и не отозван - извлекает UserID и роль
+//   - Если токен скоро истекает (TokenRefreshWindow) или выпущен без jti -
+//     перевыпускает его с тем же UserID (скользящее продление сессии)
 //   - Если токена нет/невалиден - генерирует новый UserID и токен
 //   - Добавляет UserID в контекст запроса
 //   - Устанавливает cookie с токеном для новых пользователей
 //
 // Возможные ошибки:
 //   - 500 Internal Server Error при ошибке генерации токена
+//     или проверки списка отозванных токенов
 //
 // Пример использования:
 //
 //	router.Use(Cookies)
 func Cookies(h http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var userID string
-
-		cookie, _ := r.Cookie("token")
-		if cookie != nil {
-			id, err := GetUserID(cookie.Value)
-			if err == nil {
-				userID = id
-			} else {
-				zap.L().Info("Failed to create UserID", zap.String("cookie.value", cookie.Value))
-			}
+	return NewAuth(AuthConfig{Cookie: DefaultCookieConfig()}).Middleware(h)
+}
+
+// serveWithCookie аутентифицирует запрос по JWT в cookie "token"
+// и передает управление следующему обработчику.
+func (a *Auth) serveWithCookie(w http.ResponseWriter, r *http.Request, h http.Handler) {
+	var (
+		claims  *Claims
+		refresh bool
+	)
+
+	cookie, _ := r.Cookie(TokenCookieName)
+	if cookie != nil {
+		c, err := ParseToken(cookie.Value)
+		if err == nil {
+			claims = c
+			// Токен перевыпускается и при изменении роли пользователя в конфигурации,
+			// а также если он выпущен без jti и поэтому не может быть отозван
+			refresh = needsRefresh(c) || c.ID == "" || NormalizeRole(c.Role) != a.roleFor(c.UserID)
+		} else {
+			// Сам токен в лог не пишется
+			logg.FromContext(r.Context()).Info("Rejected token cookie", zap.Error(err))
 		}
+	}
 
-		if userID == "" {
-			userID = uuid.New().String()
-			tokenString, err := BuildJWTString(userID)
-			if err != nil {
-				zap.L().Error("Failed to create a new token", zap.Error(err))
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-				return
-			}
+	if claims != nil && claims.ID != "" && a.revoked != nil {
+		revoked, err := a.revoked.IsTokenRevoked(r.Context(), claims.ID)
+		if err != nil {
+			problem.Internal(w, r, "Failed to check token revocation", err)
+			return
+		}
+		if revoked {
+			logg.FromContext(r.Context()).Info("Rejected revoked token", zap.String("jti", claims.ID))
+			claims = nil
+		}
+	}
 
-			http.SetCookie(w, &http.Cookie{
-				Name:  "token",
-				Value: tokenString,
-				Path:  "/",
-			})
+	userID := ""
+	if claims != nil {
+		userID = claims.UserID
+	} else {
+		userID = uuid.New().String()
+		refresh = true
+	}
+
+	if refresh {
+		tokenString, err := BuildRoleJWTString(userID, a.roleFor(userID))
+		if err != nil {
+			problem.Internal(w, r, "Failed to create a new token", err)
+			return
+		}
+		if claims, err = ParseToken(tokenString); err != nil {
+			problem.Internal(w, r, "Failed to parse a new token", err)
+			return
 		}
 
-		ctx := context.WithValue(r.Context(), SecretKey, userID)
-		h.ServeHTTP(w, r.WithContext(ctx))
+		a.cookie.setTokenCookie(w, tokenString)
+	}
+
+	ctx := context.WithValue(r.Context(), SecretKey, userID)
+	ctx = context.WithValue(ctx, AuthMethodKey, AuthByCookie)
+	ctx = context.WithValue(ctx, ClaimsKey, claims)
+	ctx = context.WithValue(ctx, RoleKey, NormalizeRole(claims.Role))
+	h.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// ClearTokenCookie удаляет cookie с токеном у клиента
+func (c CookieConfig) ClearTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     TokenCookieName,
+		Value:    "",
+		Path:     "/",
+		Domain:   c.Domain,
+		MaxAge:   -1,
+		Secure:   c.Secure || c.SameSite == http.SameSiteNoneMode,
+		HttpOnly: c.HTTPOnly,
+		SameSite: c.SameSite,
 	})
 }
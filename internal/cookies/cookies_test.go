@@ -0,0 +1,192 @@
+package cookies
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/services/apikey"
+	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signToken подписывает токен с заданным сроком действия
+func signToken(t *testing.T, userID string, exp time.Time) string {
+	t.Helper()
+	s, err := SignClaims(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ID: "jti-" + userID, ExpiresAt: jwt.NewNumericDate(exp)},
+		UserID:           userID,
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func TestCookies_Refresh(t *testing.T) {
+	tests := []struct {
+		name        string
+		exp         time.Duration
+		wantCookie  bool
+		wantSameUID bool
+	}{
+		{name: "fresh token is kept", exp: TokenExp, wantCookie: false, wantSameUID: true},
+		{name: "token close to expiry is refreshed", exp: TokenRefreshWindow / 2, wantCookie: true, wantSameUID: true},
+		{name: "expired token gets new user", exp: -time.Minute, wantCookie: true, wantSameUID: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			h := Cookies(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = r.Context().Value(SecretKey).(string)
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.AddCookie(&http.Cookie{Name: "token", Value: signToken(t, "user1", time.Now().Add(tt.exp))})
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.wantSameUID, gotUserID == "user1")
+
+			cookies := w.Result().Cookies()
+			if !tt.wantCookie {
+				assert.Empty(t, cookies)
+				return
+			}
+			require.Len(t, cookies, 1)
+			claims, err := ParseToken(cookies[0].Value)
+			require.NoError(t, err)
+			assert.Equal(t, gotUserID, claims.UserID)
+			assert.False(t, needsRefresh(claims))
+		})
+	}
+}
+
+func TestParseExpiredToken(t *testing.T) {
+	expired := signToken(t, "user1", time.Now().Add(-24*time.Hour))
+
+	_, err := ParseToken(expired)
+	assert.Error(t, err)
+
+	claims, err := ParseExpiredToken(expired)
+	require.NoError(t, err)
+	assert.Equal(t, "user1", claims.UserID)
+
+	_, err = ParseExpiredToken(expired + "x")
+	assert.Error(t, err, "токен с испорченной подписью не должен приниматься")
+}
+
+func TestParseClaimToken(t *testing.T) {
+	claims, err := ParseClaimToken(signToken(t, "user1", time.Now().Add(-24*time.Hour)))
+	require.NoError(t, err)
+	assert.Equal(t, "user1", claims.UserID)
+
+	_, err = ParseClaimToken(signToken(t, "user1", time.Now().Add(-ClaimMaxAge-time.Hour)))
+	assert.ErrorIs(t, err, ErrClaimTokenTooOld)
+}
+
+func TestAuth_LegacyTokenWithoutJTI(t *testing.T) {
+	s, err := SignClaims(Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExp))},
+		UserID:           "user1",
+	})
+	require.NoError(t, err)
+
+	claims, err := ParseToken(s)
+	require.NoError(t, err)
+	assert.Empty(t, claims.ID)
+
+	var gotUserID string
+	h := Cookies(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = r.Context().Value(SecretKey).(string)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: TokenCookieName, Value: s})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.Equal(t, "user1", gotUserID, "прежний UserID должен сохраниться")
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	reissued, err := ParseToken(cookies[0].Value)
+	require.NoError(t, err)
+	assert.Equal(t, "user1", reissued.UserID)
+	assert.NotEmpty(t, reissued.ID, "перевыпущенный токен должен получить jti")
+}
+
+func TestAuth_RevokedToken(t *testing.T) {
+	store := storage.NewInMemoryStorage()
+	auth := NewAuth(AuthConfig{Revocations: store, Cookie: DefaultCookieConfig()})
+
+	var gotUserID string
+	h := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserID, _ = r.Context().Value(SecretKey).(string)
+	}))
+
+	tokenString := signToken(t, "user1", time.Now().Add(TokenExp))
+	serve := func() {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: TokenCookieName, Value: tokenString})
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	serve()
+	assert.Equal(t, "user1", gotUserID)
+
+	require.NoError(t, store.RevokeToken(context.Background(), "jti-user1", time.Now().Add(TokenExp)))
+	serve()
+	assert.NotEqual(t, "user1", gotUserID, "отозванный токен не должен приниматься")
+}
+
+func TestAuth_APIKeyRoleCappedByAdmins(t *testing.T) {
+	store := storage.NewInMemoryStorage()
+	plain, hash, err := apikey.Generate()
+	require.NoError(t, err)
+	require.NoError(t, store.InsertAPIKey(context.Background(), &objects.APIKey{
+		ID: "k1", UserID: "owner", Hash: hash, Role: RoleAdmin, CreatedAt: time.Now(),
+	}))
+
+	role := func(admins ...string) string {
+		var got string
+		h := NewAuth(AuthConfig{Keys: store, Cookie: DefaultCookieConfig(), Admins: admins}).
+			Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = RoleFromContext(r.Context())
+			}))
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-API-Key", plain)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		return got
+	}
+
+	assert.Equal(t, RoleAdmin, role("owner"))
+	assert.Equal(t, RoleUser, role(), "владелец исключен из администраторов")
+}
+
+func TestSetSigningKey(t *testing.T) {
+	prev := *signingKey.Load()
+	t.Cleanup(func() { signingKey.Store(&prev) })
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		RegisteredClaims: jwt.RegisteredClaims{ID: "jti", ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenExp))},
+		UserID:           "admin",
+	}).SignedString([]byte("supersecretkey"))
+	require.NoError(t, err)
+	_, err = ParseToken(forged)
+	assert.Error(t, err, "токен, подписанный общеизвестным ключом, не должен приниматься")
+
+	old, err := BuildJWTString("user1")
+	require.NoError(t, err)
+
+	SetSigningKey("0123456789abcdef0123456789abcdef")
+	_, err = ParseToken(old)
+	assert.Error(t, err, "после смены ключа прежние токены не принимаются")
+
+	fresh, err := BuildJWTString("user1")
+	require.NoError(t, err)
+	_, err = ParseToken(fresh)
+	assert.NoError(t, err)
+}
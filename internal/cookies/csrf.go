@@ -0,0 +1,217 @@
+package cookies
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"go.uber.org/zap"
+)
+
+// Режимы защиты от CSRF
+const (
+	// CSRFOff отключает проверку
+	CSRFOff = "off"
+	// CSRFOrigin проверяет заголовки Origin/Referer изменяющих запросов
+	CSRFOrigin = "origin"
+	// CSRFDoubleSubmit дополнительно требует совпадения cookie csrf_token
+	// и заголовка X-CSRF-Token
+	CSRFDoubleSubmit = "double-submit"
+)
+
+const (
+	// CSRFCookieName - имя cookie с CSRF-токеном (доступна JavaScript)
+	CSRFCookieName = "csrf_token"
+	// CSRFHeaderName - заголовок, в котором клиент повторяет CSRF-токен
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// CSRF middleware защиты от межсайтовой подделки запросов.
+// Проверяются только изменяющие запросы (POST, PUT, PATCH, DELETE),
+// аутентифицированные по cookie. Запросы с API-ключом не проверяются:
+// ключ не отправляется браузером автоматически.
+type CSRF struct {
+	mode    string
+	trusted atomic.Pointer[map[string]struct{}]
+	cookie  CookieConfig
+}
+
+// NewCSRF создает middleware защиты от CSRF.
+//
+// Параметры:
+//   - mode: CSRFOff, CSRFOrigin или CSRFDoubleSubmit (пусто — CSRFOrigin)
+//   - trustedOrigins: доверенные Origin вида "https://example.com"
+//   - cookie: атрибуты для cookie с CSRF-токеном
+//
+// Возвращает:
+//   - *CSRF: middleware
+//   - error: ошибка для неизвестного режима или некорректного Origin
+func NewCSRF(mode string, trustedOrigins []string, cookie CookieConfig) (*CSRF, error) {
+	if mode == "" {
+		mode = CSRFOrigin
+	}
+	switch mode {
+	case CSRFOff, CSRFOrigin, CSRFDoubleSubmit:
+	default:
+		return nil, fmt.Errorf("unknown CSRF mode %q", mode)
+	}
+
+	c := &CSRF{mode: mode, cookie: cookie}
+	if err := c.SetTrustedOrigins(trustedOrigins); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetTrustedOrigins заменяет список доверенных Origin, например после
+// перезагрузки конфигурации с новым базовым URL. Безопасен для вызова
+// во время обработки запросов.
+//
+// Возвращает:
+//   - error: ошибка для некорректного Origin; прежний список сохраняется
+func (c *CSRF) SetTrustedOrigins(trustedOrigins []string) error {
+	trusted := make(map[string]struct{}, len(trustedOrigins))
+	for _, o := range trustedOrigins {
+		o = strings.TrimSpace(o)
+		if o == "" {
+			continue
+		}
+		origin, err := originOf(o)
+		if err != nil {
+			return fmt.Errorf("invalid trusted origin %q: %w", o, err)
+		}
+		trusted[origin] = struct{}{}
+	}
+	c.trusted.Store(&trusted)
+	return nil
+}
+
+// originOf приводит URL к виду scheme://host[:port]
+func originOf(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("scheme and host are required")
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host), nil
+}
+
+// isSafeMethod сообщает, является ли метод неизменяющим
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// allowedOrigin проверяет Origin запроса (или Referer, если Origin нет).
+// Запрос без обоих заголовков считается не браузерным и пропускается.
+func (c *CSRF) allowedOrigin(r *http.Request) bool {
+	source := r.Header.Get("Origin")
+	if source == "" {
+		source = r.Header.Get("Referer")
+	}
+	if source == "" {
+		return true
+	}
+
+	origin, err := originOf(source)
+	if err != nil {
+		return false
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if origin == strings.ToLower(scheme+"://"+r.Host) {
+		return true
+	}
+
+	_, ok := (*c.trusted.Load())[origin]
+	return ok
+}
+
+// ensureToken возвращает CSRF-токен из cookie, при отсутствии выдает новый
+func (c *CSRF) ensureToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to generate CSRF token", zap.Error(err))
+		return ""
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Domain:   c.cookie.Domain,
+		Secure:   c.cookie.Secure || c.cookie.SameSite == http.SameSiteNoneMode,
+		HttpOnly: false, // клиент должен прочитать токен и повторить его в заголовке
+		SameSite: c.cookie.SameSite,
+	})
+	return token
+}
+
+// Middleware возвращает обработчик с CSRF-проверкой.
+// Должен подключаться после Auth, так как использует AuthMethodKey.
+//
+// Возможные ошибки:
+//   - 403 Forbidden (код csrf_failed): Origin не доверенный или CSRF-токен не совпадает
+func (c *CSRF) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.mode == CSRFOff {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		method, _ := r.Context().Value(AuthMethodKey).(string)
+		if method == AuthByAPIKey {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		var token string
+		if c.mode == CSRFDoubleSubmit {
+			token = c.ensureToken(w, r)
+		}
+
+		if isSafeMethod(r.Method) {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if !c.allowedOrigin(r) {
+			logg.FromContext(r.Context()).Warn("CSRF origin check failed",
+				logg.URL("origin", r.Header.Get("Origin")),
+				logg.URL("referer", r.Header.Get("Referer")))
+			problem.Write(w, r, http.StatusForbidden, problem.CodeCSRFFailed, "request origin is not trusted")
+			return
+		}
+
+		if c.mode == CSRFDoubleSubmit {
+			header := r.Header.Get(CSRFHeaderName)
+			if token == "" || subtle.ConstantTimeCompare([]byte(header), []byte(token)) != 1 {
+				logg.FromContext(r.Context()).Warn("CSRF token mismatch")
+				problem.Write(w, r, http.StatusForbidden, problem.CodeCSRFFailed, "missing or invalid "+CSRFHeaderName+" header")
+				return
+			}
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
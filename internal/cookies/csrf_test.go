@@ -0,0 +1,141 @@
+package cookies
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSRF_Origin(t *testing.T) {
+	csrf, err := NewCSRF(CSRFOrigin, []string{"https://short.example"}, DefaultCookieConfig())
+	require.NoError(t, err)
+
+	h := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name    string
+		method  string
+		origin  string
+		referer string
+		auth    string
+		code    int
+	}{
+		{name: "safe method", method: http.MethodGet, origin: "https://evil.example", auth: AuthByCookie, code: http.StatusOK},
+		{name: "no origin", method: http.MethodPost, auth: AuthByCookie, code: http.StatusOK},
+		{name: "same origin", method: http.MethodPost, origin: "http://example.com", auth: AuthByCookie, code: http.StatusOK},
+		{name: "trusted origin", method: http.MethodDelete, origin: "https://short.example", auth: AuthByCookie, code: http.StatusOK},
+		{name: "foreign origin", method: http.MethodDelete, origin: "https://evil.example", auth: AuthByCookie, code: http.StatusForbidden},
+		{name: "foreign referer", method: http.MethodPost, referer: "https://evil.example/page", auth: AuthByCookie, code: http.StatusForbidden},
+		{name: "api key is exempt", method: http.MethodPost, origin: "https://evil.example", auth: AuthByAPIKey, code: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "http://example.com/api/user/urls", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.referer != "" {
+				r.Header.Set("Referer", tt.referer)
+			}
+			r = r.WithContext(context.WithValue(r.Context(), AuthMethodKey, tt.auth))
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, r)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestCSRF_SetTrustedOrigins(t *testing.T) {
+	csrf, err := NewCSRF(CSRFOrigin, []string{"https://old.example"}, DefaultCookieConfig())
+	require.NoError(t, err)
+	post := func(origin string) bool {
+		r := httptest.NewRequest(http.MethodPost, "http://api.internal/", nil)
+		r.Header.Set("Origin", origin)
+		return csrf.allowedOrigin(r)
+	}
+	assert.True(t, post("https://old.example"))
+
+	require.NoError(t, csrf.SetTrustedOrigins([]string{"https://new.example"}))
+	assert.False(t, post("https://old.example"))
+	assert.True(t, post("https://new.example"))
+
+	// Некорректный список не заменяет действующий
+	assert.Error(t, csrf.SetTrustedOrigins([]string{"not-an-origin"}))
+	assert.True(t, post("https://new.example"))
+}
+
+func TestCSRF_DoubleSubmit(t *testing.T) {
+	csrf, err := NewCSRF(CSRFDoubleSubmit, nil, DefaultCookieConfig())
+	require.NoError(t, err)
+
+	h := csrf.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	withAuth := func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), AuthMethodKey, AuthByCookie))
+	}
+
+	// GET выдает CSRF-токен
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, withAuth(httptest.NewRequest(http.MethodGet, "/", nil)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var token *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == CSRFCookieName {
+			token = c
+		}
+	}
+	require.NotNil(t, token)
+	assert.False(t, token.HttpOnly)
+
+	// POST без заголовка отклоняется
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.AddCookie(token)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, withAuth(r))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	var p problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, problem.CodeCSRFFailed, p.Code)
+
+	// POST с совпадающим заголовком проходит
+	r = httptest.NewRequest(http.MethodPost, "/", nil)
+	r.AddCookie(token)
+	r.Header.Set(CSRFHeaderName, token.Value)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, withAuth(r))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestNewCSRF_InvalidMode(t *testing.T) {
+	_, err := NewCSRF("strict", nil, DefaultCookieConfig())
+	assert.Error(t, err)
+}
+
+func TestCookieAttributes(t *testing.T) {
+	auth := NewAuth(AuthConfig{Cookie: CookieConfig{Secure: true, HTTPOnly: true, SameSite: http.SameSiteStrictMode}})
+	h := auth.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	c := cookies[0]
+	assert.Equal(t, TokenCookieName, c.Name)
+	assert.True(t, c.Secure)
+	assert.True(t, c.HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, c.SameSite)
+	assert.Equal(t, int(TokenExp.Seconds()), c.MaxAge)
+}
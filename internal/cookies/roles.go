@@ -0,0 +1,70 @@
+package cookies
+
+import (
+	"context"
+	"net/http"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"go.uber.org/zap"
+)
+
+// Роли пользователей
+const (
+	// RoleUser - обычный пользователь, работает только со своими ссылками
+	RoleUser = "user"
+	// RoleAdmin - администратор, имеет доступ к /api/admin
+	RoleAdmin = "admin"
+)
+
+// RoleKey ключ контекста с ролью аутентифицированного пользователя
+const RoleKey contextKey = "role"
+
+// NormalizeRole приводит роль к одному из известных значений.
+// Пустая или неизвестная роль считается RoleUser.
+func NormalizeRole(role string) string {
+	if role == RoleAdmin {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+// lowerRole возвращает меньшую из двух ролей: RoleAdmin, только если
+// обе роли — RoleAdmin
+func lowerRole(a, b string) string {
+	if NormalizeRole(a) == RoleAdmin && NormalizeRole(b) == RoleAdmin {
+		return RoleAdmin
+	}
+	return RoleUser
+}
+
+// RoleFromContext возвращает роль пользователя из контекста запроса
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(RoleKey).(string)
+	return NormalizeRole(role)
+}
+
+// RequireRole возвращает middleware, пропускающий только пользователей
+// с указанной ролью. Должен подключаться после Auth.
+//
+// Возможные ошибки:
+//   - 403 Forbidden (код forbidden): у пользователя нет требуемой роли
+//
+// Пример использования:
+//
+//	r.With(cookies.RequireRole(cookies.RoleAdmin)).Get("/api/admin/users", h)
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if RoleFromContext(r.Context()) != role {
+				userID, _ := r.Context().Value(SecretKey).(string)
+				logg.FromContext(r.Context()).Warn("Forbidden: insufficient role",
+					logg.UserID(userID),
+					zap.String("required", role))
+				problem.Write(w, r, http.StatusForbidden, problem.CodeForbidden, "insufficient role")
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -4,9 +4,11 @@ package database
 
 import (
 	"database/sql"
-	"log"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/GevorkovG/go-shortener-tlp/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
 )
 
 // DBStore представляет хранилище данных с подключением к БД.
@@ -39,13 +41,11 @@ func InitDB(conn string) *DBStore {
 	}
 	db := NewDB(conn)
 	if err := db.Open(); err != nil {
-		log.Println("Don't connect DataBase")
-		log.Fatal(err)
+		zap.L().Fatal("Failed to connect to database", zap.Error(err))
 		return nil
 	}
 	if err := db.PingDB(); err != nil {
-		log.Println("Don't ping DataBase")
-		log.Fatal(err)
+		zap.L().Fatal("Failed to ping database", zap.Error(err))
 		return nil
 	}
 	return db
@@ -73,12 +73,16 @@ func NewDB(conf string) *DBStore {
 //   - error: ошибка подключения или ping проверки
 //
 // При успешном подключении сохраняет соединение в поле DB структуры DBStore.
+// Запросы, выполненные с контекстом, попадают в трассировку (tracing.QueryTracer).
 func (store *DBStore) Open() error {
 
-	db, err := sql.Open("pgx", store.DatabaseConf)
+	cfg, err := pgx.ParseConfig(store.DatabaseConf)
 	if err != nil {
 		return err
 	}
+	// Каждый SQL-запрос создает спан с текстом запроса
+	cfg.Tracer = tracing.QueryTracer{}
+	db := stdlib.OpenDB(*cfg)
 
 	if err := db.Ping(); err != nil {
 		return err
@@ -102,7 +106,7 @@ func (store *DBStore) Close() {
 // Используется для проверки работоспособности подключения.
 func (store *DBStore) PingDB() error {
 	if err := store.DB.Ping(); err != nil {
-		log.Println("don't ping Database")
+		zap.L().Error("Failed to ping database", zap.Error(err))
 		return err
 	}
 	return nil
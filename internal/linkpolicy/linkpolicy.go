@@ -0,0 +1,316 @@
+// Package linkpolicy решает, на какие адреса можно создавать короткие
+// ссылки и выполнять перенаправление: списки разрешенных и запрещенных
+// доменов, запрет IP-адресов и адресов внутренних сетей. Правила читаются
+// из файла и перечитываются без перезапуска сервера.
+package linkpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/netguard"
+	"github.com/GevorkovG/go-shortener-tlp/internal/urlnorm"
+)
+
+// Действия по умолчанию для адресов, не подпадающих ни под одно правило
+const (
+	ActionAllow = "allow"
+	ActionDeny  = "deny"
+)
+
+// Разрешение имен для block_private_networks
+const (
+	resolveTimeout  = 2 * time.Second
+	resolveCacheTTL = time.Minute
+	resolveCacheMax = 10000
+)
+
+// lookup разрешает имя хоста; подменяется в тестах
+var lookup = net.DefaultResolver.LookupNetIP
+
+// ErrDenied — адрес запрещен политикой; конкретная причина — в *Violation.
+var ErrDenied = errors.New("url is denied by link policy")
+
+// Violation описывает нарушение политики. Текст ошибки предназначен
+// для клиента и не содержит внутренних данных.
+type Violation struct {
+	Host   string // хост адреса
+	Reason string // причина запрета
+}
+
+// Error возвращает причину запрета
+func (v *Violation) Error() string {
+	return fmt.Sprintf("host %q is not allowed: %s", v.Host, v.Reason)
+}
+
+// Is позволяет проверять нарушение через errors.Is(err, ErrDenied)
+func (v *Violation) Is(target error) bool {
+	return target == ErrDenied
+}
+
+// Rules — правила в файле политики.
+//
+// Шаблоны доменов:
+//   - example.com — только этот домен
+//   - *.example.com — любой поддомен, но не сам example.com
+//   - .example.com — домен и все его поддомены
+//   - * — любой домен
+//
+// Запрет имеет приоритет над разрешением: домен из deny запрещен, даже
+// если подпадает под allow. Пример файла — разрешены только домены
+// example.com, кроме uploads.example.com:
+//
+//	{
+//	  "default": "deny",
+//	  "allow": [".example.com"],
+//	  "deny": ["uploads.example.com"],
+//	  "block_ip_literals": true,
+//	  "block_private_networks": true
+//	}
+type Rules struct {
+	Default              string   `json:"default"`                // ActionAllow или ActionDeny; пусто — ActionAllow
+	Allow                []string `json:"allow"`                  // разрешенные домены
+	Deny                 []string `json:"deny"`                   // запрещенные домены; имеют приоритет над allow
+	BlockIPLiterals      bool     `json:"block_ip_literals"`      // запрещать адреса с IP вместо домена
+	BlockPrivateNetworks bool     `json:"block_private_networks"` // запрещать loopback, частные и link-local адреса, localhost и домены, которые в них разрешаются
+}
+
+// Policy — проверенные правила. Нулевое значение разрешает все адреса.
+type Policy struct {
+	denyByDefault bool
+	allow         []pattern
+	deny          []pattern
+	blockIPs      bool
+	blockPrivate  bool
+	resolved      *hostCache // результаты разрешения имен при blockPrivate
+}
+
+// hostCache хранит результаты разрешения имен, чтобы не обращаться
+// к DNS при каждой проверке
+type hostCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedHost
+}
+
+// cachedHost — результат разрешения имени
+type cachedHost struct {
+	private bool // хотя бы один адрес относится к внутренней сети
+	expires time.Time
+}
+
+// pattern — шаблон домена
+type pattern struct {
+	domain    string // домен в нижнем регистре и punycode
+	apex      bool   // совпадает сам домен
+	subdomain bool   // совпадают поддомены
+}
+
+// Parse разбирает файл политики в формате JSON.
+func Parse(data []byte) (*Policy, error) {
+	var r Rules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse link policy: %w", err)
+	}
+	return Compile(r)
+}
+
+// Compile проверяет правила и подготавливает их к сопоставлению.
+//
+// Возвращает:
+//   - *Policy: политика
+//   - error: все некорректные шаблоны и неизвестное действие по умолчанию
+func Compile(r Rules) (*Policy, error) {
+	p := &Policy{blockIPs: r.BlockIPLiterals, blockPrivate: r.BlockPrivateNetworks}
+	if p.blockPrivate {
+		p.resolved = &hostCache{entries: make(map[string]cachedHost)}
+	}
+	var errs []error
+
+	switch r.Default {
+	case "", ActionAllow:
+	case ActionDeny:
+		p.denyByDefault = true
+	default:
+		errs = append(errs, fmt.Errorf("default must be %s or %s, got %q", ActionAllow, ActionDeny, r.Default))
+	}
+
+	compile := func(list string, raw []string) []pattern {
+		patterns := make([]pattern, 0, len(raw))
+		for _, s := range raw {
+			pt, err := parsePattern(s)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q: %w", list, s, err))
+				continue
+			}
+			patterns = append(patterns, pt)
+		}
+		return patterns
+	}
+	p.allow = compile("allow", r.Allow)
+	p.deny = compile("deny", r.Deny)
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parsePattern разбирает шаблон домена
+func parsePattern(s string) (pattern, error) {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return pattern{apex: true, subdomain: true}, nil
+	}
+
+	pt := pattern{apex: true}
+	switch {
+	case strings.HasPrefix(s, "*."):
+		s, pt.apex, pt.subdomain = s[2:], false, true
+	case strings.HasPrefix(s, "."):
+		s, pt.subdomain = s[1:], true
+	}
+	if s == "" || strings.ContainsAny(s, "*/:") {
+		return pattern{}, errors.New("must be a domain, *.domain, .domain or *")
+	}
+	domain, err := urlnorm.ToASCIIHost(s)
+	if err != nil {
+		return pattern{}, err
+	}
+	pt.domain = domain
+	return pt, nil
+}
+
+// match проверяет хост по шаблону
+func (pt pattern) match(host string) bool {
+	if pt.domain == "" {
+		return true
+	}
+	if host == pt.domain {
+		return pt.apex
+	}
+	return pt.subdomain && strings.HasSuffix(host, "."+pt.domain)
+}
+
+// Check проверяет адрес по политике.
+//
+// Параметры:
+//   - ctx: контекст запроса; ограничивает разрешение имени хоста
+//   - rawURL: абсолютный URL; ожидается каноническая форма (см. urlnorm)
+//
+// Возвращает:
+//   - error: *Violation при нарушении политики (errors.Is(err, ErrDenied))
+//
+// Порядок проверки:
+//  1. IP-адрес вместо домена — при block_ip_literals
+//  2. Адрес внутренней сети или localhost — при block_private_networks
+//  3. Совпадение с deny
+//  4. Домен, который разрешается во внутренний адрес, — при block_private_networks
+//  5. Совпадение с allow
+//  6. Действие по умолчанию
+//
+// Особенности:
+//   - Имя разрешается через DNS не дольше 2 секунд, результат кэшируется
+//     на минуту. Имя, которое не удалось разрешить, не считается внутренним
+func (p *Policy) Check(ctx context.Context, rawURL string) error {
+	return p.check(ctx, rawURL, true)
+}
+
+// CheckRules проверяет адрес по политике без разрешения имени через DNS
+// (пропускается шаг 4 из Policy.Check). Не блокируется на сетевых запросах,
+// поэтому подходит для перенаправления по уже созданной ссылке.
+//
+// Параметры:
+//   - rawURL: абсолютный URL; ожидается каноническая форма (см. urlnorm)
+//
+// Возвращает:
+//   - error: *Violation при нарушении политики (errors.Is(err, ErrDenied))
+func (p *Policy) CheckRules(rawURL string) error {
+	return p.check(context.Background(), rawURL, false)
+}
+
+// check реализует Check и CheckRules; resolve включает проверку
+// разрешенных адресов домена
+func (p *Policy) check(ctx context.Context, rawURL string, resolve bool) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return &Violation{Host: rawURL, Reason: "url has no host"}
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if ascii, err := urlnorm.ToASCIIHost(host); err == nil {
+		host = ascii
+	}
+
+	addr, err := netip.ParseAddr(host)
+	isIP := err == nil
+	if isIP {
+		if p.blockIPs {
+			return &Violation{Host: host, Reason: "IP addresses are not allowed, use a domain name"}
+		}
+		if p.blockPrivate && netguard.IsPrivate(addr) {
+			return &Violation{Host: host, Reason: "private network addresses are not allowed"}
+		}
+	} else if p.blockPrivate && (host == "localhost" || strings.HasSuffix(host, ".localhost")) {
+		return &Violation{Host: host, Reason: "private network addresses are not allowed"}
+	}
+
+	for _, pt := range p.deny {
+		if pt.match(host) {
+			return &Violation{Host: host, Reason: "domain is on the deny list"}
+		}
+	}
+	if resolve && !isIP && p.resolved != nil && p.resolved.private(ctx, host) {
+		return &Violation{Host: host, Reason: "domain resolves to a private network address"}
+	}
+	for _, pt := range p.allow {
+		if pt.match(host) {
+			return nil
+		}
+	}
+	if p.denyByDefault {
+		return &Violation{Host: host, Reason: "domain is not on the allow list"}
+	}
+	return nil
+}
+
+// private разрешает имя хоста и проверяет, есть ли среди адресов внутренние.
+// Результат кэшируется на resolveCacheTTL; при переполнении кэш очищается.
+// Прерванное по ctx разрешение не кэшируется.
+func (c *hostCache) private(ctx context.Context, host string) bool {
+	now := time.Now()
+	c.mu.Lock()
+	e, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && now.Before(e.expires) {
+		return e.private
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, resolveTimeout)
+	defer cancel()
+	addrs, err := lookup(ctx, "ip", host)
+	if err != nil && ctx.Err() != nil {
+		return false
+	}
+	private := false
+	for _, addr := range addrs {
+		if netguard.IsPrivate(addr) {
+			private = true
+			break
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= resolveCacheMax {
+		clear(c.entries)
+	}
+	c.entries[host] = cachedHost{private: private, expires: now.Add(resolveCacheTTL)}
+	return private
+}
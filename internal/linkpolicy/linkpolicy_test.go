@@ -0,0 +1,194 @@
+package linkpolicy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubLookup подменяет разрешение имен: known — адреса известных хостов,
+// остальные не разрешаются
+func stubLookup(t *testing.T, known map[string]string) *int {
+	t.Helper()
+	calls := new(int)
+	lookup = func(_ context.Context, _, host string) ([]netip.Addr, error) {
+		*calls++
+		if addr, ok := known[host]; ok {
+			return []netip.Addr{netip.MustParseAddr(addr)}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	t.Cleanup(func() { lookup = net.DefaultResolver.LookupNetIP })
+	return calls
+}
+
+func TestPolicy_Check(t *testing.T) {
+	stubLookup(t, map[string]string{
+		"internal.example": "10.0.0.1",
+		"docs.internal":    "127.0.0.1",
+		"public.example":   "93.184.215.14",
+	})
+	p, err := Compile(Rules{
+		Deny:                 []string{".phishing.example", "*.bit.ly", "Пример.рф"},
+		Allow:                []string{"docs.bit.ly", "docs.internal"},
+		BlockPrivateNetworks: true,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		url     string
+		allowed bool
+	}{
+		{"unlisted domain", "https://example.com/", true},
+		{"suffix rule apex", "https://phishing.example/login", false},
+		{"suffix rule subdomain", "https://a.b.phishing.example/", false},
+		{"suffix is not substring", "https://notphishing.example/", true},
+		{"wildcard subdomain", "https://x.bit.ly/abc", false},
+		{"wildcard excludes apex", "https://bit.ly/abc", true},
+		{"deny wins over allow", "https://docs.bit.ly/", false},
+		{"idn rule", "https://xn--e1afmkfd.xn--p1ai/", false},
+		{"upper case host", "https://X.BIT.LY/", false},
+		{"loopback", "http://127.0.0.1/", false},
+		{"private ipv4", "http://192.168.1.10/", false},
+		{"cgnat", "http://100.64.0.1/", false},
+		{"link-local metadata", "http://169.254.169.254/latest/meta-data", false},
+		{"private ipv6", "http://[fd00::1]/", false},
+		{"mapped loopback", "http://[::ffff:127.0.0.1]/", false},
+		{"localhost", "http://localhost:8080/", false},
+		{"public ip", "http://8.8.8.8/", true},
+		{"domain resolving to private ip", "https://internal.example/", false},
+		{"allow list does not bypass resolution", "https://docs.internal/", false},
+		{"domain resolving to public ip", "https://public.example/", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Check(ctx, tt.url)
+			if tt.allowed {
+				assert.NoError(t, err)
+				return
+			}
+			var v *Violation
+			require.ErrorAs(t, err, &v)
+			assert.ErrorIs(t, err, ErrDenied)
+			assert.NotEmpty(t, v.Reason)
+		})
+	}
+}
+
+func TestPolicy_ResolveCache(t *testing.T) {
+	calls := stubLookup(t, map[string]string{"internal.example": "10.0.0.1"})
+	p, err := Compile(Rules{BlockPrivateNetworks: true})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, p.Check(ctx, "https://internal.example/"), ErrDenied)
+	}
+	assert.Equal(t, 1, *calls)
+
+	// CheckRules не разрешает имена, но применяет остальные правила
+	*calls = 0
+	assert.NoError(t, p.CheckRules("https://other.example/"))
+	assert.ErrorIs(t, p.CheckRules("https://localhost/"), ErrDenied)
+	assert.Equal(t, 0, *calls)
+
+	// Без block_private_networks имена не разрешаются
+	p, err = Compile(Rules{})
+	require.NoError(t, err)
+	assert.NoError(t, p.Check(ctx, "https://internal.example/"))
+	assert.Equal(t, 0, *calls)
+}
+
+func TestPolicy_DefaultDeny(t *testing.T) {
+	ctx := context.Background()
+	p, err := Parse([]byte(`{"default":"deny","allow":[".example.com"],"block_ip_literals":true}`))
+	require.NoError(t, err)
+
+	assert.NoError(t, p.Check(ctx, "https://example.com/"))
+	assert.NoError(t, p.Check(ctx, "https://www.example.com/"))
+	assert.EqualError(t, p.Check(ctx, "https://other.org/"), `host "other.org" is not allowed: domain is not on the allow list`)
+	assert.ErrorContains(t, p.Check(ctx, "http://8.8.8.8/"), "IP addresses are not allowed")
+
+	var zero Policy
+	assert.NoError(t, zero.Check(ctx, "http://127.0.0.1/"), "zero policy allows everything")
+}
+
+func TestParse_Errors(t *testing.T) {
+	_, err := Parse([]byte(`{"default":"maybe","allow":["ex*ample.com"],"deny":["https://x.com",""]}`))
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "default must be")
+	assert.ErrorContains(t, err, `allow: "ex*ample.com"`)
+	assert.ErrorContains(t, err, `deny: "https://x.com"`)
+	assert.ErrorContains(t, err, `deny: ""`)
+
+	_, err = Parse([]byte(`{"allow":`))
+	assert.ErrorContains(t, err, "parse link policy")
+}
+
+func TestEngine_Reload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"deny":["evil.example"]}`), 0o644))
+
+	e, err := NewEngine(path)
+	require.NoError(t, err)
+	assert.Error(t, e.Check(ctx, "https://evil.example/"))
+	assert.NoError(t, e.Check(ctx, "https://good.example/"))
+
+	// Без изменений файл не перечитывается
+	reloaded, err := e.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Новые правила вступают в силу без пересоздания
+	touch := func(data string, at time.Time) {
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+		require.NoError(t, os.Chtimes(path, at, at))
+	}
+	touch(`{"deny":["good.example"]}`, time.Now().Add(time.Minute))
+	reloaded, err = e.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.NoError(t, e.Check(ctx, "https://evil.example/"))
+	assert.Error(t, e.Check(ctx, "https://good.example/"))
+
+	// Поврежденный файл не заменяет рабочую политику
+	touch(`{"deny":`, time.Now().Add(2*time.Minute))
+	_, err = e.Reload()
+	assert.Error(t, err)
+	assert.Error(t, e.Check(ctx, "https://good.example/"))
+
+	// Ошибка сообщается один раз, пока файл не изменится
+	reloaded, err = e.Reload()
+	assert.NoError(t, err)
+	assert.False(t, reloaded)
+
+	touch(`{"deny":["evil.example"]}`, time.Now().Add(3*time.Minute))
+	reloaded, err = e.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Error(t, e.Check(ctx, "https://evil.example/"))
+}
+
+func TestNewEngine(t *testing.T) {
+	ctx := context.Background()
+	e, err := NewEngine("")
+	require.NoError(t, err)
+	assert.NoError(t, e.Check(ctx, "http://127.0.0.1/"))
+
+	var nilEngine *Engine
+	assert.NoError(t, nilEngine.Check(ctx, "https://example.com/"))
+
+	_, err = NewEngine(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package linkpolicy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultReloadInterval — период проверки файла политики на изменение
+const DefaultReloadInterval = 5 * time.Second
+
+// Engine хранит текущую политику и перечитывает файл правил,
+// когда меняется время его модификации. При ошибке разбора
+// продолжает действовать прежняя политика.
+type Engine struct {
+	path string
+
+	mu      sync.RWMutex
+	policy  *Policy
+	modTime time.Time
+}
+
+// NewEngine загружает политику из файла.
+//
+// Параметры:
+//   - path: путь к JSON-файлу правил; пустая строка — разрешить все адреса
+//
+// Возвращает:
+//   - *Engine: движок политики
+//   - error: ошибка чтения или разбора файла
+func NewEngine(path string) (*Engine, error) {
+	e := &Engine{path: path, policy: &Policy{}}
+	if path == "" {
+		return e, nil
+	}
+	if _, err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Check проверяет адрес по текущей политике, см. Policy.Check.
+// Нулевой *Engine разрешает все адреса.
+func (e *Engine) Check(ctx context.Context, rawURL string) error {
+	if e == nil {
+		return nil
+	}
+	e.mu.RLock()
+	p := e.policy
+	e.mu.RUnlock()
+	return p.Check(ctx, rawURL)
+}
+
+// CheckRules проверяет адрес по текущей политике без разрешения имени
+// через DNS, см. Policy.CheckRules. Нулевой *Engine разрешает все адреса.
+func (e *Engine) CheckRules(rawURL string) error {
+	if e == nil {
+		return nil
+	}
+	e.mu.RLock()
+	p := e.policy
+	e.mu.RUnlock()
+	return p.CheckRules(rawURL)
+}
+
+// Reload перечитывает правила, если файл изменился с прошлой загрузки.
+//
+// Возвращает:
+//   - bool: true если политика была загружена заново
+//   - error: ошибка чтения или разбора файла; прежняя политика сохраняется,
+//     а тот же поврежденный файл повторно не разбирается
+func (e *Engine) Reload() (bool, error) {
+	if e == nil || e.path == "" {
+		return false, nil
+	}
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return false, err
+	}
+
+	e.mu.RLock()
+	unchanged := !e.modTime.IsZero() && info.ModTime().Equal(e.modTime)
+	e.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(e.path)
+	if err != nil {
+		return false, err
+	}
+	p, err := Parse(data)
+	if err != nil {
+		// Время модификации запоминается и для поврежденного файла: ошибка
+		// сообщается один раз, а не при каждой проверке до исправления файла
+		e.mu.Lock()
+		e.modTime = info.ModTime()
+		e.mu.Unlock()
+		return false, fmt.Errorf("load link policy %s: %w", e.path, err)
+	}
+
+	e.mu.Lock()
+	e.policy = p
+	e.modTime = info.ModTime()
+	e.mu.Unlock()
+	return true, nil
+}
+
+// Watch проверяет файл правил каждые interval до отмены ctx.
+// Без файла правил сразу возвращается.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	if e == nil || e.path == "" {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.reloadAndLog()
+		}
+	}
+}
+
+// reloadAndLog перечитывает правила и пишет результат в журнал
+func (e *Engine) reloadAndLog() {
+	reloaded, err := e.Reload()
+	if err != nil {
+		zap.L().Error("Failed to reload link policy, keeping the previous one",
+			zap.String("file", e.path), zap.Error(err))
+		return
+	}
+	if reloaded {
+		zap.L().Info("Link policy reloaded", zap.String("file", e.path))
+	}
+}
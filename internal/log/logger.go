@@ -1,13 +1,45 @@
-// Package log предоставляет кастомную реализацию логгера для приложения.
-// Поддерживает различные уровни логирования (Debug, Info, Warn, Error)
-// и конфигурируемые выходные потоки.
+// Package log настраивает логгер приложения: уровень, формат JSON или
+// консольный, вывод в файл с ротацией и сэмплирование повторяющихся
+// сообщений. Логгер устанавливается глобально и доступен через zap.L().
 package log
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/GevorkovG/go-shortener-tlp/internal/requestid"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Форматы записей лога
+const (
+	FormatJSON    = "json"    // одна JSON-запись на строку, для сборщиков логов
+	FormatConsole = "console" // читаемый текст для локальной разработки
+)
+
+// Options описывает настройки логгера.
+type Options struct {
+	Level      string       // debug, info, warn или error; пусто — info
+	Format     string       // FormatJSON или FormatConsole; пусто — FormatJSON
+	File       string       // путь к файлу лога; пусто — stderr
+	MaxSizeMB  int          // размер файла, после которого он ротируется
+	MaxBackups int          // число хранимых ротированных файлов, 0 — без ограничения
+	MaxAgeDays int          // срок хранения ротированных файлов в днях, 0 — без ограничения
+	Compress   bool         // сжимать ротированные файлы gzip
+	Sampling   bool         // ограничивать повторяющиеся сообщения
+	Redact     RedactPolicy // скрытие персональных данных, см. SetRedactPolicy
+}
+
+// Параметры сэмплирования: в каждую секунду записываются первые
+// samplingInitial одинаковых сообщений, затем каждое samplingThereafter-е
+const (
+	samplingInitial    = 100
+	samplingThereafter = 100
 )
 
 // responseData хранит информацию о HTTP-ответе
@@ -24,6 +56,9 @@ type loggingResponseWriter struct {
 
 // Write переопределяет метод Write для захвата размера ответа
 func (r *loggingResponseWriter) Write(b []byte) (int, error) {
+	if r.responseData.status == 0 {
+		r.responseData.status = http.StatusOK
+	}
 	size, err := r.ResponseWriter.Write(b)
 	r.responseData.size += size
 	return size, err
@@ -32,26 +67,148 @@ func (r *loggingResponseWriter) Write(b []byte) (int, error) {
 // WriteHeader переопределяет метод WriteHeader для захвата статуса ответа
 func (r *loggingResponseWriter) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
-	r.responseData.status = statusCode
+	if r.responseData.status == 0 {
+		r.responseData.status = statusCode
+	}
 }
 
-// Logger — глобальная переменная для логгера
-var Logger *zap.Logger
+// level — текущий уровень логирования; меняется без пересоздания логгера
+var level = zap.NewAtomicLevelAt(zap.InfoLevel)
+
+// New создает логгер по настройкам. Уровень общий для всех логгеров
+// пакета и меняется через SetLevel.
+//
+// Возвращает:
+//   - *zap.Logger: логгер
+//   - error: ошибка для неизвестного уровня или формата
+func New(opts Options) (*zap.Logger, error) {
+	if err := SetLevel(opts.Level); err != nil {
+		return nil, err
+	}
+	SetRedactPolicy(opts.Redact)
+
+	encCfg := zap.NewProductionEncoderConfig()
+	encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	switch opts.Format {
+	case "", FormatJSON:
+		encoder = zapcore.NewJSONEncoder(encCfg)
+	case FormatConsole:
+		encCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encCfg)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	var out zapcore.WriteSyncer = zapcore.Lock(os.Stderr)
+	if opts.File != "" {
+		// lumberjack сам синхронизирует запись
+		out = zapcore.AddSync(&lumberjack.Logger{
+			Filename:   opts.File,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			Compress:   opts.Compress,
+		})
+	}
 
-// InitLogger инициализирует логгер
-func InitLogger() {
-	var err error
-	Logger, err = zap.NewDevelopment() // Логгер для разработки (вывод в консоль)
+	core := zapcore.NewCore(encoder, out, level)
+	if opts.Sampling {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, samplingInitial, samplingThereafter)
+	}
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel)), nil
+}
+
+// Init создает логгер по настройкам и устанавливает его глобально
+// (zap.L(), zap.S()) вместо логгера по умолчанию, который ничего не пишет.
+// Стандартный пакет log также перенаправляется в этот логгер.
+//
+// Возвращает:
+//   - error: ошибка создания логгера; глобальный логгер при этом не меняется
+func Init(opts Options) error {
+	logger, err := New(opts)
 	if err != nil {
-		panic(err) // В случае ошибки завершаем программу
+		return err
 	}
+	zap.ReplaceGlobals(logger)
+	zap.RedirectStdLog(logger)
+	return nil
 }
 
-// LoggerMiddleware — middleware для логирования HTTP-запросов
+// SetLevel меняет уровень логирования работающего логгера.
+//
+// Параметры:
+//   - l: уровень: debug, info, warn или error; пустая строка — info
+//
+// Возвращает:
+//   - error: ошибка для неизвестного уровня
+func SetLevel(l string) error {
+	if l == "" {
+		l = "info"
+	}
+	parsed, err := zapcore.ParseLevel(l)
+	if err != nil {
+		return err
+	}
+	level.SetLevel(parsed)
+	return nil
+}
+
+// ctxKey — ключ контекста для логгера запроса
+type ctxKey struct{}
+
+// WithLogger возвращает контекст с логгером.
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext возвращает логгер из контекста, а если его там нет —
+// глобальный логгер. Логгер запроса (см. LoggerMiddleware) добавляет
+// к каждой записи поле request_id.
+//
+// Пример:
+//
+//	logg.FromContext(r.Context()).Error("Failed to save URL", zap.Error(err))
+func FromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.L()
+}
+
+// statusLevel выбирает уровень записи о запросе по коду ответа:
+// 5xx — error, 4xx — warn, остальные — info
+func statusLevel(status int) zapcore.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return zap.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zap.WarnLevel
+	default:
+		return zap.InfoLevel
+	}
+}
+
+// LoggerMiddleware — middleware для логирования HTTP-запросов. Уровень
+// записи зависит от кода ответа (см. statusLevel), поэтому при уровне
+// warn в лог попадают только неуспешные запросы.
+//
+// Особенности:
+//   - Строка запроса и адрес перенаправления сокращаются по политике
+//     скрытия данных (см. RedactPolicy)
+//   - Кладет в контекст запроса логгер с полем request_id (если
+//     requestid.Middleware стоит раньше); обработчики и хранилище
+//     получают его через FromContext
 func LoggerMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
+		logger := zap.L()
+		if id := requestid.FromContext(r.Context()); id != "" {
+			logger = logger.With(zap.String("request_id", id))
+		}
+		r = r.WithContext(WithLogger(r.Context(), logger))
+
 		// Создаем обёртку для захвата статуса и размера ответа
 		responseData := &responseData{}
 		lw := loggingResponseWriter{
@@ -62,14 +219,19 @@ func LoggerMiddleware(next http.Handler) http.Handler {
 		// Передаем управление следующему обработчику
 		next.ServeHTTP(&lw, r)
 
+		status := responseData.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
 		// Логируем информацию о запросе
-		Logger.Info("HTTP request",
-			zap.String("uri", r.RequestURI),
+		logger.Log(statusLevel(status), "HTTP request",
+			zap.String("uri", requestURI(r.RequestURI)),
 			zap.String("method", r.Method),
-			zap.Int("status", responseData.status),
+			zap.Int("status", status),
 			zap.Duration("duration", time.Since(start)),
 			zap.Int("size", responseData.size),
-			zap.String("location", w.Header().Get("Location")),
+			URL("location", w.Header().Get("Location")),
 		)
 	})
 }
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observe устанавливает глобальный логгер, запоминающий записи
+func observe(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+	return logs
+}
+
+func TestLoggerMiddleware_LevelByStatus(t *testing.T) {
+	logs := observe(t)
+
+	tests := []struct {
+		status int
+		want   zapcore.Level
+	}{
+		{http.StatusOK, zap.InfoLevel},
+		{http.StatusTemporaryRedirect, zap.InfoLevel},
+		{http.StatusNotFound, zap.WarnLevel},
+		{http.StatusServiceUnavailable, zap.ErrorLevel},
+	}
+	for _, tt := range tests {
+		h := LoggerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+
+		entries := logs.TakeAll()
+		require.Len(t, entries, 1)
+		assert.Equal(t, tt.want, entries[0].Level, "status %d", tt.status)
+		assert.EqualValues(t, tt.status, entries[0].ContextMap()["status"])
+	}
+}
+
+func TestLoggerMiddleware_ImplicitOK(t *testing.T) {
+	logs := observe(t)
+
+	h := LoggerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("pong"))
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 1)
+	assert.Equal(t, zap.InfoLevel, entries[0].Level)
+	assert.EqualValues(t, http.StatusOK, entries[0].ContextMap()["status"])
+	assert.EqualValues(t, 4, entries[0].ContextMap()["size"])
+}
+
+func TestLoggerMiddleware_RequestID(t *testing.T) {
+	logs := observe(t)
+
+	h := requestid.Middleware(LoggerMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context()).Info("from handler")
+	})))
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.TakeAll()
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		assert.Equal(t, "req-1", e.ContextMap()["request_id"], e.Message)
+	}
+}
+
+func TestFromContext_Fallback(t *testing.T) {
+	logs := observe(t)
+
+	FromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()).Info("global")
+	assert.Equal(t, 1, logs.Len())
+}
+
+func TestNew_JSONFile(t *testing.T) {
+	t.Cleanup(func() { _ = SetLevel("info") })
+	path := filepath.Join(t.TempDir(), "app.log")
+
+	logger, err := New(Options{Level: "warn", File: path, MaxSizeMB: 1})
+	require.NoError(t, err)
+	logger.Info("skipped")
+	logger.Warn("written", zap.String("key", "value"))
+	require.NoError(t, logger.Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "written", entry["msg"])
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "value", entry["key"])
+}
+
+func TestNew_Errors(t *testing.T) {
+	t.Cleanup(func() { _ = SetLevel("info") })
+
+	_, err := New(Options{Format: "text"})
+	assert.Error(t, err)
+	_, err = New(Options{Level: "verbose"})
+	assert.Error(t, err)
+}
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(func() { _ = SetLevel("info") })
+
+	logger, err := New(Options{Level: "error"})
+	require.NoError(t, err)
+	assert.False(t, logger.Core().Enabled(zap.WarnLevel))
+
+	require.NoError(t, SetLevel("debug"))
+	assert.True(t, logger.Core().Enabled(zap.DebugLevel))
+}
@@ -0,0 +1,150 @@
+package log
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sync/atomic"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Режимы записи URL в лог
+const (
+	URLFull = "full" // URL целиком, без учетных данных
+	URLPath = "path" // схема, хост и путь; строка запроса и фрагмент удаляются
+	URLHost = "host" // только схема и хост
+)
+
+// RedactPolicy описывает, как персональные данные попадают в лог.
+// Токены (JWT, API-ключи, cookie) в лог не пишутся ни при какой политике.
+type RedactPolicy struct {
+	HashUserIDs bool   // писать вместо UserID его хэш
+	URLs        string // URLFull, URLPath или URLHost
+	MaxItems    int    // сколько элементов массовой операции перечислять в записи
+}
+
+// DefaultRedactPolicy — политика по умолчанию
+var DefaultRedactPolicy = RedactPolicy{HashUserIDs: true, URLs: URLPath, MaxItems: 10}
+
+// policy — текущая политика; меняется без пересоздания логгера
+var policy atomic.Pointer[RedactPolicy]
+
+func init() {
+	SetRedactPolicy(DefaultRedactPolicy)
+}
+
+// SetRedactPolicy меняет политику скрытия данных работающего логгера.
+// Неизвестный режим URL заменяется на URLPath, отрицательный MaxItems — на 0.
+func SetRedactPolicy(p RedactPolicy) {
+	switch p.URLs {
+	case URLFull, URLPath, URLHost:
+	default:
+		p.URLs = URLPath
+	}
+	if p.MaxItems < 0 {
+		p.MaxItems = 0
+	}
+	policy.Store(&p)
+}
+
+// UserID возвращает поле userID. При HashUserIDs значение заменяется
+// первыми 16 символами SHA-256: записи одного пользователя по-прежнему
+// можно сопоставить, но сам идентификатор в лог не попадает.
+func UserID(id string) zap.Field {
+	return zap.String("userID", hashUserID(id, policy.Load().HashUserIDs))
+}
+
+// hashUserID хэширует непустой идентификатор
+func hashUserID(id string, hash bool) string {
+	if !hash || id == "" {
+		return id
+	}
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:8])
+}
+
+// URL возвращает поле с URL, сокращенным по политике. Учетные данные
+// (user:password@) удаляются всегда; строка, которая не разбирается
+// как URL, заменяется на "[invalid]".
+func URL(key, raw string) zap.Field {
+	return zap.String(key, redactURL(raw, policy.Load().URLs))
+}
+
+// redactURL сокращает URL по режиму
+func redactURL(raw, mode string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[invalid]"
+	}
+	u.User = nil
+	switch mode {
+	case URLFull:
+	case URLHost:
+		u.Path, u.RawPath, u.RawQuery, u.Fragment, u.RawFragment = "", "", "", "", ""
+		u.ForceQuery = false
+	default:
+		u.RawQuery, u.Fragment, u.RawFragment = "", "", ""
+		u.ForceQuery = false
+	}
+	return u.String()
+}
+
+// Links возвращает поле с числом ссылок и короткими идентификаторами
+// первых MaxItems из них. Оригинальные URL и владельцы не пишутся,
+// поэтому размер записи не зависит от размера пакета.
+func Links[L objects.Link | *objects.Link](key string, links []L) zap.Field {
+	shorts := make([]string, 0, min(len(links), policy.Load().MaxItems))
+	for i := 0; i < cap(shorts); i++ {
+		switch l := any(links[i]).(type) {
+		case objects.Link:
+			shorts = append(shorts, l.Short)
+		case *objects.Link:
+			if l != nil {
+				shorts = append(shorts, l.Short)
+			}
+		}
+	}
+	return zap.Object(key, bulk{count: len(links), sample: shorts})
+}
+
+// Shorts возвращает поле с числом коротких идентификаторов и первыми
+// MaxItems из них.
+func Shorts(key string, shorts []string) zap.Field {
+	n := min(len(shorts), policy.Load().MaxItems)
+	return zap.Object(key, bulk{count: len(shorts), sample: shorts[:n]})
+}
+
+// bulk — ограниченное описание массовой операции
+type bulk struct {
+	count  int
+	sample []string
+}
+
+// MarshalLogObject записывает число элементов и их выборку
+func (b bulk) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt("count", b.count)
+	return enc.AddArray("sample", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		for _, s := range b.sample {
+			arr.AppendString(s)
+		}
+		return nil
+	}))
+}
+
+// requestURI возвращает путь запроса с параметрами по политике URL:
+// в режиме URLFull — как есть, иначе без строки запроса
+func requestURI(uri string) string {
+	if policy.Load().URLs == URLFull {
+		return uri
+	}
+	if u, err := url.ParseRequestURI(uri); err == nil {
+		return u.EscapedPath()
+	}
+	return "[invalid]"
+}
@@ -0,0 +1,179 @@
+// Package metrics собирает метрики сервиса в формате Prometheus:
+// HTTP-запросы по шаблонам маршрутов chi, операции хранилища,
+// переходы по коротким ссылкам, очередь удаления и метрики среды Go.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace — префикс имен всех метрик сервиса
+const namespace = "shortener"
+
+// Результаты перехода по короткой ссылке (метка result)
+const (
+	RedirectOK       = "ok"        // перенаправление на оригинальный URL
+	RedirectGone     = "gone"      // ссылка удалена или отключена
+	RedirectNotFound = "not_found" // ссылка не найдена
+	RedirectBlocked  = "blocked"   // адрес запрещен политикой ссылок
+)
+
+// Registry — реестр метрик сервиса. Отдельный реестр вместо
+// prometheus.DefaultRegisterer не содержит метрик сторонних библиотек.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by chi route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by chi route pattern, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Storage operation latency by backend and method.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"backend", "method"})
+
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_errors_total",
+		Help:      "Storage operations that returned an error, by backend and method.",
+	}, []string{"backend", "method"})
+
+	redirects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Short link lookups by result: ok, gone or not_found.",
+	}, []string{"result"})
+
+	panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_panics_total",
+		Help:      "Handler panics recovered by the recovery middleware, by chi route pattern.",
+	}, []string{"route"})
+
+	deletionQueue = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "deletion_queue_depth",
+		Help:      "Short links accepted for deletion and not yet processed.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		httpRequests,
+		httpDuration,
+		storageDuration,
+		storageErrors,
+		redirects,
+		panics,
+		deletionQueue,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler возвращает обработчик, отдающий метрики в текстовом формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// statusWriter запоминает код ответа
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware считает запросы и их длительность. Метка route — шаблон
+// маршрута chi (например, "/{id}"), а не путь запроса, поэтому число
+// временных рядов не зависит от числа ссылок. Запросы, не совпавшие
+// ни с одним маршрутом, получают метку "unmatched".
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+
+		next.ServeHTTP(sw, r)
+
+		route := routeLabel(r)
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := prometheus.Labels{"route": route, "method": r.Method, "status": strconv.Itoa(status)}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routeLabel возвращает шаблон маршрута chi или "unmatched"
+func routeLabel(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if p := rctx.RoutePattern(); p != "" {
+			return p
+		}
+	}
+	return "unmatched"
+}
+
+// Panic учитывает перехваченную панику обработчика запроса r.
+func Panic(r *http.Request) {
+	panics.WithLabelValues(routeLabel(r)).Inc()
+}
+
+// StorageObserver возвращает наблюдателя операций хранилища
+// (см. storage.Instrument) для бэкенда с указанным именем.
+func StorageObserver(backend string) func(ctx context.Context, method string) (context.Context, func(error)) {
+	return func(ctx context.Context, method string) (context.Context, func(error)) {
+		start := time.Now()
+		return ctx, func(err error) {
+			storageDuration.WithLabelValues(backend, method).Observe(time.Since(start).Seconds())
+			if err != nil {
+				storageErrors.WithLabelValues(backend, method).Inc()
+			}
+		}
+	}
+}
+
+// Redirect учитывает переход по короткой ссылке с результатом
+// RedirectOK, RedirectGone, RedirectNotFound или RedirectBlocked.
+func Redirect(result string) {
+	redirects.WithLabelValues(result).Inc()
+}
+
+// DeletionQueued меняет глубину очереди удаления на n
+// (положительное — ссылки приняты, отрицательное — обработаны).
+func DeletionQueued(n int) {
+	deletionQueue.Add(float64(n))
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_UsesRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	for _, path := range []string{"/abc", "/def", "/ghi"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/a/b", nil))
+
+	assert.Equal(t, 3.0, testutil.ToFloat64(httpRequests.WithLabelValues("/{id}", http.MethodGet, "307")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("unmatched", http.MethodPost, "404")))
+}
+
+func TestStorageObserver(t *testing.T) {
+	observe := StorageObserver("memory")
+
+	_, done := observe(context.Background(), "GetOriginal")
+	done(nil)
+	_, done = observe(context.Background(), "GetOriginal")
+	done(errors.New("not found"))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(storageErrors.WithLabelValues("memory", "GetOriginal")))
+	assert.Equal(t, 1, testutil.CollectAndCount(storageDuration, namespace+"_storage_operation_duration_seconds"))
+}
+
+func TestHandler(t *testing.T) {
+	Redirect(RedirectOK)
+	DeletionQueued(3)
+	DeletionQueued(-1)
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`shortener_redirects_total{result="ok"} 1`,
+		"shortener_deletion_queue_depth 2",
+		"go_goroutines",
+	} {
+		assert.True(t, strings.Contains(body, want), "нет %q в выводе", want)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Panic(r)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(panics.WithLabelValues("/{id}")))
+}
@@ -0,0 +1,80 @@
+// Package netguard не дает серверу обращаться к адресам внутренних сетей
+// по адресам, которые задают пользователи (защита от SSRF): loopback,
+// частные сети, link-local, CGNAT и неопределенный адрес.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrPrivate — адрес относится к внутренней сети
+var ErrPrivate = errors.New("address is in a loopback, private or link-local network")
+
+// cgnat — общее адресное пространство провайдеров (RFC 6598)
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// lookup разрешает имя хоста; подменяется в тестах
+var lookup = net.DefaultResolver.LookupNetIP
+
+// IsPrivate проверяет, что адрес не маршрутизируется в интернете:
+// loopback, частные сети, link-local, CGNAT и неопределенный адрес.
+// IPv4-адреса, отображенные в IPv6 (::ffff:a.b.c.d), проверяются как IPv4.
+func IsPrivate(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsUnspecified() || addr.IsInterfaceLocalMulticast() ||
+		cgnat.Contains(addr)
+}
+
+// CheckHost разрешает имя хоста и проверяет все полученные адреса.
+//
+// Параметры:
+//   - ctx: контекст; ограничивает время разрешения имени
+//   - host: имя хоста или IP-адрес без порта
+//
+// Возвращает:
+//   - error: ErrPrivate, если хотя бы один адрес внутренний, или ошибка
+//     разрешения имени
+//
+// Особенности:
+//   - Проверка при регистрации не защищает от смены DNS-записи после нее;
+//     при соединении используйте Control
+func CheckHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if IsPrivate(addr) {
+			return fmt.Errorf("%w: %s", ErrPrivate, host)
+		}
+		return nil
+	}
+
+	addrs, err := lookup(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if IsPrivate(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrPrivate, host, addr)
+		}
+	}
+	return nil
+}
+
+// Control запрещает соединение с внутренним адресом; подходит для
+// net.Dialer.Control. Вызывается после разрешения имени для каждого
+// адреса, к которому выполняется подключение, поэтому смена DNS-записи
+// (DNS rebinding) не позволяет обойти проверку.
+func Control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if IsPrivate(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivate, addrPort.Addr())
+	}
+	return nil
+}
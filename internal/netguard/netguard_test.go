@@ -0,0 +1,71 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPrivate(t *testing.T) {
+	tests := []struct {
+		addr    string
+		private bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.10", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2001:4860:4860::8888", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.private, IsPrivate(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	lookup = func(_ context.Context, _, host string) ([]netip.Addr, error) {
+		switch host {
+		case "public.example":
+			return []netip.Addr{netip.MustParseAddr("93.184.215.14")}, nil
+		case "mixed.example":
+			return []netip.Addr{netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr("10.0.0.1")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	t.Cleanup(func() { lookup = net.DefaultResolver.LookupNetIP })
+
+	ctx := context.Background()
+	assert.NoError(t, CheckHost(ctx, "public.example"))
+	assert.NoError(t, CheckHost(ctx, "8.8.8.8"))
+	assert.ErrorIs(t, CheckHost(ctx, "mixed.example"), ErrPrivate)
+	assert.ErrorIs(t, CheckHost(ctx, "169.254.169.254"), ErrPrivate)
+	err := CheckHost(ctx, "missing.example")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrPrivate)
+}
+
+func TestControl(t *testing.T) {
+	assert.ErrorIs(t, Control("tcp", "127.0.0.1:80", nil), ErrPrivate)
+	assert.ErrorIs(t, Control("tcp", "[::1]:443", nil), ErrPrivate)
+	assert.NoError(t, Control("tcp", "8.8.8.8:443", nil))
+
+	// Соединение с loopback-адресом отклоняется до отправки запроса
+	dialer := &net.Dialer{Control: Control}
+	_, err := dialer.Dial("tcp", "127.0.0.1:1")
+	assert.ErrorIs(t, err, ErrPrivate)
+}
@@ -2,7 +2,10 @@
 // для сервиса сокращения URL
 package objects
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Link представляет структуру для хранения информации о URL.
 // Используется для хранения как оригинальных, так и сокращенных URL
@@ -11,6 +14,33 @@ type Link struct {
 	Original    string `json:"original_url"` //Оригинальный URL
 	UserID      string `json:"-"`            //ID пользователя
 	DeletedFlag bool   `json:"-"`            //Флаг удаления
+	Disabled    bool   `json:"-"`            //Ссылка отключена администратором
+}
+
+// APIKey представляет долгоживущий API-ключ пользователя.
+// Сам ключ не хранится — только его хеш.
+type APIKey struct {
+	ID        string    `json:"id"`         //Идентификатор ключа
+	UserID    string    `json:"user_id"`    //ID пользователя-владельца
+	Name      string    `json:"name"`       //Произвольное имя ключа
+	Hash      string    `json:"hash"`       //SHA-256 хеш ключа
+	CreatedAt time.Time `json:"created_at"` //Время создания
+	Revoked   bool      `json:"revoked"`    //Флаг отзыва
+	Role      string    `json:"role"`       //Роль, с которой аутентифицируется ключ
+}
+
+// LinkFilter задает условия выборки ссылок для администратора.
+type LinkFilter struct {
+	Query  string // подстрока в коротком или оригинальном URL
+	UserID string // только ссылки указанного пользователя
+	Limit  int    // максимальное количество записей (0 — без ограничения)
+	Offset int    // количество пропускаемых записей
+}
+
+// UserStat содержит сведения о пользователе и количестве его ссылок.
+type UserStat struct {
+	UserID string `json:"user_id"`
+	Links  int    `json:"links"`
 }
 
 // Storage определяет интерфейс для работы с хранилищем URL.
@@ -18,9 +48,90 @@ type Link struct {
 type Storage interface {
 	Insert(ctx context.Context, link *Link) error
 	InsertLinks(ctx context.Context, links []*Link) error
-	GetOriginal(short string) (*Link, error)
-	GetShort(original string) (*Link, error)
-	GetAllByUserID(userID string) ([]Link, error)
-	MarkAsDeleted(userID string, short string) error
-	Ping() error
+	GetOriginal(ctx context.Context, short string) (*Link, error)
+	GetShort(ctx context.Context, original string) (*Link, error)
+	GetAllByUserID(ctx context.Context, userID string) ([]Link, error)
+	MarkAsDeleted(ctx context.Context, userID string, short string) error
+	Ping(ctx context.Context) error
+}
+
+// APIKeyStorage определяет интерфейс хранилища API-ключей.
+// Реализуется всеми бэкендами хранилища наряду со Storage.
+type APIKeyStorage interface {
+	InsertAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKeyByHash(ctx context.Context, hash string) (*APIKey, error)
+	GetAPIKeysByUserID(ctx context.Context, userID string) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID string, id string) error
+}
+
+// OwnershipStorage определяет интерфейс переноса ссылок между пользователями.
+// Используется для восстановления доступа к ссылкам после смены токена.
+type OwnershipStorage interface {
+	TransferLinks(ctx context.Context, fromUserID string, toUserID string) (int, error)
+}
+
+// RevocationStorage определяет интерфейс списка отозванных токенов.
+// Записи хранятся до переданного expiresAt и затем удаляются.
+type RevocationStorage interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// AdminStorage определяет интерфейс хранилища для административных операций
+// над ссылками всех пользователей.
+type AdminStorage interface {
+	ListLinks(ctx context.Context, filter LinkFilter) ([]Link, error)
+	SetLinkDisabled(ctx context.Context, short string, disabled bool) error
+	ListUsers(ctx context.Context) ([]UserStat, error)
+}
+
+// Webhook представляет подписку пользователя на события его ссылок.
+type Webhook struct {
+	ID        string    `json:"id"`         //Идентификатор подписки
+	UserID    string    `json:"user_id"`    //ID пользователя-владельца
+	URL       string    `json:"url"`        //Адрес, на который отправляются события
+	Secret    string    `json:"secret"`     //Ключ подписи тела запроса (HMAC-SHA256)
+	Events    []string  `json:"events"`     //Типы событий, на которые оформлена подписка
+	CreatedAt time.Time `json:"created_at"` //Время создания
+}
+
+// Статусы доставки вебхука
+const (
+	DeliveryPending   = "pending"   // ожидает отправки или повторной попытки
+	DeliveryDelivered = "delivered" // получатель ответил 2xx
+	DeliveryDead      = "dead"      // попытки исчерпаны, запись в dead-letter
+)
+
+// WebhookDelivery представляет одну доставку события по подписке.
+// Доставки со статусом DeliveryDead образуют dead-letter хранилище.
+type WebhookDelivery struct {
+	ID           string    `json:"id"`                      //Идентификатор доставки
+	WebhookID    string    `json:"webhook_id"`              //Идентификатор подписки
+	UserID       string    `json:"user_id"`                 //ID владельца подписки
+	Event        string    `json:"event"`                   //Тип события
+	Payload      string    `json:"payload"`                 //Тело запроса (JSON)
+	Status       string    `json:"status"`                  //Статус доставки
+	Attempts     int       `json:"attempts"`                //Количество выполненных попыток
+	ResponseCode int       `json:"response_code,omitempty"` //HTTP-код последнего ответа
+	LastError    string    `json:"last_error,omitempty"`    //Ошибка последней попытки
+	CreatedAt    time.Time `json:"created_at"`              //Время создания
+	UpdatedAt    time.Time `json:"updated_at"`              //Время последнего изменения
+}
+
+// MaxDeliveriesPerWebhook — сколько последних доставок хранится по каждой
+// подписке; более старые записи удаляются при сохранении новых
+const MaxDeliveriesPerWebhook = 100
+
+// WebhookStorage определяет интерфейс хранилища подписок на вебхуки
+// и истории их доставки. История по каждой подписке ограничена
+// MaxDeliveriesPerWebhook последними доставками.
+type WebhookStorage interface {
+	InsertWebhook(ctx context.Context, hook *Webhook) error
+	GetWebhooksByUserID(ctx context.Context, userID string) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, userID string, id string) error
+	SaveDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	GetDeliveries(ctx context.Context, userID string, webhookID string) ([]WebhookDelivery, error)
+	// MarkFirstClick отмечает переход по ссылке и возвращает true,
+	// если это первый переход.
+	MarkFirstClick(ctx context.Context, short string) (bool, error)
 }
@@ -0,0 +1,239 @@
+// Package openapi содержит описание JSON API сервиса в формате OpenAPI 3
+// (openapi.json) и проверку запросов и ответов по этому описанию.
+//
+// Поддерживается подмножество JSON Schema, используемое в описании:
+// type, format (uri), required, properties, items, enum, minLength,
+// maxLength, minItems, maxItems и ссылки $ref на components.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"go.uber.org/zap"
+)
+
+// document — описание API, встроенное в бинарный файл
+//
+//go:embed openapi.json
+var document []byte
+
+// Spec — разобранное описание API.
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+// Components — переиспользуемые схемы и ответы.
+type Components struct {
+	Schemas   map[string]*Schema   `json:"schemas"`
+	Responses map[string]*Response `json:"responses"`
+}
+
+// Operation — метод пути.
+type Operation struct {
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// RequestBody — описание тела запроса.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response — описание ответа; Content пуст для ответа без тела.
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType — схема тела для одного типа содержимого.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema — схема JSON-значения.
+type Schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Required   []string           `json:"required"`
+	Properties map[string]*Schema `json:"properties"`
+	Items      *Schema            `json:"items"`
+	Enum       []any              `json:"enum"`
+	MinLength  *int               `json:"minLength"`
+	MaxLength  *int               `json:"maxLength"`
+	MinItems   *int               `json:"minItems"`
+	MaxItems   *int               `json:"maxItems"`
+}
+
+// spec — описание API, разобранное при запуске
+var spec = mustParse(document)
+
+// mustParse разбирает встроенное описание; ошибка означает
+// некорректный openapi.json и обнаруживается тестами
+func mustParse(data []byte) *Spec {
+	s, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Parse разбирает описание API в формате JSON и проверяет, что все
+// ссылки $ref указывают на существующие компоненты.
+func Parse(data []byte) (*Spec, error) {
+	var s Spec
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse openapi document: %w", err)
+	}
+	for path, ops := range s.Paths {
+		for method, op := range ops {
+			if err := s.checkRefs(op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// Default возвращает встроенное описание API.
+func Default() *Spec {
+	return spec
+}
+
+// Handler отдает описание API.
+// Эндпоинт: GET /api/openapi.json
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(document); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// Operation возвращает описание метода для запроса. Сегменты пути
+// в фигурных скобках ({id}) совпадают с любым непустым сегментом.
+//
+// Возвращает:
+//   - *Operation: описание метода
+//   - bool: false, если метод не описан
+func (s *Spec) Operation(method, path string) (*Operation, bool) {
+	if ops, ok := s.Paths[path]; ok {
+		op, ok := ops[strings.ToLower(method)]
+		return op, ok
+	}
+	for pattern, ops := range s.Paths {
+		if !matchPath(pattern, path) {
+			continue
+		}
+		op, ok := ops[strings.ToLower(method)]
+		return op, ok
+	}
+	return nil, false
+}
+
+// matchPath сравнивает путь запроса с шаблоном пути из описания
+func matchPath(pattern, path string) bool {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(want) != len(got) {
+		return false
+	}
+	for i := range want {
+		if strings.HasPrefix(want[i], "{") && strings.HasSuffix(want[i], "}") {
+			if got[i] == "" {
+				return false
+			}
+			continue
+		}
+		if want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// response возвращает описание ответа с кодом status или ответа default
+func (s *Spec) response(op *Operation, status int) (*Response, bool) {
+	resp, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return nil, false
+	}
+	return s.resolveResponse(resp)
+}
+
+// resolveResponse возвращает ответ, на который указывает $ref
+func (s *Spec) resolveResponse(resp *Response) (*Response, bool) {
+	if resp.Ref == "" {
+		return resp, true
+	}
+	name, ok := strings.CutPrefix(resp.Ref, "#/components/responses/")
+	target, found := s.Components.Responses[name]
+	return target, ok && found
+}
+
+// resolve возвращает схему, на которую указывает $ref
+func (s *Spec) resolve(schema *Schema) (*Schema, error) {
+	if schema.Ref == "" {
+		return schema, nil
+	}
+	name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+	target, found := s.Components.Schemas[name]
+	if !ok || !found {
+		return nil, fmt.Errorf("unresolved reference %q", schema.Ref)
+	}
+	return target, nil
+}
+
+// checkRefs проверяет ссылки в теле запроса и ответах метода
+func (s *Spec) checkRefs(op *Operation) error {
+	var schemas []*Schema
+	if op.RequestBody != nil {
+		for _, m := range op.RequestBody.Content {
+			schemas = append(schemas, m.Schema)
+		}
+	}
+	for code, r := range op.Responses {
+		resp, ok := s.resolveResponse(r)
+		if !ok {
+			return fmt.Errorf("response %s: unresolved reference %q", code, r.Ref)
+		}
+		for _, m := range resp.Content {
+			schemas = append(schemas, m.Schema)
+		}
+	}
+	for _, schema := range schemas {
+		if err := s.checkSchema(schema, map[*Schema]bool{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSchema рекурсивно проверяет ссылки схемы
+func (s *Spec) checkSchema(schema *Schema, seen map[*Schema]bool) error {
+	if schema == nil || seen[schema] {
+		return nil
+	}
+	seen[schema] = true
+	resolved, err := s.resolve(schema)
+	if err != nil {
+		return err
+	}
+	for _, p := range resolved.Properties {
+		if err := s.checkSchema(p, seen); err != nil {
+			return err
+		}
+	}
+	return s.checkSchema(resolved.Items, seen)
+}
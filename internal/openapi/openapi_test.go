@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Default(t *testing.T) {
+	s, err := Parse(document)
+	require.NoError(t, err)
+	for _, path := range []string{"/api/shorten", "/api/shorten/batch", "/api/user/urls"} {
+		assert.Contains(t, s.Paths, path)
+	}
+
+	_, err = Parse([]byte(`{"paths":{"/x":{"post":{"responses":{"200":{"$ref":"#/components/responses/Missing"}}}}}}`))
+	assert.ErrorContains(t, err, "POST /x")
+}
+
+func TestOperation(t *testing.T) {
+	s, err := Parse([]byte(`{"paths":{"/api/user/urls":{"get":{}},"/api/user/{id}":{"post":{}}}}`))
+	require.NoError(t, err)
+
+	_, ok := s.Operation(http.MethodGet, "/api/user/urls")
+	assert.True(t, ok)
+	_, ok = s.Operation(http.MethodPost, "/api/user/42")
+	assert.True(t, ok)
+	_, ok = s.Operation(http.MethodPost, "/api/user/42/more")
+	assert.False(t, ok)
+	_, ok = s.Operation(http.MethodDelete, "/api/user/urls")
+	assert.False(t, ok)
+}
+
+func TestHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	Handler(w, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		code        string
+		errors      []problem.FieldError
+	}{
+		{
+			name: "valid shorten", method: http.MethodPost, path: "/api/shorten",
+			contentType: "application/json; charset=utf-8", body: `{"url":"https://example.com"}`,
+			status: http.StatusOK,
+		},
+		{
+			name: "missing url", method: http.MethodPost, path: "/api/shorten",
+			body:   `{"link":"https://example.com"}`,
+			status: http.StatusBadRequest, code: problem.CodeValidation,
+			errors: []problem.FieldError{{Field: "/url", Message: "is required"}},
+		},
+		{
+			name: "url of wrong type", method: http.MethodPost, path: "/api/shorten",
+			body:   `{"url":42}`,
+			status: http.StatusBadRequest, code: problem.CodeValidation,
+			errors: []problem.FieldError{{Field: "/url", Message: "must be a string"}},
+		},
+		{
+			name: "relative url", method: http.MethodPost, path: "/api/shorten",
+			body:   `{"url":"example.com/page"}`,
+			status: http.StatusBadRequest, code: problem.CodeValidation,
+			errors: []problem.FieldError{{Field: "/url", Message: "must be an absolute URI"}},
+		},
+		{
+			name: "batch item errors", method: http.MethodPost, path: "/api/shorten/batch",
+			body:   `[{"correlation_id":"1","original_url":"https://example.com"},{"correlation_id":""},"x"]`,
+			status: http.StatusBadRequest, code: problem.CodeValidation,
+			errors: []problem.FieldError{
+				{Field: "/1/original_url", Message: "is required"},
+				{Field: "/1/correlation_id", Message: "must be at least 1 characters long"},
+				{Field: "/2", Message: "must be an object"},
+			},
+		},
+		{
+			name: "batch is not an array", method: http.MethodPost, path: "/api/shorten/batch",
+			body:   `{"correlation_id":"1"}`,
+			status: http.StatusBadRequest, code: problem.CodeValidation,
+			errors: []problem.FieldError{{Field: "", Message: "must be an array"}},
+		},
+		{
+			name: "delete with empty id", method: http.MethodDelete, path: "/api/user/urls",
+			body:   `["abc",""]`,
+			status: http.StatusBadRequest, code: problem.CodeValidation,
+			errors: []problem.FieldError{{Field: "/1", Message: "must be at least 1 characters long"}},
+		},
+		{
+			name: "invalid json", method: http.MethodPost, path: "/api/shorten",
+			body:   `{"url":`,
+			status: http.StatusBadRequest, code: problem.CodeInvalidJSON,
+		},
+		{
+			name: "trailing data", method: http.MethodPost, path: "/api/shorten",
+			body:   `{"url":"https://example.com"} {}`,
+			status: http.StatusBadRequest, code: problem.CodeInvalidJSON,
+		},
+		{
+			name: "empty body", method: http.MethodPost, path: "/api/shorten",
+			status: http.StatusBadRequest, code: problem.CodeEmptyBody,
+		},
+		{
+			name: "unsupported media type", method: http.MethodPost, path: "/api/shorten",
+			contentType: "text/plain", body: "https://example.com",
+			status: http.StatusUnsupportedMediaType, code: problem.CodeUnsupportedMediaType,
+		},
+		{
+			name: "undocumented path", method: http.MethodPost, path: "/",
+			contentType: "text/plain", body: "not json",
+			status: http.StatusOK,
+		},
+		{
+			name: "operation without body", method: http.MethodGet, path: "/api/user/urls",
+			status: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := Default().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				got = string(data)
+			}))
+
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			require.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.body, got, "body must reach the handler unchanged")
+				return
+			}
+			assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+			var p problem.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+			assert.Equal(t, tt.code, p.Code)
+			assert.Equal(t, tt.errors, p.Errors)
+		})
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	s := Default()
+	jsonHeader := http.Header{"Content-Type": []string{"application/json"}}
+
+	assert.NoError(t, s.ValidateResponse(http.MethodPost, "/api/shorten", http.StatusCreated, jsonHeader,
+		[]byte(`{"result":"http://localhost:8080/abc","submitted_url":"https://example.com","stored_url":"https://example.com/"}`)))
+	assert.NoError(t, s.ValidateResponse(http.MethodGet, "/api/user/urls", http.StatusNoContent, http.Header{}, nil))
+
+	err := s.ValidateResponse(http.MethodPost, "/api/shorten", http.StatusCreated, jsonHeader, []byte(`{"short":"abc","submitted_url":"x","stored_url":"https://example.com/"}`))
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Equal(t, []problem.FieldError{{Field: "/result", Message: "is required"}}, verr.Errors)
+
+	assert.ErrorContains(t, s.ValidateResponse(http.MethodPost, "/api/shorten", http.StatusTeapot, jsonHeader, nil), "not documented")
+	assert.ErrorContains(t, s.ValidateResponse(http.MethodPost, "/api/shorten", http.StatusCreated,
+		http.Header{"Content-Type": []string{"text/plain"}}, []byte("x")), "not documented")
+	assert.ErrorContains(t, s.ValidateResponse(http.MethodGet, "/api/user/urls", http.StatusNoContent, http.Header{}, []byte("x")), "no body")
+}
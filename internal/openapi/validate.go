@@ -0,0 +1,309 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"go.uber.org/zap"
+)
+
+// defaultMediaType — тип содержимого запроса без заголовка Content-Type
+const defaultMediaType = "application/json"
+
+// ValidationError — несоответствие значения схеме.
+type ValidationError struct {
+	Errors []problem.FieldError
+}
+
+// Error перечисляет ошибки по полям
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fmt.Sprintf("%q %s", fe.Field, fe.Message))
+	}
+	return "schema validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Middleware проверяет тело запросов к методам из описания API.
+// Запросы к неописанным путям и методам без тела передаются дальше
+// без проверки.
+//
+// Возможные ответы:
+//   - 415 Unsupported Media Type: тип содержимого не описан (код unsupported_media_type)
+//   - 400 Bad Request: пустое тело (empty_body), некорректный JSON (invalid_json)
+//     или несоответствие схеме (validation_failed со списком errors по полям)
+//
+// Особенности:
+//   - Запрос без Content-Type считается application/json
+//   - Тело читается целиком и передается обработчику без изменений,
+//     поэтому middleware должно стоять после распаковки gzip
+func (s *Spec) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op, ok := s.Operation(r.Method, r.URL.Path)
+		if !ok || op.RequestBody == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		media, ok := op.RequestBody.mediaType(r.Header.Get("Content-Type"))
+		if !ok {
+			problem.Write(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+				"supported content types: "+strings.Join(op.RequestBody.mediaTypes(), ", "))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			logg.FromContext(r.Context()).Warn("Failed to read request body", zap.Error(err))
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "cannot read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			if op.RequestBody.Required {
+				problem.Write(w, r, http.StatusBadRequest, problem.CodeEmptyBody, "request body is required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		value, err := decodeJSON(body)
+		if err != nil {
+			problem.Write(w, r, http.StatusBadRequest, problem.CodeInvalidJSON, "request body is not valid JSON")
+			return
+		}
+		if errs := s.Validate(media.Schema, value); len(errs) > 0 {
+			logg.FromContext(r.Context()).Debug("Request does not match schema", zap.Int("errors", len(errs)))
+			problem.Invalid(w, r, errs)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ValidateResponse проверяет ответ обработчика по описанию API:
+// код ответа описан, тип содержимого совпадает, тело соответствует схеме.
+//
+// Параметры:
+//   - method, path: запрос, на который дан ответ
+//   - status, header, body: ответ
+//
+// Возвращает:
+//   - error: *ValidationError при несоответствии тела схеме или описание расхождения
+func (s *Spec) ValidateResponse(method, path string, status int, header http.Header, body []byte) error {
+	op, ok := s.Operation(method, path)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	resp, ok := s.response(op, status)
+	if !ok {
+		return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+	}
+
+	if len(resp.Content) == 0 {
+		if len(body) != 0 {
+			return fmt.Errorf("%s %s: status %d must have no body", method, path, status)
+		}
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid Content-Type %q", method, path, header.Get("Content-Type"))
+	}
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s %s: status %d with Content-Type %q is not documented", method, path, status, mediaType)
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("%s %s: response is not valid JSON: %w", method, path, err)
+	}
+	if errs := s.Validate(media.Schema, value); len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// Validate проверяет значение, разобранное decodeJSON, по схеме.
+//
+// Возвращает:
+//   - []problem.FieldError: ошибки с JSON Pointer на поле; nil, если
+//     значение соответствует схеме
+func (s *Spec) Validate(schema *Schema, value any) []problem.FieldError {
+	var errs []problem.FieldError
+	s.validate(schema, value, "", &errs)
+	return errs
+}
+
+// mediaType выбирает схему по заголовку Content-Type запроса
+func (b *RequestBody) mediaType(contentType string) (MediaType, bool) {
+	mediaType := defaultMediaType
+	if contentType != "" {
+		var err error
+		if mediaType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return MediaType{}, false
+		}
+	}
+	m, ok := b.Content[mediaType]
+	return m, ok
+}
+
+// mediaTypes возвращает описанные типы содержимого в постоянном порядке
+func (b *RequestBody) mediaTypes() []string {
+	types := make([]string, 0, len(b.Content))
+	for t := range b.Content {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// decodeJSON разбирает JSON, сохраняя числа как json.Number, чтобы
+// отличать целые числа от дробных
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return v, nil
+}
+
+// validate рекурсивно проверяет значение и добавляет ошибки в errs
+func (s *Spec) validate(schema *Schema, value any, ptr string, errs *[]problem.FieldError) {
+	if schema == nil {
+		return
+	}
+	schema, err := s.resolve(schema)
+	if err != nil {
+		// ссылки проверены в Parse
+		panic(err)
+	}
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, problem.FieldError{Field: ptr, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !matchType(schema.Type, value) {
+		fail("must be %s", typeName(schema.Type))
+		return
+	}
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		fail("must be one of %v", schema.Enum)
+		return
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if schema.MinLength != nil && n < *schema.MinLength {
+			fail("must be at least %d characters long", *schema.MinLength)
+			return
+		}
+		if schema.MaxLength != nil && n > *schema.MaxLength {
+			fail("must be at most %d characters long", *schema.MaxLength)
+			return
+		}
+		if schema.Format == "uri" && !isAbsoluteURI(v) {
+			fail("must be an absolute URI")
+		}
+	case []any:
+		if schema.MinItems != nil && len(v) < *schema.MinItems {
+			fail("must contain at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(v) > *schema.MaxItems {
+			fail("must contain at most %d items", *schema.MaxItems)
+		}
+		for i, item := range v {
+			s.validate(schema.Items, item, ptr+"/"+strconv.Itoa(i), errs)
+		}
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, problem.FieldError{Field: ptr + "/" + escapePointer(name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(schema.Properties))
+		for name := range schema.Properties {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if item, ok := v[name]; ok {
+				s.validate(schema.Properties[name], item, ptr+"/"+escapePointer(name), errs)
+			}
+		}
+	}
+}
+
+// matchType проверяет тип значения; пустой тип допускает любое значение
+func matchType(t string, value any) bool {
+	switch t {
+	case "":
+		return true
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		_, err := n.Int64()
+		return err == nil
+	}
+	return false
+}
+
+// typeName возвращает тип с артиклем для сообщения об ошибке
+func typeName(t string) string {
+	switch t {
+	case "object", "array", "integer":
+		return "an " + t
+	default:
+		return "a " + t
+	}
+}
+
+// isAbsoluteURI проверяет, что строка — абсолютный URI с хостом
+func isAbsoluteURI(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.IsAbs() && u.Host != ""
+}
+
+// escapePointer экранирует имя поля для JSON Pointer (RFC 6901)
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
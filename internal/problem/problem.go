@@ -0,0 +1,127 @@
+// Package problem формирует ответы об ошибках в формате RFC 7807
+// (application/problem+json). Каждый ответ содержит постоянный
+// машиночитаемый код, по которому клиент различает ошибки, не разбирая
+// текст сообщения.
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/requestid"
+	"go.uber.org/zap"
+)
+
+// ContentType — тип содержимого ответа об ошибке
+const ContentType = "application/problem+json"
+
+// TypePrefix — префикс поля type; полный идентификатор типа — префикс и код
+const TypePrefix = "urn:shortener:problem:"
+
+// Коды ошибок. Коды — часть API: их нельзя менять или удалять,
+// можно только добавлять новые.
+const (
+	CodeInvalidRequest       = "invalid_request"        // некорректные параметры запроса
+	CodeInvalidJSON          = "invalid_json"           // тело запроса не разбирается как JSON
+	CodeEmptyBody            = "empty_body"             // пустое тело запроса
+	CodeInvalidURL           = "invalid_url"            // некорректный URL для сокращения
+	CodeURLDenied            = "url_denied"             // адрес запрещен политикой ссылок
+	CodeValidation           = "validation_failed"      // тело запроса не соответствует схеме API
+	CodeUnsupportedMediaType = "unsupported_media_type" // тип содержимого не описан в схеме API
+	CodeUnauthorized         = "unauthorized"           // запрос без аутентификации
+	CodeForbidden            = "forbidden"              // недостаточно прав
+	CodeCSRFFailed           = "csrf_failed"            // запрос не прошел проверку CSRF
+	CodeNotFound             = "not_found"              // ресурс не найден
+	CodeGone                 = "gone"                   // ссылка удалена
+	CodeNotImplemented       = "not_implemented"        // хранилище не поддерживает операцию
+	CodeInternal             = "internal_error"         // внутренняя ошибка сервера
+)
+
+// Problem — тело ответа об ошибке (RFC 7807) с расширениями code, request_id
+// и errors (ошибки по полям, см. Invalid).
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError описывает ошибку в одном поле тела запроса.
+type FieldError struct {
+	Field   string `json:"field"`   // JSON Pointer (RFC 6901) на значение; пусто — тело целиком
+	Message string `json:"message"` // что не так со значением
+}
+
+// New создает описание ошибки для запроса.
+//
+// Параметры:
+//   - r: запрос; из него берутся путь (instance) и X-Request-ID
+//   - status: HTTP-статус ответа
+//   - code: постоянный код ошибки (Code*)
+//   - detail: пояснение для клиента; не должно содержать текст внутренних ошибок
+func New(r *http.Request, status int, code, detail string) Problem {
+	return Problem{
+		Type:      TypePrefix + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestid.FromContext(r.Context()),
+	}
+}
+
+// Write отправляет ответ об ошибке.
+//
+// Пример ответа:
+//
+//	HTTP/1.1 400 Bad Request
+//	Content-Type: application/problem+json
+//
+//	{"type":"urn:shortener:problem:invalid_url","title":"Bad Request","status":400,
+//	 "detail":"url must be an absolute http or https URL","instance":"/api/shorten",
+//	 "code":"invalid_url","request_id":"..."}
+func Write(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	write(w, r, New(r, status, code, detail))
+}
+
+// Invalid отвечает 400 с кодом CodeValidation и списком ошибок по полям.
+//
+// Пример ответа:
+//
+//	{"type":"urn:shortener:problem:validation_failed","title":"Bad Request","status":400,
+//	 "detail":"request body does not match the API schema","instance":"/api/shorten/batch",
+//	 "code":"validation_failed","errors":[{"field":"/0/original_url","message":"is required"}]}
+func Invalid(w http.ResponseWriter, r *http.Request, errs []FieldError) {
+	p := New(r, http.StatusBadRequest, CodeValidation, "request body does not match the API schema")
+	p.Errors = errs
+	write(w, r, p)
+}
+
+// write отправляет описание ошибки
+func write(w http.ResponseWriter, r *http.Request, p Problem) {
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		logg.FromContext(r.Context()).Error("Failed to write response", zap.Error(err))
+	}
+}
+
+// Internal записывает ошибку в лог и отвечает 500 с кодом CodeInternal.
+// Текст ошибки клиенту не передается: он может содержать SQL, пути
+// к файлам и адреса; сопоставить ответ с записью лога можно по request_id.
+//
+// Параметры:
+//   - msg: сообщение для лога
+//   - err: внутренняя ошибка
+//   - fields: дополнительные поля записи лога
+func Internal(w http.ResponseWriter, r *http.Request, msg string, err error, fields ...zap.Field) {
+	logg.FromContext(r.Context()).Error(msg, append(fields, zap.Error(err))...)
+	Write(w, r, http.StatusInternalServerError, CodeInternal, "")
+}
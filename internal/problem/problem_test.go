@@ -0,0 +1,65 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// decode разбирает ответ об ошибке
+func decode(t *testing.T, w *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	return p
+}
+
+func TestWrite(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/api/shorten?x=1", nil)
+	r = r.WithContext(requestid.NewContext(r.Context(), "req-1"))
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Length", "10")
+
+	Write(w, r, http.StatusBadRequest, CodeInvalidJSON, "request body must be a JSON object")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Equal(t, Problem{
+		Type:      "urn:shortener:problem:invalid_json",
+		Title:     "Bad Request",
+		Status:    http.StatusBadRequest,
+		Detail:    "request body must be a JSON object",
+		Instance:  "/api/shorten",
+		Code:      CodeInvalidJSON,
+		RequestID: "req-1",
+	}, decode(t, w))
+}
+
+func TestInternal_HidesError(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+
+	secret := errors.New(`pq: duplicate key value violates unique constraint "links_pkey" on INSERT INTO links`)
+	w := httptest.NewRecorder()
+	Internal(w, httptest.NewRequest(http.MethodGet, "/api/user/urls", nil), "Failed to insert URL", secret, zap.String("id", "abc"))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "INSERT")
+	p := decode(t, w)
+	assert.Equal(t, CodeInternal, p.Code)
+	assert.Empty(t, p.Detail)
+
+	entries := logs.FilterMessage("Failed to insert URL").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, secret.Error(), entries[0].ContextMap()["error"])
+	assert.Equal(t, "abc", entries[0].ContextMap()["id"])
+}
@@ -0,0 +1,74 @@
+// Package recovery перехватывает панику в обработчиках HTTP-запросов:
+// записывает стек в лог и отвечает клиенту 500 в формате
+// application/problem+json вместо обрыва соединения.
+package recovery
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/metrics"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"go.uber.org/zap"
+)
+
+// headerWriter запоминает, отправлены ли заголовки ответа
+type headerWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *headerWriter) WriteHeader(statusCode int) {
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Middleware перехватывает панику в следующих обработчиках.
+//
+// Особенности:
+//   - Стек и значение паники пишутся в лог вместе с X-Request-ID
+//     (requestid.Middleware и log.LoggerMiddleware должны стоять раньше)
+//   - Клиент получает 500 с кодом internal_error и request_id
+//     (см. problem.Write); подробности паники в ответ не попадают
+//   - Если обработчик уже начал ответ, изменить статус нельзя: паника
+//     только записывается в лог
+//   - Увеличивается метрика shortener_http_panics_total
+//   - http.ErrAbortHandler пробрасывается дальше: так обработчик
+//     намеренно обрывает соединение
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hw := &headerWriter{ResponseWriter: w}
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if err, ok := rec.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(rec)
+			}
+
+			logg.FromContext(r.Context()).Error("Recovered from panic",
+				zap.String("panic", fmt.Sprint(rec)),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.ByteString("stack", debug.Stack()),
+			)
+			metrics.Panic(r)
+
+			if hw.wroteHeader {
+				return
+			}
+			problem.Write(w, r, http.StatusInternalServerError, problem.CodeInternal, "")
+		}()
+
+		next.ServeHTTP(hw, r)
+	})
+}
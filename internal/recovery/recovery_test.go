@@ -0,0 +1,105 @@
+package recovery
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/problem"
+	"github.com/GevorkovG/go-shortener-tlp/internal/requestid"
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// router собирает цепочку middleware, как в app.Run
+func router(h http.HandlerFunc) http.Handler {
+	r := chi.NewRouter()
+	r.Use(requestid.Middleware, logg.LoggerMiddleware, Middleware)
+	r.Get("/{id}", h)
+	return r
+}
+
+// observe устанавливает глобальный логгер, запоминающий записи
+func observe(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	t.Cleanup(zap.ReplaceGlobals(zap.New(core)))
+	return logs
+}
+
+func TestMiddleware_RecoversPanic(t *testing.T) {
+	logs := observe(t)
+
+	var link *struct{ Short string }
+	h := router(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(link.Short)) // разыменование nil
+	})
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set(requestid.Header, "req-7")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	var resp problem.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, problem.CodeInternal, resp.Code)
+	assert.Equal(t, http.StatusInternalServerError, resp.Status)
+	assert.Equal(t, "req-7", resp.RequestID)
+	assert.NotContains(t, w.Body.String(), "nil pointer")
+
+	panics := logs.FilterMessage("Recovered from panic").All()
+	require.Len(t, panics, 1)
+	fields := panics[0].ContextMap()
+	assert.Equal(t, "req-7", fields["request_id"])
+	assert.Contains(t, fields["panic"], "nil pointer dereference")
+	assert.Contains(t, fields["stack"], "recovery_test.go")
+
+	// Запрос учтен в логе как ошибка сервера
+	access := logs.FilterMessage("HTTP request").All()
+	require.Len(t, access, 1)
+	assert.EqualValues(t, http.StatusInternalServerError, access[0].ContextMap()["status"])
+}
+
+func TestMiddleware_TypeAssertion(t *testing.T) {
+	observe(t)
+
+	h := router(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.Context().Value(struct{}{}).(string)
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotEmpty(t, w.Header().Get(requestid.Header))
+}
+
+func TestMiddleware_AfterWrite(t *testing.T) {
+	logs := observe(t)
+
+	h := router(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("late failure")
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/abc", nil))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, 1, logs.FilterMessage("Recovered from panic").Len())
+}
+
+func TestMiddleware_AbortHandler(t *testing.T) {
+	observe(t)
+
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
@@ -0,0 +1,67 @@
+// Package requestid присваивает каждому HTTP-запросу идентификатор
+// для сопоставления записей лога, ответа и трассировки одного запроса.
+package requestid
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// Header — заголовок запроса и ответа с идентификатором
+const Header = "X-Request-ID"
+
+// maxLength — максимальная длина принимаемого идентификатора
+const maxLength = 128
+
+// ctxKey — ключ контекста для идентификатора запроса
+type ctxKey struct{}
+
+// NewContext возвращает контекст с идентификатором запроса.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext возвращает идентификатор запроса или пустую строку,
+// если контекст создан не в Middleware.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// Middleware принимает идентификатор из заголовка X-Request-ID или,
+// если заголовка нет, создает новый (UUID). Идентификатор сохраняется
+// в контексте запроса и возвращается клиенту в том же заголовке.
+//
+// Особенности:
+//   - Значение от клиента принимается, только если оно не длиннее
+//     128 символов и состоит из букв, цифр и символов "-_.:"; иначе
+//     создается новый идентификатор, чтобы клиент не мог подделать
+//     записи лога
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// valid проверяет идентификатор, переданный клиентом
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
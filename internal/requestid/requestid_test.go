@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve выполняет запрос с заголовком X-Request-ID (если он не пуст) и возвращает
+// идентификатор из контекста обработчика и из ответа
+func serve(header string) (fromContext, fromResponse string) {
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fromContext = FromContext(r.Context())
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(Header, header)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return fromContext, rec.Header().Get(Header)
+}
+
+func TestMiddleware_AcceptsClientID(t *testing.T) {
+	ctxID, respID := serve("req-42.abc:1")
+	assert.Equal(t, "req-42.abc:1", ctxID)
+	assert.Equal(t, "req-42.abc:1", respID)
+}
+
+func TestMiddleware_GeneratesID(t *testing.T) {
+	for _, header := range []string{"", "bad id\nwith newline", strings.Repeat("a", 129), `{"json":1}`} {
+		ctxID, respID := serve(header)
+		_, err := uuid.Parse(ctxID)
+		require.NoError(t, err, "header %q", header)
+		assert.Equal(t, ctxID, respID)
+	}
+}
+
+func TestFromContext_Empty(t *testing.T) {
+	assert.Empty(t, FromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}
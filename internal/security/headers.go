@@ -0,0 +1,183 @@
+// Package security содержит HTTP-middleware заголовков безопасности
+// и обработчик перенаправления с HTTP на HTTPS.
+package security
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ReferrerPolicies — допустимые значения заголовка Referrer-Policy
+var ReferrerPolicies = []string{
+	"no-referrer",
+	"no-referrer-when-downgrade",
+	"origin",
+	"origin-when-cross-origin",
+	"same-origin",
+	"strict-origin",
+	"strict-origin-when-cross-origin",
+	"unsafe-url",
+}
+
+// HeadersConfig описывает заголовки безопасности.
+type HeadersConfig struct {
+	HSTSMaxAge            int    // max-age HSTS в секундах, 0 — заголовок не отправляется
+	HSTSIncludeSubdomains bool   // директива includeSubDomains
+	HSTSPreload           bool   // директива preload
+	ReferrerPolicy        string // значение Referrer-Policy, пусто — не отправляется
+	ContentSecurityPolicy string // CSP для HTML-ответов, пусто — не отправляется
+}
+
+// Headers middleware добавляет заголовки безопасности ко всем ответам:
+//   - X-Content-Type-Options: nosniff
+//   - Referrer-Policy
+//   - Strict-Transport-Security (только для запросов по TLS)
+//   - Content-Security-Policy (только для ответов с Content-Type text/html)
+type Headers struct {
+	hsts           string
+	referrerPolicy string
+	csp            string
+}
+
+// NewHeaders создает middleware заголовков безопасности.
+//
+// Возвращает:
+//   - *Headers: middleware
+//   - error: ошибка для неизвестной Referrer-Policy или отрицательного max-age
+func NewHeaders(cfg HeadersConfig) (*Headers, error) {
+	if cfg.HSTSMaxAge < 0 {
+		return nil, fmt.Errorf("HSTS max-age must not be negative")
+	}
+	if cfg.ReferrerPolicy != "" && !ValidReferrerPolicy(cfg.ReferrerPolicy) {
+		return nil, fmt.Errorf("unknown Referrer-Policy %q", cfg.ReferrerPolicy)
+	}
+
+	h := &Headers{
+		referrerPolicy: cfg.ReferrerPolicy,
+		csp:            cfg.ContentSecurityPolicy,
+	}
+	if cfg.HSTSMaxAge > 0 {
+		h.hsts = "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			h.hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			h.hsts += "; preload"
+		}
+	}
+	return h, nil
+}
+
+// ValidReferrerPolicy проверяет значение Referrer-Policy; допускается
+// список через запятую (последнее поддерживаемое значение имеет приоритет)
+func ValidReferrerPolicy(p string) bool {
+	for _, v := range strings.Split(p, ",") {
+		v = strings.TrimSpace(v)
+		ok := false
+		for _, known := range ReferrerPolicies {
+			if v == known {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Middleware возвращает обработчик, добавляющий заголовки безопасности.
+func (h *Headers) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if h.referrerPolicy != "" {
+			header.Set("Referrer-Policy", h.referrerPolicy)
+		}
+		// HSTS по незащищенному соединению браузеры игнорируют
+		if h.hsts != "" && r.TLS != nil {
+			header.Set("Strict-Transport-Security", h.hsts)
+		}
+
+		if h.csp == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&cspWriter{ResponseWriter: w, csp: h.csp}, r)
+	})
+}
+
+// cspWriter выставляет Content-Security-Policy перед записью заголовков,
+// когда тип ответа уже известен
+type cspWriter struct {
+	http.ResponseWriter
+	csp         string
+	wroteHeader bool
+}
+
+func (c *cspWriter) WriteHeader(statusCode int) {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		if isHTML(c.Header().Get("Content-Type")) {
+			c.Header().Set("Content-Security-Policy", c.csp)
+		}
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (c *cspWriter) Write(p []byte) (int, error) {
+	if !c.wroteHeader {
+		// Так же, как net/http, определяем тип по содержимому
+		if c.Header().Get("Content-Type") == "" {
+			c.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		c.WriteHeader(http.StatusOK)
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// isHTML сообщает, является ли Content-Type HTML-документом
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/html"
+}
+
+// RedirectToHTTPS возвращает обработчик, перенаправляющий HTTP-запросы
+// на HTTPS с сохранением пути и параметров запроса. Ответ 308 сохраняет
+// метод и тело запроса.
+//
+// Параметры:
+//   - host: хост HTTPS-сервера; пусто — хост из запроса
+//   - port: порт HTTPS-сервера; пусто или "443" — порт не указывается
+func RedirectToHTTPS(host, port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target := host
+		if target == "" {
+			target = r.Host
+			if h, _, err := net.SplitHostPort(r.Host); err == nil {
+				target = h
+			}
+			// SplitHostPort снимает скобки с IPv6; без порта они остаются
+			target = strings.TrimSuffix(strings.TrimPrefix(target, "["), "]")
+		}
+		if target == "" {
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		}
+		if port != "" && port != "443" {
+			target = net.JoinHostPort(target, port)
+		} else if strings.Contains(target, ":") {
+			target = "[" + target + "]"
+		}
+
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = target
+		http.Redirect(w, r, u.String(), http.StatusPermanentRedirect)
+	})
+}
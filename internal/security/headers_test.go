@@ -0,0 +1,98 @@
+package security
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaders_Middleware(t *testing.T) {
+	h, err := NewHeaders(HeadersConfig{
+		HSTSMaxAge:            600,
+		HSTSIncludeSubdomains: true,
+		ReferrerPolicy:        "no-referrer",
+		ContentSecurityPolicy: "default-src 'none'",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		tls      bool
+		wantHSTS string
+		wantCSP  string
+	}{
+		{
+			name: "redirect over TLS",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "https://example.com", http.StatusTemporaryRedirect)
+			},
+			tls:      true,
+			wantHSTS: "max-age=600; includeSubDomains",
+			wantCSP:  "default-src 'none'",
+		},
+		{
+			name: "json over plain HTTP",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+			},
+		},
+		{
+			name: "sniffed html",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("<html><body>hi</body></html>"))
+			},
+			wantCSP: "default-src 'none'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			w := httptest.NewRecorder()
+			h.Middleware(tt.handler).ServeHTTP(w, req)
+
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+			assert.Equal(t, tt.wantHSTS, w.Header().Get("Strict-Transport-Security"))
+			assert.Equal(t, tt.wantCSP, w.Header().Get("Content-Security-Policy"))
+		})
+	}
+
+	_, err = NewHeaders(HeadersConfig{ReferrerPolicy: "everywhere"})
+	assert.Error(t, err)
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		port   string
+		method string
+		target string
+		want   string
+	}{
+		{"request host, custom port", "", "8443", http.MethodGet, "http://sho.rt:8080/abc?x=1", "https://sho.rt:8443/abc?x=1"},
+		{"request host, default port", "", "443", http.MethodGet, "http://sho.rt/abc", "https://sho.rt/abc"},
+		{"fixed host keeps method", "links.example.com", "", http.MethodPost, "http://10.0.0.1/api/shorten", "https://links.example.com/api/shorten"},
+		{"ipv6 request host", "", "", http.MethodGet, "http://[::1]:8080/abc", "https://[::1]/abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(""))
+			w := httptest.NewRecorder()
+			RedirectToHTTPS(tt.host, tt.port).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Location"))
+		})
+	}
+}
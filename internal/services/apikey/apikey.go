@@ -0,0 +1,46 @@
+// Package apikey предоставляет функции для генерации и хеширования
+// долгоживущих API-ключей сервисных клиентов.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix — префикс, с которого начинается каждый выданный API-ключ.
+// Позволяет отличать API-ключ от JWT токена в заголовке Authorization.
+const Prefix = "sk_"
+
+// keyBytes — количество случайных байт в теле ключа.
+const keyBytes = 32
+
+// Generate создает новый API-ключ.
+//
+// Возвращает:
+//   - plain: ключ в открытом виде (показывается пользователю один раз)
+//   - hash: SHA-256 хеш ключа для сохранения в хранилище
+//   - error: ошибка генератора случайных чисел
+func Generate() (plain string, hash string, err error) {
+	buf := make([]byte, keyBytes)
+	if _, err = rand.Read(buf); err != nil {
+		return "", "", err
+	}
+
+	plain = Prefix + base64.RawURLEncoding.EncodeToString(buf)
+	return plain, Hash(plain), nil
+}
+
+// Hash возвращает SHA-256 хеш ключа в шестнадцатеричном виде.
+// В хранилище ключи сохраняются только в виде хеша.
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey проверяет, похожа ли строка на API-ключ (по префиксу).
+func IsAPIKey(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
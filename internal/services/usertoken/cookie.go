@@ -4,7 +4,6 @@ package usertoken
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/GevorkovG/go-shortener-tlp/internal/services/jwtstring"
 	"github.com/golang-jwt/jwt"
@@ -28,9 +27,6 @@ import (
 func GetUserID(tokenString string) (string, error) {
 	claims := &jwtstring.Claims{}
 
-	//DEBUG--------------------------------------------------------------------------------------------------
-	log.Printf("internal/services/usertoken/cookie.go ValidationToken tokenString %s ", tokenString)
-
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
@@ -59,13 +55,10 @@ func GetUserID(tokenString string) (string, error) {
 // Особенности:
 //   - Проверяет метод подписи (должен быть HMAC)
 //   - Проверяет срок действия токена
-//   - Логирует процесс проверки (в debug режиме)
+//   - Токен не логируется
 func ValidationToken(tokenString string) bool {
 	claims := &jwtstring.Claims{}
 
-	//DEBUG--------------------------------------------------------------------------------------------------
-	log.Printf("internal/services/usertoken/cookie.go ValidationToken tokenString %s ", tokenString)
-
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
@@ -4,12 +4,16 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
-	"log"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/GevorkovG/go-shortener-tlp/internal/database"
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/google/uuid"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
@@ -18,6 +22,17 @@ import (
 // ErrConflict возвращается при попытке вставить дубликат URL
 var ErrConflict = errors.New("conflict on inserting new record")
 
+// ErrAPIKeyNotFound возвращается, если API-ключ не найден, отозван
+// или принадлежит другому пользователю
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// ErrLinkNotFound возвращается, если ссылка с указанным коротким URL не найдена
+var ErrLinkNotFound = errors.New("link not found")
+
+// ErrWebhookNotFound возвращается, если подписка на вебхуки не найдена
+// или принадлежит другому пользователю
+var ErrWebhookNotFound = errors.New("webhook not found")
+
 // Link реализует интерфейс Storage для работы с PostgreSQL
 type Link struct {
 	Store *database.DBStore // Подключение к базе данных
@@ -46,13 +61,96 @@ func IsDeleted(link *objects.Link) bool {
 // Возвращает:
 //   - error: ошибка при создании таблицы
 func (l *Link) CreateTable(ctx context.Context) error {
-	if _, err := l.Store.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS links (id SERIAL PRIMARY KEY, short CHAR(20) UNIQUE, original CHAR(255) UNIQUE, userid CHAR(36), is_deleted BOOLEAN DEFAULT FALSE);"); err != nil {
-		zap.L().Error("Failed to create table", zap.Error(err))
+	if _, err := l.Store.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS links (id SERIAL PRIMARY KEY, short CHAR(20) UNIQUE, original TEXT, userid CHAR(36), is_deleted BOOLEAN DEFAULT FALSE, is_disabled BOOLEAN DEFAULT FALSE);"); err != nil {
+		logg.FromContext(ctx).Error("Failed to create table", zap.Error(err))
+		return err
+	}
+	if _, err := l.Store.DB.ExecContext(ctx, "ALTER TABLE links ADD COLUMN IF NOT EXISTS is_disabled BOOLEAN DEFAULT FALSE, ADD COLUMN IF NOT EXISTS first_clicked_at TIMESTAMPTZ;"); err != nil {
+		logg.FromContext(ctx).Error("Failed to migrate table", zap.Error(err))
+		return err
+	}
+	if err := l.migrateOriginalColumn(ctx); err != nil {
+		logg.FromContext(ctx).Error("Failed to migrate original column", zap.Error(err))
 		return err
 	}
 	return nil
 }
 
+// migrateOriginalColumn переводит столбец original из CHAR(255) в TEXT,
+// чтобы в нем помещались URL длиной до url_max_length. Уникальность
+// обеспечивается индексом по md5(original): индекс btree по самому значению
+// не принимает строки длиннее трети страницы (около 2,7 КБ).
+// Повторный вызов ничего не меняет.
+func (l *Link) migrateOriginalColumn(ctx context.Context) error {
+	var dataType string
+	err := l.Store.DB.QueryRowContext(ctx,
+		"SELECT data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'links' AND column_name = 'original'",
+	).Scan(&dataType)
+	if err != nil {
+		return err
+	}
+	if dataType != "text" {
+		// Значения CHAR дополнены пробелами до длины столбца
+		if _, err := l.Store.DB.ExecContext(ctx, "ALTER TABLE links ALTER COLUMN original TYPE TEXT USING RTRIM(original)"); err != nil {
+			return err
+		}
+	}
+	if _, err := l.Store.DB.ExecContext(ctx, "CREATE UNIQUE INDEX IF NOT EXISTS links_original_md5_key ON links (md5(original))"); err != nil {
+		return err
+	}
+	_, err = l.Store.DB.ExecContext(ctx, "ALTER TABLE links DROP CONSTRAINT IF EXISTS links_original_key")
+	return err
+}
+
+// Migrate создает все таблицы хранилища и добавляет недостающие столбцы.
+// Вызывается один раз при старте приложения.
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//
+// Возвращает:
+//   - error: первая ошибка при создании таблиц
+func (l *Link) Migrate(ctx context.Context) error {
+	if err := l.CreateTable(ctx); err != nil {
+		return err
+	}
+	if err := l.CreateAPIKeysTable(ctx); err != nil {
+		return err
+	}
+	if err := l.CreateRevokedTokensTable(ctx); err != nil {
+		return err
+	}
+	return l.CreateWebhooksTables(ctx)
+}
+
+// migrationTables — таблицы, которые создает Migrate
+var migrationTables = []string{"links", "api_keys", "revoked_tokens", "webhooks", "webhook_deliveries"}
+
+// CheckMigrations проверяет, что все таблицы, создаваемые Migrate,
+// существуют. Используется проверкой готовности (/readyz).
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//
+// Возвращает:
+//   - error: ошибка запроса или список отсутствующих таблиц
+func (l *Link) CheckMigrations(ctx context.Context) error {
+	var missing []string
+	for _, table := range migrationTables {
+		var exists bool
+		if err := l.Store.DB.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // Insert добавляет новую ссылку в хранилище
 //
 // Параметры:
@@ -68,14 +166,10 @@ func (l *Link) CreateTable(ctx context.Context) error {
 //   - Конфликты при вставке
 //   - Успешное завершение операции
 func (l *Link) Insert(ctx context.Context, link *objects.Link) error {
-	zap.L().Info("DB Inserting URL",
+	logg.FromContext(ctx).Info("DB Inserting URL",
 		zap.String("short", link.Short),
-		zap.String("original", link.Original),
-		zap.String("userID", link.UserID))
-
-	if err := l.CreateTable(ctx); err != nil {
-		return err
-	}
+		logg.URL("original", link.Original),
+		logg.UserID(link.UserID))
 
 	if _, err := l.Store.DB.ExecContext(ctx,
 		"INSERT INTO links (short, original, userid) VALUES ($1, $2, $3)",
@@ -83,20 +177,20 @@ func (l *Link) Insert(ctx context.Context, link *objects.Link) error {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == pgerrcode.UniqueViolation {
-				zap.L().Warn("Conflict on inserting new record",
+				logg.FromContext(ctx).Warn("Conflict on inserting new record",
 					zap.String("short", link.Short),
-					zap.String("original", link.Original))
+					logg.URL("original", link.Original))
 				return ErrConflict
 			}
 		}
-		zap.L().Error("Failed to insert link", zap.Error(err))
+		logg.FromContext(ctx).Error("Failed to insert link", zap.Error(err))
 		return err
 	}
 
-	zap.L().Info("DB URL inserted successfully",
+	logg.FromContext(ctx).Info("DB URL inserted successfully",
 		zap.String("short", link.Short),
-		zap.String("original", link.Original),
-		zap.String("userID", link.UserID))
+		logg.URL("original", link.Original),
+		logg.UserID(link.UserID))
 	return nil
 }
 
@@ -113,33 +207,23 @@ func (l *Link) Insert(ctx context.Context, link *objects.Link) error {
 //   - Использует транзакцию для атомарности
 //   - Прерывается при первой же ошибке
 func (l *Link) InsertLinks(ctx context.Context, links []*objects.Link) error {
-	if err := l.CreateTable(ctx); err != nil {
-		return err
-	}
-
-	tx, err := l.Store.DB.Begin()
+	tx, err := l.Store.DB.BeginTx(ctx, nil)
 	if err != nil {
-		zap.L().Error("Failed to begin transaction", zap.Error(err))
+		logg.FromContext(ctx).Error("Failed to begin transaction", zap.Error(err))
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare("INSERT INTO links (short, original, userid) VALUES ($1, $2, $3)")
-	if err != nil {
-		zap.L().Error("Failed to prepare statement", zap.Error(err))
-		return err
-	}
-	defer stmt.Close()
-
+	// Драйвер pgx сам подготавливает и кэширует повторяющиеся запросы
 	for _, link := range links {
-		if _, err := stmt.Exec(link.Short, link.Original, link.UserID); err != nil {
-			zap.L().Error("Failed to insert link", zap.String("short", link.Short), zap.String("original", link.Original), zap.Error(err))
+		if _, err := tx.ExecContext(ctx, "INSERT INTO links (short, original, userid) VALUES ($1, $2, $3)", link.Short, link.Original, link.UserID); err != nil {
+			logg.FromContext(ctx).Error("Failed to insert link", zap.String("short", link.Short), logg.URL("original", link.Original), zap.Error(err))
 			return err
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		zap.L().Error("Failed to commit transaction", zap.Error(err))
+		logg.FromContext(ctx).Error("Failed to commit transaction", zap.Error(err))
 		return err
 	}
 
@@ -149,6 +233,7 @@ func (l *Link) InsertLinks(ctx context.Context, links []*objects.Link) error {
 // GetOriginal возвращает оригинальный URL по его сокращенной версии
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - short: сокращенный URL
 //
 // Возвращает:
@@ -157,22 +242,23 @@ func (l *Link) InsertLinks(ctx context.Context, links []*objects.Link) error {
 //
 // Логирует:
 //   - Ошибки при выполнении запроса
-func (l *Link) GetOriginal(short string) (*objects.Link, error) {
+func (l *Link) GetOriginal(ctx context.Context, short string) (*objects.Link, error) {
 	link := &objects.Link{Short: short}
 
 	var (
-		original  string
-		userID    string
-		isDeleted bool
+		original   string
+		userID     string
+		isDeleted  bool
+		isDisabled bool
 	)
 
-	err := l.Store.DB.QueryRow(
-		"SELECT TRIM(original), TRIM(userid), is_deleted FROM links WHERE short = $1",
+	err := l.Store.DB.QueryRowContext(ctx,
+		"SELECT TRIM(original), TRIM(userid), is_deleted, COALESCE(is_disabled, FALSE) FROM links WHERE short = $1",
 		strings.TrimSpace(short),
-	).Scan(&original, &userID, &isDeleted)
+	).Scan(&original, &userID, &isDeleted, &isDisabled)
 
 	if err != nil {
-		zap.L().Error("Failed to get original URL",
+		logg.FromContext(ctx).Error("Failed to get original URL",
 			zap.String("short", short),
 			zap.Error(err))
 		return nil, err
@@ -181,6 +267,7 @@ func (l *Link) GetOriginal(short string) (*objects.Link, error) {
 	link.Original = original
 	link.UserID = userID
 	link.DeletedFlag = isDeleted
+	link.Disabled = isDisabled
 
 	return link, nil
 }
@@ -188,6 +275,7 @@ func (l *Link) GetOriginal(short string) (*objects.Link, error) {
 // GetShort возвращает сокращенный URL по оригинальному
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - original: оригинальный URL
 //
 // Возвращает:
@@ -196,7 +284,7 @@ func (l *Link) GetOriginal(short string) (*objects.Link, error) {
 //
 // Логирует:
 //   - Ошибки при выполнении запроса
-func (l *Link) GetShort(original string) (*objects.Link, error) {
+func (l *Link) GetShort(ctx context.Context, original string) (*objects.Link, error) {
 	link := &objects.Link{Original: original}
 
 	var (
@@ -204,14 +292,14 @@ func (l *Link) GetShort(original string) (*objects.Link, error) {
 		userID string
 	)
 
-	err := l.Store.DB.QueryRow(
-		"SELECT TRIM(short), TRIM(userid) FROM links WHERE original = $1",
+	err := l.Store.DB.QueryRowContext(ctx,
+		"SELECT TRIM(short), TRIM(userid) FROM links WHERE md5(original) = md5($1) AND original = $1",
 		strings.TrimSpace(original),
 	).Scan(&short, &userID)
 
 	if err != nil {
-		zap.L().Error("Failed to get short URL",
-			zap.String("original", original),
+		logg.FromContext(ctx).Error("Failed to get short URL",
+			logg.URL("original", original),
 			zap.Error(err))
 		return nil, err
 	}
@@ -225,6 +313,7 @@ func (l *Link) GetShort(original string) (*objects.Link, error) {
 // GetAllByUserID возвращает все ссылки принадлежащие пользователю
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - userID: идентификатор пользователя
 //
 // Возвращает:
@@ -234,15 +323,15 @@ func (l *Link) GetShort(original string) (*objects.Link, error) {
 // Логирует:
 //   - Начало и завершение операции
 //   - Ошибки при выполнении запроса
-func (l *Link) GetAllByUserID(userID string) ([]objects.Link, error) {
-	zap.L().Info("Getting URLs for user", zap.String("userID", userID))
+func (l *Link) GetAllByUserID(ctx context.Context, userID string) ([]objects.Link, error) {
+	logg.FromContext(ctx).Info("Getting URLs for user", logg.UserID(userID))
 	var links []objects.Link
 
-	zap.L().Info("Querying user URLs from database", zap.String("userID", userID))
+	logg.FromContext(ctx).Info("Querying user URLs from database", logg.UserID(userID))
 
-	rows, err := l.Store.DB.Query("SELECT original, short FROM links WHERE userid = $1", userID)
+	rows, err := l.Store.DB.QueryContext(ctx, "SELECT original, short FROM links WHERE userid = $1", userID)
 	if err != nil {
-		zap.L().Error("Failed to query user URLs", zap.String("userID", userID), zap.Error(err))
+		logg.FromContext(ctx).Error("Failed to query user URLs", logg.UserID(userID), zap.Error(err))
 		return nil, err
 	}
 	defer rows.Close()
@@ -250,17 +339,17 @@ func (l *Link) GetAllByUserID(userID string) ([]objects.Link, error) {
 	for rows.Next() {
 		var link objects.Link
 		if err := rows.Scan(&link.Original, &link.Short); err != nil {
-			zap.L().Error("Failed to scan row", zap.Error(err))
+			logg.FromContext(ctx).Error("Failed to scan row", zap.Error(err))
 			return nil, err
 		}
 		if err := rows.Err(); err != nil {
-			zap.L().Error("Error after iterating rows", zap.String("userID", userID), zap.Error(err))
+			logg.FromContext(ctx).Error("Error after iterating rows", logg.UserID(userID), zap.Error(err))
 			return nil, err
 		}
 		links = append(links, link)
 	}
 
-	zap.L().Info("User URLs retrieved from database", zap.String("userID", userID), zap.Any("links", links))
+	logg.FromContext(ctx).Info("User URLs retrieved from database", logg.UserID(userID), logg.Links("links", links))
 
 	return links, nil
 }
@@ -268,54 +357,555 @@ func (l *Link) GetAllByUserID(userID string) ([]objects.Link, error) {
 // MarkAsDeleted помечает ссылку как удаленную
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - userID: идентификатор пользователя
 //   - short: сокращенный URL для удаления
 //
 // Возвращает:
-//   - error: ошибка при выполнении операции
+//   - error: ErrLinkNotFound, если ссылка не найдена или принадлежит
+//     другому пользователю; ошибка базы данных
 //
 // Особенности:
 //   - Проверяет принадлежность ссылки пользователю
 //   - Использует транзакцию
 //   - Логирует успешное выполнение
-func (l *Link) MarkAsDeleted(userID string, short string) error {
-	tx, err := l.Store.DB.Begin()
+func (l *Link) MarkAsDeleted(ctx context.Context, userID string, short string) error {
+	tx, err := l.Store.DB.BeginTx(ctx, nil)
 	if err != nil {
-		zap.L().Error("Failed to begin transaction", zap.Error(err))
-		log.Printf("Failed to begin transaction")
+		logg.FromContext(ctx).Error("Failed to begin transaction", zap.Error(err))
 		return err
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.Prepare("UPDATE links SET is_deleted = TRUE WHERE short = $1 AND userid = $2")
+	res, err := tx.ExecContext(ctx, "UPDATE links SET is_deleted = TRUE WHERE short = $1 AND userid = $2", short, userID)
 	if err != nil {
-		log.Printf("Failed to prepare statement")
-		zap.L().Error("Failed to prepare statement", zap.Error(err))
+		logg.FromContext(ctx).Error("Failed to mark URL as deleted", zap.String("short", short), logg.UserID(userID), zap.Error(err))
 		return err
 	}
-	defer stmt.Close()
-
-	if _, err := stmt.Exec(short, userID); err != nil {
-		log.Printf("Failed to mark URL as deleted---Short:%s    userID:%s   error: %s", short, userID, err)
-		zap.L().Error("Failed to mark URL as deleted", zap.String("short", short), zap.String("userID", userID), zap.Error(err))
+	n, err := res.RowsAffected()
+	if err != nil {
 		return err
 	}
+	if n == 0 {
+		return ErrLinkNotFound
+	}
 
 	if err := tx.Commit(); err != nil {
-		log.Printf("Successfully deleted---Short:%s    userID:%s", short, userID)
-		zap.L().Error("Failed to commit transaction", zap.Error(err))
+		logg.FromContext(ctx).Error("Failed to commit transaction", zap.Error(err))
+		return err
+	}
+	logg.FromContext(ctx).Info("Successfully marked URL as deleted", zap.String("short", short), logg.UserID(userID))
+	return nil
+}
+
+// CreateRevokedTokensTable создает таблицу revoked_tokens если она не существует
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//
+// Возвращает:
+//   - error: ошибка при создании таблицы
+func (l *Link) CreateRevokedTokensTable(ctx context.Context) error {
+	if _, err := l.Store.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS revoked_tokens (jti VARCHAR(36) PRIMARY KEY, expires_at TIMESTAMPTZ NOT NULL);"); err != nil {
+		logg.FromContext(ctx).Error("Failed to create revoked_tokens table", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RevokeToken добавляет токен в список отозванных
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - jti: уникальный идентификатор токена
+//   - expiresAt: срок действия токена
+//
+// Возвращает:
+//   - error: ошибка базы данных
+//
+// Особенности:
+//   - Попутно удаляет записи об истекших токенах
+//   - Таблицу revoked_tokens создает Migrate при старте
+func (l *Link) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if _, err := l.Store.DB.ExecContext(ctx, "DELETE FROM revoked_tokens WHERE expires_at <= now()"); err != nil {
+		logg.FromContext(ctx).Warn("Failed to purge revoked tokens", zap.Error(err))
+	}
+
+	if _, err := l.Store.DB.ExecContext(ctx,
+		"INSERT INTO revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING",
+		jti, expiresAt); err != nil {
+		logg.FromContext(ctx).Error("Failed to revoke token", zap.Error(err))
 		return err
 	}
-	log.Printf("Successfully deleted---Short:%s    userID:%s", short, userID)
-	zap.L().Info("Successfully marked URL as deleted", zap.String("short", short), zap.String("userID", userID))
 	return nil
 }
 
+// IsTokenRevoked проверяет, отозван ли токен
+//
+// Возвращает:
+//   - bool: true если токен отозван и его срок действия еще не истек
+//   - error: ошибка базы данных
+func (l *Link) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+	err := l.Store.DB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1 AND expires_at > now())",
+		jti).Scan(&revoked)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to check revoked token", zap.Error(err))
+		return false, err
+	}
+	return revoked, nil
+}
+
+// ListLinks возвращает ссылки всех пользователей, подходящие под фильтр
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - filter: условия выборки
+//
+// Возвращает:
+//   - []objects.Link: ссылки, упорядоченные по короткому URL
+//   - error: ошибка при запросе
+func (l *Link) ListLinks(ctx context.Context, filter objects.LinkFilter) ([]objects.Link, error) {
+	query := "SELECT TRIM(short), TRIM(original), TRIM(COALESCE(userid, '')), is_deleted, COALESCE(is_disabled, FALSE) FROM links WHERE ($1 = '' OR userid = $1) AND ($2 = '' OR short ILIKE '%' || $2 || '%' ESCAPE '\\' OR original ILIKE '%' || $2 || '%' ESCAPE '\\') ORDER BY short OFFSET $3"
+	args := []interface{}{filter.UserID, escapeLike(filter.Query), filter.Offset}
+	if filter.Limit > 0 {
+		query += " LIMIT $4"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := l.Store.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to list links", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []objects.Link
+	for rows.Next() {
+		var link objects.Link
+		if err := rows.Scan(&link.Short, &link.Original, &link.UserID, &link.DeletedFlag, &link.Disabled); err != nil {
+			logg.FromContext(ctx).Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// likeEscaper экранирует спецсимволы шаблона LIKE
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike экранирует %, _ и \ в подстроке поиска, чтобы ILIKE
+// сравнивал их буквально, как поиск в остальных хранилищах
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// SetLinkDisabled отключает ссылку или восстанавливает отключенную
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - short: сокращенный URL
+//   - disabled: true — отключить, false — восстановить
+//
+// Возвращает:
+//   - error: ErrLinkNotFound если ссылка не существует
+func (l *Link) SetLinkDisabled(ctx context.Context, short string, disabled bool) error {
+	res, err := l.Store.DB.ExecContext(ctx,
+		"UPDATE links SET is_disabled = $1 WHERE short = $2", disabled, strings.TrimSpace(short))
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to update link", zap.String("short", short), zap.Error(err))
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
+
+// ListUsers возвращает пользователей и количество их ссылок
+//
+// Возвращает:
+//   - []objects.UserStat: пользователи, упорядоченные по убыванию числа ссылок
+//   - error: ошибка при запросе
+func (l *Link) ListUsers(ctx context.Context) ([]objects.UserStat, error) {
+	rows, err := l.Store.DB.QueryContext(ctx,
+		"SELECT TRIM(COALESCE(userid, '')), COUNT(*) FROM links GROUP BY 1 ORDER BY 2 DESC, 1")
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to list users", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []objects.UserStat
+	for rows.Next() {
+		var st objects.UserStat
+		if err := rows.Scan(&st.UserID, &st.Links); err != nil {
+			logg.FromContext(ctx).Error("Failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// TransferLinks переносит все ссылки одного пользователя другому
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - fromUserID: прежний владелец ссылок
+//   - toUserID: новый владелец ссылок
+//
+// Возвращает:
+//   - int: количество перенесенных ссылок
+//   - error: ошибка при выполнении запроса
+func (l *Link) TransferLinks(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	res, err := l.Store.DB.ExecContext(ctx,
+		"UPDATE links SET userid = $1 WHERE userid = $2", toUserID, fromUserID)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to transfer links", zap.Error(err))
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
 // Ping проверяет соединение с базой данных
 //
 // Возвращает:
 //   - error: ошибка если соединение недоступно
-func (l *Link) Ping() error {
+func (l *Link) Ping(ctx context.Context) error {
+	return l.Store.DB.PingContext(ctx)
+}
+
+// CheckRoundTrip записывает пробную ссылку и читает ее обратно в транзакции,
+// которая затем откатывается. Используется проверкой готовности (/readyz).
+//
+// Возвращает:
+//   - error: ошибка базы данных или несовпадение прочитанных данных
+func (l *Link) CheckRoundTrip(ctx context.Context) error {
+	tx, err := l.Store.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	probe := uuid.NewString()
+	short := "hc_" + strings.ReplaceAll(probe, "-", "")[:16]
+	if _, err := tx.ExecContext(ctx, "INSERT INTO links (short, original, userid) VALUES ($1, $2, '')", short, probe); err != nil {
+		return err
+	}
+	var original string
+	if err := tx.QueryRowContext(ctx, "SELECT original FROM links WHERE short = $1", short).Scan(&original); err != nil {
+		return err
+	}
+	if original != probe {
+		return errors.New("storage probe read back different data")
+	}
+	return nil
+}
+
+// CreateAPIKeysTable создает таблицу api_keys если она не существует
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//
+// Возвращает:
+//   - error: ошибка при создании таблицы
+func (l *Link) CreateAPIKeysTable(ctx context.Context) error {
+	if _, err := l.Store.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS api_keys (id VARCHAR(36) PRIMARY KEY, userid VARCHAR(36) NOT NULL, name TEXT NOT NULL DEFAULT '', hash CHAR(64) UNIQUE NOT NULL, created_at TIMESTAMPTZ NOT NULL DEFAULT now(), revoked BOOLEAN NOT NULL DEFAULT FALSE, role VARCHAR(16) NOT NULL DEFAULT '');"); err != nil {
+		logg.FromContext(ctx).Error("Failed to create api_keys table", zap.Error(err))
+		return err
+	}
+	if _, err := l.Store.DB.ExecContext(ctx, "ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT '';"); err != nil {
+		logg.FromContext(ctx).Error("Failed to migrate api_keys table", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// InsertAPIKey сохраняет новый API-ключ
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - key: ключ для сохранения (хранится только хеш)
+//
+// Возвращает:
+//   - ErrConflict: если ключ с таким ID или хешем уже существует
+//   - error: другие ошибки базы данных
+func (l *Link) InsertAPIKey(ctx context.Context, key *objects.APIKey) error {
+	if _, err := l.Store.DB.ExecContext(ctx,
+		"INSERT INTO api_keys (id, userid, name, hash, created_at, role) VALUES ($1, $2, $3, $4, $5, $6)",
+		key.ID, key.UserID, key.Name, key.Hash, key.CreatedAt, key.Role); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrConflict
+		}
+		logg.FromContext(ctx).Error("Failed to insert API key", zap.String("id", key.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetAPIKeyByHash возвращает действующий API-ключ по его хешу
+//
+// Возвращает:
+//   - *objects.APIKey: найденный ключ
+//   - error: ErrAPIKeyNotFound если ключ не найден или отозван
+func (l *Link) GetAPIKeyByHash(ctx context.Context, hash string) (*objects.APIKey, error) {
+	key := &objects.APIKey{Hash: hash}
+	err := l.Store.DB.QueryRowContext(ctx,
+		"SELECT id, userid, name, created_at, role FROM api_keys WHERE hash = $1 AND NOT revoked",
+		hash,
+	).Scan(&key.ID, &key.UserID, &key.Name, &key.CreatedAt, &key.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to get API key", zap.Error(err))
+		return nil, err
+	}
+	return key, nil
+}
+
+// GetAPIKeysByUserID возвращает все API-ключи пользователя, включая отозванные
+//
+// Возвращает:
+//   - []objects.APIKey: ключи пользователя, отсортированные по времени создания
+//   - error: ошибка при запросе
+func (l *Link) GetAPIKeysByUserID(ctx context.Context, userID string) ([]objects.APIKey, error) {
+	rows, err := l.Store.DB.QueryContext(ctx,
+		"SELECT id, userid, name, hash, created_at, revoked, role FROM api_keys WHERE userid = $1 ORDER BY created_at",
+		userID)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to query API keys", logg.UserID(userID), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []objects.APIKey
+	for rows.Next() {
+		var k objects.APIKey
+		if err := rows.Scan(&k.ID, &k.UserID, &k.Name, &k.Hash, &k.CreatedAt, &k.Revoked, &k.Role); err != nil {
+			logg.FromContext(ctx).Error("Failed to scan API key", zap.Error(err))
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey отзывает API-ключ пользователя
+//
+// Возвращает:
+//   - error: ErrAPIKeyNotFound если ключ не найден или принадлежит другому пользователю
+func (l *Link) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	res, err := l.Store.DB.ExecContext(ctx,
+		"UPDATE api_keys SET revoked = TRUE WHERE id = $1 AND userid = $2", id, userID)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to revoke API key", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// CreateWebhooksTables создает таблицы webhooks и webhook_deliveries если они не существуют
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//
+// Возвращает:
+//   - error: ошибка при создании таблиц
+func (l *Link) CreateWebhooksTables(ctx context.Context) error {
+	if _, err := l.Store.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS webhooks (id VARCHAR(36) PRIMARY KEY, userid VARCHAR(36) NOT NULL, url TEXT NOT NULL, secret TEXT NOT NULL, events TEXT NOT NULL DEFAULT '', created_at TIMESTAMPTZ NOT NULL DEFAULT now());"); err != nil {
+		logg.FromContext(ctx).Error("Failed to create webhooks table", zap.Error(err))
+		return err
+	}
+	if _, err := l.Store.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS webhook_deliveries (id VARCHAR(36) PRIMARY KEY, webhook_id VARCHAR(36) NOT NULL, userid VARCHAR(36) NOT NULL, event VARCHAR(64) NOT NULL, payload TEXT NOT NULL, status VARCHAR(16) NOT NULL, attempts INTEGER NOT NULL DEFAULT 0, response_code INTEGER NOT NULL DEFAULT 0, last_error TEXT NOT NULL DEFAULT '', created_at TIMESTAMPTZ NOT NULL DEFAULT now(), updated_at TIMESTAMPTZ NOT NULL DEFAULT now());"); err != nil {
+		logg.FromContext(ctx).Error("Failed to create webhook_deliveries table", zap.Error(err))
+		return err
+	}
+	if _, err := l.Store.DB.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, created_at);"); err != nil {
+		logg.FromContext(ctx).Error("Failed to create webhook_deliveries index", zap.Error(err))
+		return err
+	}
+	return nil
+}
 
-	return l.Store.PingDB()
+// InsertWebhook сохраняет новую подписку на вебхуки
+//
+// Возвращает:
+//   - ErrConflict: если подписка с таким ID уже существует
+//   - error: другие ошибки базы данных
+func (l *Link) InsertWebhook(ctx context.Context, hook *objects.Webhook) error {
+	if _, err := l.Store.DB.ExecContext(ctx,
+		"INSERT INTO webhooks (id, userid, url, secret, events, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		hook.ID, hook.UserID, hook.URL, hook.Secret, strings.Join(hook.Events, ","), hook.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrConflict
+		}
+		logg.FromContext(ctx).Error("Failed to insert webhook", zap.String("id", hook.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetWebhooksByUserID возвращает подписки пользователя
+//
+// Возвращает:
+//   - []objects.Webhook: подписки, отсортированные по времени создания
+//   - error: ошибка при запросе
+func (l *Link) GetWebhooksByUserID(ctx context.Context, userID string) ([]objects.Webhook, error) {
+	rows, err := l.Store.DB.QueryContext(ctx,
+		"SELECT id, userid, url, secret, events, created_at FROM webhooks WHERE userid = $1 ORDER BY created_at",
+		userID)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to query webhooks", logg.UserID(userID), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hooks []objects.Webhook
+	for rows.Next() {
+		var (
+			h      objects.Webhook
+			events string
+		)
+		if err := rows.Scan(&h.ID, &h.UserID, &h.URL, &h.Secret, &events, &h.CreatedAt); err != nil {
+			logg.FromContext(ctx).Error("Failed to scan webhook", zap.Error(err))
+			return nil, err
+		}
+		if events != "" {
+			h.Events = strings.Split(events, ",")
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks, rows.Err()
+}
+
+// DeleteWebhook удаляет подписку пользователя вместе с историей доставок
+//
+// Возвращает:
+//   - error: ErrWebhookNotFound если подписка не найдена или принадлежит другому пользователю
+func (l *Link) DeleteWebhook(ctx context.Context, userID string, id string) error {
+	tx, err := l.Store.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND userid = $2", id, userID)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to delete webhook", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrWebhookNotFound
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = $1", id); err != nil {
+		logg.FromContext(ctx).Error("Failed to delete webhook deliveries", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveDelivery сохраняет доставку вебхука или обновляет существующую с тем же ID
+//
+// Возвращает:
+//   - error: ошибка базы данных
+//
+// Особенности:
+//   - После добавления новой доставки удаляет записи подписки сверх
+//     objects.MaxDeliveriesPerWebhook последних; ошибка очистки только логируется
+func (l *Link) SaveDelivery(ctx context.Context, d *objects.WebhookDelivery) error {
+	// xmax = 0 только у строки, вставленной этим запросом, а не обновленной
+	var inserted bool
+	if err := l.Store.DB.QueryRowContext(ctx,
+		`INSERT INTO webhook_deliveries (id, webhook_id, userid, event, payload, status, attempts, response_code, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (id) DO UPDATE SET status = EXCLUDED.status, attempts = EXCLUDED.attempts,
+			response_code = EXCLUDED.response_code, last_error = EXCLUDED.last_error, updated_at = EXCLUDED.updated_at
+		RETURNING (xmax = 0)`,
+		d.ID, d.WebhookID, d.UserID, d.Event, d.Payload, d.Status, d.Attempts, d.ResponseCode, d.LastError, d.CreatedAt, d.UpdatedAt).Scan(&inserted); err != nil {
+		logg.FromContext(ctx).Error("Failed to save webhook delivery", zap.String("id", d.ID), zap.Error(err))
+		return err
+	}
+
+	if inserted {
+		if _, err := l.Store.DB.ExecContext(ctx,
+			`DELETE FROM webhook_deliveries WHERE webhook_id = $1 AND id NOT IN
+			(SELECT id FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC, id LIMIT $2)`,
+			d.WebhookID, objects.MaxDeliveriesPerWebhook); err != nil {
+			logg.FromContext(ctx).Warn("Failed to trim webhook delivery history", zap.String("webhookID", d.WebhookID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// GetDeliveries возвращает историю доставок по подписке пользователя
+//
+// Возвращает:
+//   - []objects.WebhookDelivery: доставки, начиная с самых новых
+//   - error: ErrWebhookNotFound если подписка не найдена или принадлежит другому пользователю
+func (l *Link) GetDeliveries(ctx context.Context, userID string, webhookID string) ([]objects.WebhookDelivery, error) {
+	var exists bool
+	if err := l.Store.DB.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND userid = $2)",
+		webhookID, userID).Scan(&exists); err != nil {
+		logg.FromContext(ctx).Error("Failed to check webhook", zap.String("id", webhookID), zap.Error(err))
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	rows, err := l.Store.DB.QueryContext(ctx,
+		"SELECT id, webhook_id, userid, event, payload, status, attempts, response_code, last_error, created_at, updated_at FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY created_at DESC, id",
+		webhookID)
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to query webhook deliveries", zap.String("id", webhookID), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []objects.WebhookDelivery
+	for rows.Next() {
+		var d objects.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.UserID, &d.Event, &d.Payload, &d.Status,
+			&d.Attempts, &d.ResponseCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			logg.FromContext(ctx).Error("Failed to scan webhook delivery", zap.Error(err))
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// MarkFirstClick отмечает переход по ссылке
+//
+// Возвращает:
+//   - bool: true если это первый переход по ссылке
+//   - error: ошибка базы данных
+func (l *Link) MarkFirstClick(ctx context.Context, short string) (bool, error) {
+	res, err := l.Store.DB.ExecContext(ctx,
+		"UPDATE links SET first_clicked_at = now() WHERE short = $1 AND first_clicked_at IS NULL",
+		strings.TrimSpace(short))
+	if err != nil {
+		logg.FromContext(ctx).Error("Failed to mark click", zap.String("short", short), zap.Error(err))
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -55,6 +56,7 @@ func (s *LinkStorageTestSuite) SetupSuite() {
 	// Инициализация хранилища
 	dbStore := &database.DBStore{DB: s.db}
 	s.storage = NewLinkStorage(dbStore)
+	require.NoError(s.T(), s.storage.Migrate(ctx), "Failed to migrate schema")
 
 	// Тестовые данные
 	s.testData = []*objects.Link{
@@ -109,6 +111,13 @@ func TestLinkStorageSuite(t *testing.T) {
 	suite.Run(t, new(LinkStorageTestSuite))
 }
 
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, `100\%`, escapeLike("100%"))
+	assert.Equal(t, `a\_b`, escapeLike("a_b"))
+	assert.Equal(t, `c:\\dir`, escapeLike(`c:\dir`))
+	assert.Equal(t, "", escapeLike(""))
+}
+
 func (s *LinkStorageTestSuite) TestCreateTable() {
 	// Удаляем таблицу для этого теста
 	_, err := s.db.Exec("DROP TABLE IF EXISTS links")
@@ -127,6 +136,34 @@ func (s *LinkStorageTestSuite) TestCreateTable() {
 	assert.True(s.T(), exists)
 }
 
+// URL длиннее 255 байт (до url_max_length) сохраняется и проверяется
+// на уникальность после миграции столбца original
+func (s *LinkStorageTestSuite) TestLongOriginal() {
+	ctx := context.Background()
+	_, err := s.db.Exec("DROP TABLE IF EXISTS links")
+	require.NoError(s.T(), err)
+	_, err = s.db.Exec("CREATE TABLE links (id SERIAL PRIMARY KEY, short CHAR(20) UNIQUE, original CHAR(255) UNIQUE, userid CHAR(36), is_deleted BOOLEAN DEFAULT FALSE)")
+	require.NoError(s.T(), err)
+	_, err = s.db.Exec("INSERT INTO links (short, original, userid) VALUES ('old', 'https://example.com/old', 'user1')")
+	require.NoError(s.T(), err)
+
+	// Миграция старой схемы и повторный запуск
+	require.NoError(s.T(), s.storage.CreateTable(ctx))
+	require.NoError(s.T(), s.storage.CreateTable(ctx))
+
+	old, err := s.storage.GetShort(ctx, "https://example.com/old")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "old", old.Short)
+
+	long := &objects.Link{Short: "long1", Original: "https://example.com/" + strings.Repeat("a", 2000), UserID: "user1"}
+	require.NoError(s.T(), s.storage.Insert(ctx, long))
+	assert.Equal(s.T(), ErrConflict, s.storage.Insert(ctx, &objects.Link{Short: "long2", Original: long.Original}))
+
+	link, err := s.storage.GetShort(ctx, long.Original)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "long1", link.Short)
+}
+
 func (s *LinkStorageTestSuite) TestInsert() {
 	ctx := context.Background()
 
@@ -147,7 +184,7 @@ func (s *LinkStorageTestSuite) TestGetOriginal() {
 	require.NoError(s.T(), err)
 
 	// Успешное получение
-	link, err := s.storage.GetOriginal(s.testData[0].Short)
+	link, err := s.storage.GetOriginal(context.Background(), s.testData[0].Short)
 	require.NoError(s.T(), err)
 
 	assert.Equal(s.T(), s.testData[0].Original, link.Original)
@@ -155,7 +192,7 @@ func (s *LinkStorageTestSuite) TestGetOriginal() {
 	assert.Equal(s.T(), s.testData[0].UserID, link.UserID)
 
 	// Несуществующая ссылка
-	_, err = s.storage.GetOriginal("nonexistent")
+	_, err = s.storage.GetOriginal(context.Background(), "nonexistent")
 	assert.Error(s.T(), err)
 	assert.True(s.T(), errors.Is(err, sql.ErrNoRows))
 }
@@ -168,7 +205,7 @@ func (s *LinkStorageTestSuite) TestGetShort() {
 	require.NoError(s.T(), err)
 
 	// Успешное получение
-	link, err := s.storage.GetShort(s.testData[0].Original)
+	link, err := s.storage.GetShort(context.Background(), s.testData[0].Original)
 	require.NoError(s.T(), err)
 
 	assert.Equal(s.T(), s.testData[0].Short, link.Short)
@@ -176,7 +213,7 @@ func (s *LinkStorageTestSuite) TestGetShort() {
 	assert.Equal(s.T(), s.testData[0].UserID, link.UserID)
 
 	// Несуществующая ссылка
-	_, err = s.storage.GetShort("https://nonexistent.com")
+	_, err = s.storage.GetShort(context.Background(), "https://nonexistent.com")
 	assert.Error(s.T(), err)
 	assert.True(s.T(), errors.Is(err, sql.ErrNoRows))
 }
@@ -188,7 +225,7 @@ func (s *LinkStorageTestSuite) TestGetAllByUserID() {
 	require.NoError(s.T(), err)
 
 	// Получаем ссылки user1
-	links, err := s.storage.GetAllByUserID("user1")
+	links, err := s.storage.GetAllByUserID(context.Background(), "user1")
 	assert.NoError(s.T(), err)
 	assert.Len(s.T(), links, 2)
 }
@@ -201,7 +238,7 @@ func (s *LinkStorageTestSuite) TestMarkAsDeleted() {
 	require.NoError(s.T(), err)
 
 	// Успешное удаление
-	err = s.storage.MarkAsDeleted("user1", s.testData[0].Short)
+	err = s.storage.MarkAsDeleted(context.Background(), "user1", s.testData[0].Short)
 	assert.NoError(s.T(), err)
 
 	// Проверяем что ссылка помечена как удаленная
@@ -211,10 +248,25 @@ func (s *LinkStorageTestSuite) TestMarkAsDeleted() {
 	).Scan(&isDeleted)
 	assert.NoError(s.T(), err)
 	assert.True(s.T(), isDeleted)
+
+	// Чужая или несуществующая ссылка не удаляется
+	err = s.storage.MarkAsDeleted(ctx, "user2", s.testData[0].Short)
+	assert.ErrorIs(s.T(), err, ErrLinkNotFound)
+	err = s.storage.MarkAsDeleted(ctx, "user1", "missing")
+	assert.ErrorIs(s.T(), err, ErrLinkNotFound)
+}
+
+func (s *LinkStorageTestSuite) TestCheckRoundTrip() {
+	require.NoError(s.T(), s.storage.CheckRoundTrip(context.Background()))
+
+	// Пробная запись откатывается
+	var count int
+	require.NoError(s.T(), s.db.QueryRow("SELECT count(*) FROM links").Scan(&count))
+	assert.Zero(s.T(), count)
 }
 
 func (s *LinkStorageTestSuite) TestPing() {
 	// Проверяем что Ping возвращает nil при успешном подключении
-	err := s.storage.Ping()
+	err := s.storage.Ping(context.Background())
 	assert.NoError(s.T(), err)
 }
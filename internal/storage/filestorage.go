@@ -4,11 +4,15 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"os"
+	"path/filepath"
+	"time"
 
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
 	"go.uber.org/zap"
 )
@@ -50,6 +54,74 @@ func (fs *FileStorage) ConfigureFileStorage() {
 	}
 
 	fs.Load(data)
+
+	keys, err := LoadAPIKeysFromFile(fs.keysPATH())
+	if err != nil {
+		zap.L().Fatal("Don't load API keys from file!", zap.Error(err))
+	}
+
+	for _, key := range keys {
+		fs.memStorage.keys[key.ID] = key
+	}
+
+	revoked, err := LoadRevokedFromFile(fs.revokedPATH())
+	if err != nil {
+		zap.L().Fatal("Don't load revoked tokens from file!", zap.Error(err))
+	}
+	fs.memStorage.revoked = revoked
+
+	disabled, err := LoadDisabledFromFile(fs.disabledPATH())
+	if err != nil {
+		zap.L().Fatal("Don't load disabled links from file!", zap.Error(err))
+	}
+	fs.memStorage.disabled = disabled
+
+	hooks, deliveries, err := LoadWebhooksFromFile(fs.webhooksPATH(), fs.deliveriesPATH())
+	if err != nil {
+		zap.L().Fatal("Don't load webhooks from file!", zap.Error(err))
+	}
+	fs.memStorage.webhooks = hooks
+	// Через SaveDelivery применяются обновления записей и ограничение истории
+	for _, d := range deliveries {
+		_ = fs.memStorage.SaveDelivery(context.Background(), d)
+	}
+
+	clicked, err := LoadClicksFromFile(fs.clicksPATH())
+	if err != nil {
+		zap.L().Fatal("Don't load clicks from file!", zap.Error(err))
+	}
+	fs.memStorage.clicked = clicked
+}
+
+// webhooksPATH возвращает путь к файлу подписок на вебхуки.
+func (fs *FileStorage) webhooksPATH() string {
+	return fs.filePATH + ".webhooks"
+}
+
+// deliveriesPATH возвращает путь к файлу истории доставок вебхуков.
+func (fs *FileStorage) deliveriesPATH() string {
+	return fs.filePATH + ".deliveries"
+}
+
+// clicksPATH возвращает путь к файлу ссылок, по которым были переходы.
+func (fs *FileStorage) clicksPATH() string {
+	return fs.filePATH + ".clicks"
+}
+
+// disabledPATH возвращает путь к файлу отключенных администратором ссылок.
+func (fs *FileStorage) disabledPATH() string {
+	return fs.filePATH + ".disabled"
+}
+
+// revokedPATH возвращает путь к файлу отозванных токенов.
+func (fs *FileStorage) revokedPATH() string {
+	return fs.filePATH + ".revoked"
+}
+
+// keysPATH возвращает путь к файлу API-ключей.
+// Ключи хранятся рядом с файлом ссылок с суффиксом ".keys".
+func (fs *FileStorage) keysPATH() string {
+	return fs.filePATH + ".keys"
 }
 
 // SaveToFile сохраняет одну ссылку в файл
@@ -135,6 +207,235 @@ func LoadFromFile(fileName string) (map[string]string, error) {
 	return data, nil
 }
 
+// LoadAPIKeysFromFile загружает API-ключи из файла
+//
+// Параметры:
+//   - fileName: путь к файлу ключей
+//
+// Возвращает:
+//   - map[string]*objects.APIKey: маппинг id→ключ
+//   - error: ошибка при загрузке
+//
+// Особенности:
+//   - Файл является журналом: более поздняя запись с тем же ID
+//     заменяет предыдущую (так сохраняется отзыв ключа)
+//   - Создает файл если он не существует
+func LoadAPIKeysFromFile(fileName string) (map[string]*objects.APIKey, error) {
+	file, err := os.OpenFile(fileName, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	keys := make(map[string]*objects.APIKey)
+
+	for scanner.Scan() {
+		var k objects.APIKey
+		if err := json.Unmarshal(scanner.Bytes(), &k); err != nil {
+			zap.L().Error("error scan API key", zap.Error(err))
+			continue
+		}
+		keys[k.ID] = &k
+	}
+	return keys, scanner.Err()
+}
+
+// saveAPIKeyToFile дописывает запись о ключе в конец файла ключей
+func saveAPIKeyToFile(key *objects.APIKey, fileName string) error {
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(key)
+}
+
+// revokedRecord — запись файла отозванных токенов
+type revokedRecord struct {
+	JTI       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LoadRevokedFromFile загружает список отозванных токенов из файла
+//
+// Параметры:
+//   - fileName: путь к файлу
+//
+// Возвращает:
+//   - map[string]time.Time: маппинг jti→срок действия
+//   - error: ошибка при загрузке
+//
+// Особенности:
+//   - Записи об уже истекших токенах пропускаются
+//   - Создает файл если он не существует
+func LoadRevokedFromFile(fileName string) (map[string]time.Time, error) {
+	file, err := os.OpenFile(fileName, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	now := time.Now()
+	revoked := make(map[string]time.Time)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec revokedRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			zap.L().Error("error scan revoked token", zap.Error(err))
+			continue
+		}
+		if now.Before(rec.ExpiresAt) {
+			revoked[rec.JTI] = rec.ExpiresAt
+		}
+	}
+	return revoked, scanner.Err()
+}
+
+// disabledRecord — запись файла отключенных ссылок
+type disabledRecord struct {
+	Short    string `json:"short_url"`
+	Disabled bool   `json:"disabled"`
+}
+
+// LoadDisabledFromFile загружает состояние отключенных ссылок из файла
+//
+// Параметры:
+//   - fileName: путь к файлу
+//
+// Возвращает:
+//   - map[string]bool: множество отключенных коротких URL
+//   - error: ошибка при загрузке
+//
+// Особенности:
+//   - Файл является журналом: более поздняя запись заменяет предыдущую
+//   - Создает файл если он не существует
+func LoadDisabledFromFile(fileName string) (map[string]bool, error) {
+	file, err := os.OpenFile(fileName, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	disabled := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec disabledRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			zap.L().Error("error scan disabled link", zap.Error(err))
+			continue
+		}
+		if rec.Disabled {
+			disabled[rec.Short] = true
+		} else {
+			delete(disabled, rec.Short)
+		}
+	}
+	return disabled, scanner.Err()
+}
+
+// webhookRecord — запись файла подписок на вебхуки
+type webhookRecord struct {
+	objects.Webhook
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// LoadWebhooksFromFile загружает подписки на вебхуки и историю их доставок
+//
+// Параметры:
+//   - hooksFile: путь к файлу подписок
+//   - deliveriesFile: путь к файлу доставок
+//
+// Возвращает:
+//   - map[string]*objects.Webhook: маппинг id→подписка
+//   - []*objects.WebhookDelivery: записи доставок в порядке файла
+//   - error: ошибка при загрузке
+//
+// Особенности:
+//   - Оба файла являются журналами: более поздняя запись с тем же ID
+//     заменяет предыдущую (для доставок — при сохранении через SaveDelivery)
+//   - Доставки удаленных подписок пропускаются
+//   - Создает файлы если они не существуют
+func LoadWebhooksFromFile(hooksFile string, deliveriesFile string) (map[string]*objects.Webhook, []*objects.WebhookDelivery, error) {
+	file, err := os.OpenFile(hooksFile, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	hooks := make(map[string]*objects.Webhook)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var rec webhookRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			zap.L().Error("error scan webhook", zap.Error(err))
+			continue
+		}
+		if rec.Deleted {
+			delete(hooks, rec.ID)
+			continue
+		}
+		hook := rec.Webhook
+		hooks[hook.ID] = &hook
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	dfile, err := os.OpenFile(deliveriesFile, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer dfile.Close()
+
+	var deliveries []*objects.WebhookDelivery
+	scanner = bufio.NewScanner(dfile)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var d objects.WebhookDelivery
+		if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+			zap.L().Error("error scan webhook delivery", zap.Error(err))
+			continue
+		}
+		if _, ok := hooks[d.WebhookID]; ok {
+			deliveries = append(deliveries, &d)
+		}
+	}
+	return hooks, deliveries, scanner.Err()
+}
+
+// LoadClicksFromFile загружает множество ссылок, по которым были переходы
+//
+// Особенности:
+//   - Каждая строка файла содержит один короткий URL
+//   - Создает файл если он не существует
+func LoadClicksFromFile(fileName string) (map[string]bool, error) {
+	file, err := os.OpenFile(fileName, os.O_RDONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	clicked := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if short := scanner.Text(); short != "" {
+			clicked[short] = true
+		}
+	}
+	return clicked, scanner.Err()
+}
+
+// appendJSONLine дописывает значение в конец файла-журнала отдельной строкой
+func appendJSONLine(fileName string, v any) error {
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(v)
+}
+
 // Load загружает предварительно сохраненные данные в in-memory хранилище
 func (fs *FileStorage) Load(data map[string]string) {
 	fs.memStorage.Load(data)
@@ -153,7 +454,7 @@ func (fs *FileStorage) Load(data map[string]string) {
 //   - Информацию о добавляемой ссылке
 //   - Успешное завершение операции
 func (fs *FileStorage) Insert(ctx context.Context, link *objects.Link) error {
-	zap.L().Info("FILE Inserting URL", zap.String("short", link.Short), zap.String("original", link.Original), zap.String("userID", link.UserID))
+	logg.FromContext(ctx).Info("FILE Inserting URL", zap.String("short", link.Short), logg.URL("original", link.Original), logg.UserID(link.UserID))
 
 	err := fs.memStorage.Insert(ctx, link)
 	if err != nil {
@@ -165,7 +466,7 @@ func (fs *FileStorage) Insert(ctx context.Context, link *objects.Link) error {
 		return err2
 	}
 
-	zap.L().Info("FILE URL inserted successfully", zap.String("short", link.Short), zap.String("original", link.Original), zap.String("userID", link.UserID))
+	logg.FromContext(ctx).Info("FILE URL inserted successfully", zap.String("short", link.Short), logg.URL("original", link.Original), logg.UserID(link.UserID))
 	return nil
 }
 
@@ -193,6 +494,7 @@ func (fs *FileStorage) InsertLinks(ctx context.Context, links []*objects.Link) e
 // GetOriginal возвращает оригинальный URL по сокращенному
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - short: сокращенный URL
 //
 // Возвращает:
@@ -202,32 +504,33 @@ func (fs *FileStorage) InsertLinks(ctx context.Context, links []*objects.Link) e
 // Логирует:
 //   - Ошибки поиска
 //   - Успешное выполнение
-func (fs *FileStorage) GetOriginal(short string) (*objects.Link, error) {
-	link, err := fs.memStorage.GetOriginal(short)
+func (fs *FileStorage) GetOriginal(ctx context.Context, short string) (*objects.Link, error) {
+	link, err := fs.memStorage.GetOriginal(ctx, short)
 	if err != nil {
-		zap.L().Error("Failed to get original URL", zap.String("short", short), zap.Error(err))
+		logg.FromContext(ctx).Error("Failed to get original URL", zap.String("short", short), zap.Error(err))
 		return nil, err
 	}
 
 	// Логируем успешное получение оригинального URL
-	zap.L().Info("Successfully retrieved original URL", zap.String("short", short), zap.String("original", link.Original))
+	logg.FromContext(ctx).Info("Successfully retrieved original URL", zap.String("short", short), logg.URL("original", link.Original))
 	return link, nil
 }
 
 // GetShort возвращает сокращенный URL по оригинальному
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - original: оригинальный URL
 //
 // Возвращает:
 //   - *objects.Link: найденная ссылка
 //   - error: ошибка при поиске
-func (fs *FileStorage) GetShort(original string) (*objects.Link, error) {
+func (fs *FileStorage) GetShort(ctx context.Context, original string) (*objects.Link, error) {
 
-	link, err := fs.memStorage.GetShort(original)
+	link, err := fs.memStorage.GetShort(ctx, original)
 
 	if err != nil {
-		zap.L().Error("Don't get short URL", zap.Error(err))
+		logg.FromContext(ctx).Error("Don't get short URL", zap.Error(err))
 		return link, err
 	}
 	return link, nil
@@ -236,6 +539,7 @@ func (fs *FileStorage) GetShort(original string) (*objects.Link, error) {
 // GetAllByUserID возвращает все ссылки пользователя
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - userID: идентификатор пользователя
 //
 // Возвращает:
@@ -245,11 +549,12 @@ func (fs *FileStorage) GetShort(original string) (*objects.Link, error) {
 // Логирует:
 //   - Начало и завершение операции
 //   - Результаты поиска
-func (fs *FileStorage) GetAllByUserID(userID string) ([]objects.Link, error) {
-	zap.L().Info("Getting URLs for user", zap.String("userID", userID))
-	userLinks := make([]objects.Link, 0, len(fs.memStorage.urls))
+func (fs *FileStorage) GetAllByUserID(ctx context.Context, userID string) ([]objects.Link, error) {
+	logg.FromContext(ctx).Info("Getting URLs for user", logg.UserID(userID))
+	logg.FromContext(ctx).Info("Querying user URLs from file storage", logg.UserID(userID))
 
-	zap.L().Info("Querying user URLs from file storage", zap.String("userID", userID))
+	fs.memStorage.mu.RLock()
+	userLinks := make([]objects.Link, 0, len(fs.memStorage.urls))
 
 	// Проходим по всем ссылкам в памяти и фильтруем по userID
 	for short, original := range fs.memStorage.urls {
@@ -262,8 +567,9 @@ func (fs *FileStorage) GetAllByUserID(userID string) ([]objects.Link, error) {
 			userLinks = append(userLinks, link)
 		}
 	}
+	fs.memStorage.mu.RUnlock()
 
-	zap.L().Info("User URLs retrieved from file storage", zap.String("userID", userID), zap.Any("userLinks", userLinks))
+	logg.FromContext(ctx).Info("User URLs retrieved from file storage", logg.UserID(userID), logg.Links("userLinks", userLinks))
 
 	if len(userLinks) == 0 {
 		return nil, nil
@@ -275,21 +581,225 @@ func (fs *FileStorage) GetAllByUserID(userID string) ([]objects.Link, error) {
 // MarkAsDeleted помечает ссылку как удаленную
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - userID: идентификатор пользователя
 //   - short: сокращенный URL
 //
 // Возвращает:
 //   - error: ошибка если ссылка не найдена или не принадлежит пользователю
-func (fs *FileStorage) MarkAsDeleted(userID string, short string) error {
-	if fs.memStorage.userIDs[short] == userID {
-		fs.memStorage.urls[short] = ""        // Помечаем URL как удаленный
-		fs.memStorage.userIDs[short] = userID // Сохраняем userID
-		return nil
+func (fs *FileStorage) MarkAsDeleted(ctx context.Context, userID string, short string) error {
+	return fs.memStorage.MarkAsDeleted(ctx, userID, short)
+}
+
+// RevokeToken добавляет токен в список отозванных в памяти и в файле
+//
+// Особенности:
+//   - Файл только дописывается; истекшие записи отбрасываются при загрузке
+func (fs *FileStorage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := fs.memStorage.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
 	}
-	return errors.New("URL not found or user mismatch")
+
+	file, err := os.OpenFile(fs.revokedPATH(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(revokedRecord{JTI: jti, ExpiresAt: expiresAt})
+}
+
+// IsTokenRevoked проверяет, отозван ли токен
+func (fs *FileStorage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return fs.memStorage.IsTokenRevoked(ctx, jti)
+}
+
+// ListLinks возвращает ссылки всех пользователей, подходящие под фильтр
+func (fs *FileStorage) ListLinks(ctx context.Context, filter objects.LinkFilter) ([]objects.Link, error) {
+	return fs.memStorage.ListLinks(ctx, filter)
+}
+
+// SetLinkDisabled отключает ссылку или восстанавливает отключенную
+//
+// Особенности:
+//   - Изменение дописывается в файл отключенных ссылок
+func (fs *FileStorage) SetLinkDisabled(ctx context.Context, short string, disabled bool) error {
+	if err := fs.memStorage.SetLinkDisabled(ctx, short, disabled); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(fs.disabledPATH(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(disabledRecord{Short: short, Disabled: disabled})
+}
+
+// ListUsers возвращает пользователей и количество их ссылок
+func (fs *FileStorage) ListUsers(ctx context.Context) ([]objects.UserStat, error) {
+	return fs.memStorage.ListUsers(ctx)
+}
+
+// TransferLinks переносит все ссылки одного пользователя другому
+//
+// Особенности:
+//   - Файл хранилища не содержит владельцев ссылок, поэтому перенос
+//     выполняется только в памяти
+func (fs *FileStorage) TransferLinks(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	return fs.memStorage.TransferLinks(ctx, fromUserID, toUserID)
 }
 
 // Ping проверяет доступность хранилища
-func (fs *FileStorage) Ping() error {
+func (fs *FileStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// CheckRoundTrip записывает пробный файл рядом с файлом ссылок, читает
+// его обратно и удаляет. Используется проверкой готовности (/readyz).
+//
+// Возвращает:
+//   - error: ошибка записи, чтения или несовпадение прочитанных данных
+func (fs *FileStorage) CheckRoundTrip(ctx context.Context) error {
+	file, err := os.CreateTemp(filepath.Dir(fs.filePATH), ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	probe := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	_, err = file.Write(probe)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(data, probe) {
+		return errors.New("storage probe read back different data")
+	}
 	return nil
 }
+
+// CheckWritable проверяет, что файл ссылок и все вспомогательные файлы
+// можно открыть на дозапись. Используется проверкой готовности (/readyz).
+//
+// Возвращает:
+//   - error: ошибки открытия файлов, объединенные через errors.Join
+func (fs *FileStorage) CheckWritable() error {
+	var errs []error
+	for _, path := range []string{
+		fs.filePATH,
+		fs.keysPATH(),
+		fs.revokedPATH(),
+		fs.disabledPATH(),
+		fs.webhooksPATH(),
+		fs.deliveriesPATH(),
+		fs.clicksPATH(),
+	} {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := file.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InsertAPIKey сохраняет новый API-ключ в памяти и в файле ключей
+func (fs *FileStorage) InsertAPIKey(ctx context.Context, key *objects.APIKey) error {
+	if err := fs.memStorage.InsertAPIKey(ctx, key); err != nil {
+		return err
+	}
+	return saveAPIKeyToFile(key, fs.keysPATH())
+}
+
+// GetAPIKeyByHash возвращает действующий API-ключ по его хешу
+func (fs *FileStorage) GetAPIKeyByHash(ctx context.Context, hash string) (*objects.APIKey, error) {
+	return fs.memStorage.GetAPIKeyByHash(ctx, hash)
+}
+
+// GetAPIKeysByUserID возвращает все API-ключи пользователя
+func (fs *FileStorage) GetAPIKeysByUserID(ctx context.Context, userID string) ([]objects.APIKey, error) {
+	return fs.memStorage.GetAPIKeysByUserID(ctx, userID)
+}
+
+// RevokeAPIKey отзывает API-ключ пользователя
+//
+// Особенности:
+//   - В файл дописывается обновленная запись ключа с флагом revoked
+func (fs *FileStorage) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	if err := fs.memStorage.RevokeAPIKey(ctx, userID, id); err != nil {
+		return err
+	}
+
+	fs.memStorage.mu.RLock()
+	key := *fs.memStorage.keys[id]
+	fs.memStorage.mu.RUnlock()
+
+	return saveAPIKeyToFile(&key, fs.keysPATH())
+}
+
+// InsertWebhook сохраняет новую подписку в памяти и в файле подписок
+func (fs *FileStorage) InsertWebhook(ctx context.Context, hook *objects.Webhook) error {
+	if err := fs.memStorage.InsertWebhook(ctx, hook); err != nil {
+		return err
+	}
+	return appendJSONLine(fs.webhooksPATH(), webhookRecord{Webhook: *hook})
+}
+
+// GetWebhooksByUserID возвращает подписки пользователя
+func (fs *FileStorage) GetWebhooksByUserID(ctx context.Context, userID string) ([]objects.Webhook, error) {
+	return fs.memStorage.GetWebhooksByUserID(ctx, userID)
+}
+
+// DeleteWebhook удаляет подписку пользователя
+//
+// Особенности:
+//   - В файл дописывается запись с флагом deleted; доставки удаленной
+//     подписки отбрасываются при следующей загрузке
+func (fs *FileStorage) DeleteWebhook(ctx context.Context, userID string, id string) error {
+	if err := fs.memStorage.DeleteWebhook(ctx, userID, id); err != nil {
+		return err
+	}
+	return appendJSONLine(fs.webhooksPATH(), webhookRecord{Webhook: objects.Webhook{ID: id, UserID: userID}, Deleted: true})
+}
+
+// SaveDelivery сохраняет доставку вебхука в памяти и дописывает ее в файл доставок
+func (fs *FileStorage) SaveDelivery(ctx context.Context, delivery *objects.WebhookDelivery) error {
+	if err := fs.memStorage.SaveDelivery(ctx, delivery); err != nil {
+		return err
+	}
+	return appendJSONLine(fs.deliveriesPATH(), delivery)
+}
+
+// GetDeliveries возвращает историю доставок по подписке пользователя
+func (fs *FileStorage) GetDeliveries(ctx context.Context, userID string, webhookID string) ([]objects.WebhookDelivery, error) {
+	return fs.memStorage.GetDeliveries(ctx, userID, webhookID)
+}
+
+// MarkFirstClick отмечает переход по ссылке
+//
+// Особенности:
+//   - В файл записывается только первый переход по каждой ссылке
+func (fs *FileStorage) MarkFirstClick(ctx context.Context, short string) (bool, error) {
+	first, err := fs.memStorage.MarkFirstClick(ctx, short)
+	if err != nil || !first {
+		return first, err
+	}
+
+	file, err := os.OpenFile(fs.clicksPATH(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return true, err
+	}
+	defer file.Close()
+	_, err = file.WriteString(short + "\n")
+	return true, err
+}
@@ -3,7 +3,9 @@ package storage
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
 	"github.com/stretchr/testify/assert"
@@ -58,14 +60,14 @@ func TestFileStorage_InsertAndGet(t *testing.T) {
 	require.NoError(t, err)
 
 	// Получаем ссылку по короткому URL
-	retrievedLink, err := fs.GetOriginal(link.Short)
+	retrievedLink, err := fs.GetOriginal(context.Background(), link.Short)
 	require.NoError(t, err)
 	assert.Equal(t, link.Original, retrievedLink.Original)
 	assert.Equal(t, link.Short, retrievedLink.Short)
 	assert.Equal(t, link.UserID, retrievedLink.UserID)
 
 	// Получаем ссылку по оригинальному URL
-	retrievedShortLink, err := fs.GetShort(link.Original)
+	retrievedShortLink, err := fs.GetShort(context.Background(), link.Original)
 	require.NoError(t, err)
 	assert.Equal(t, link.Original, retrievedShortLink.Original)
 	assert.Equal(t, link.Short, retrievedShortLink.Short)
@@ -100,7 +102,7 @@ func TestFileStorage_InsertLinks(t *testing.T) {
 
 	// Проверяем что ссылки сохранились
 	for _, link := range links {
-		retrievedLink, err := fs.GetOriginal(link.Short)
+		retrievedLink, err := fs.GetOriginal(context.Background(), link.Short)
 		require.NoError(t, err)
 		assert.Equal(t, link.Original, retrievedLink.Original)
 	}
@@ -141,7 +143,7 @@ func TestFileStorage_GetAllByUserID(t *testing.T) {
 	require.NoError(t, err)
 
 	// Получаем ссылки для user1
-	links, err := fs.GetAllByUserID("user1")
+	links, err := fs.GetAllByUserID(context.Background(), "user1")
 	require.NoError(t, err)
 	assert.Len(t, links, 2)
 
@@ -153,7 +155,7 @@ func TestFileStorage_GetAllByUserID(t *testing.T) {
 	}
 
 	// Получаем ссылки для user2
-	links, err = fs.GetAllByUserID("user2")
+	links, err = fs.GetAllByUserID(context.Background(), "user2")
 	require.NoError(t, err)
 	assert.Len(t, links, 1)
 	assert.Equal(t, user2Link.Short, links[0].Short)
@@ -181,16 +183,16 @@ func TestFileStorage_MarkAsDeleted(t *testing.T) {
 	require.NoError(t, err)
 
 	// Помечаем как удаленную
-	err = fs.MarkAsDeleted("user1", link.Short)
+	err = fs.MarkAsDeleted(context.Background(), "user1", link.Short)
 	require.NoError(t, err)
 
 	// Проверяем что ссылка помечена как удаленная
-	retrievedLink, err := fs.GetOriginal(link.Short)
+	retrievedLink, err := fs.GetOriginal(context.Background(), link.Short)
 	require.NoError(t, err)
 	assert.Empty(t, retrievedLink.Original)
 
 	// Попытка пометить как удаленную чужую ссылку
-	err = fs.MarkAsDeleted("user2", link.Short)
+	err = fs.MarkAsDeleted(context.Background(), "user2", link.Short)
 	assert.Error(t, err)
 }
 
@@ -213,7 +215,7 @@ func TestFileStorage_Persistence(t *testing.T) {
 
 	// Второе хранилище - проверяем что данные сохранились
 	fs2 := NewFileStorage(tmpFile.Name())
-	retrievedLink, err := fs2.GetOriginal(link.Short)
+	retrievedLink, err := fs2.GetOriginal(context.Background(), link.Short)
 	require.NoError(t, err)
 	assert.Equal(t, link.Original, retrievedLink.Original)
 }
@@ -240,7 +242,7 @@ func TestFileStorage_GetOriginal(t *testing.T) {
 
 	t.Run("successful get", func(t *testing.T) {
 		// Получаем существующую ссылку
-		link, err := fs.GetOriginal(testLink.Short)
+		link, err := fs.GetOriginal(context.Background(), testLink.Short)
 		require.NoError(t, err)
 		assert.Equal(t, testLink.Original, link.Original)
 		assert.Equal(t, testLink.Short, link.Short)
@@ -249,14 +251,14 @@ func TestFileStorage_GetOriginal(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		// Пытаемся получить несуществующую ссылку
-		_, err := fs.GetOriginal("nonexistent")
+		_, err := fs.GetOriginal(context.Background(), "nonexistent")
 		assert.Error(t, err)
 		assert.Equal(t, "short URL not found", err.Error())
 	})
 
 	t.Run("empty short URL", func(t *testing.T) {
 		// Пытаемся получить с пустым short URL
-		_, err := fs.GetOriginal("")
+		_, err := fs.GetOriginal(context.Background(), "")
 		assert.Error(t, err)
 		assert.Equal(t, "short URL not found", err.Error())
 	})
@@ -284,7 +286,7 @@ func TestFileStorage_GetShort(t *testing.T) {
 
 	t.Run("successful get", func(t *testing.T) {
 		// Получаем существующую ссылку
-		link, err := fs.GetShort(testLink.Original)
+		link, err := fs.GetShort(context.Background(), testLink.Original)
 		require.NoError(t, err)
 		assert.Equal(t, testLink.Original, link.Original)
 		assert.Equal(t, testLink.Short, link.Short)
@@ -293,14 +295,14 @@ func TestFileStorage_GetShort(t *testing.T) {
 
 	t.Run("not found", func(t *testing.T) {
 		// Пытаемся получить несуществующую ссылку
-		_, err := fs.GetShort("https://nonexistent.com")
+		_, err := fs.GetShort(context.Background(), "https://nonexistent.com")
 		assert.Error(t, err)
 		assert.Equal(t, "original URL not found", err.Error())
 	})
 
 	t.Run("empty original URL", func(t *testing.T) {
 		// Пытаемся получить с пустым original URL
-		_, err := fs.GetShort("")
+		_, err := fs.GetShort(context.Background(), "")
 		assert.Error(t, err)
 		assert.Equal(t, "original URL not found", err.Error())
 	})
@@ -340,6 +342,84 @@ func TestFileStorage_Ping(t *testing.T) {
 	fs := NewFileStorage(tmpFile.Name())
 
 	// Проверяем что Ping возвращает nil (успешная проверка доступности)
-	err = fs.Ping()
+	err = fs.Ping(context.Background())
 	assert.NoError(t, err, "Ping should always return nil for FileStorage")
 }
+
+func TestFileStorage_APIKeysPersistence(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "test_storage_*.json")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".keys")
+
+	ctx := context.Background()
+	fs := NewFileStorage(tmpFile.Name())
+
+	key := &objects.APIKey{ID: "k1", UserID: "user1", Name: "ci", Hash: "h1", CreatedAt: time.Now()}
+	require.NoError(t, fs.InsertAPIKey(ctx, key))
+	assert.ErrorIs(t, fs.InsertAPIKey(ctx, key), ErrConflict)
+
+	// Ключ доступен после перезагрузки хранилища
+	fs = NewFileStorage(tmpFile.Name())
+	got, err := fs.GetAPIKeyByHash(ctx, "h1")
+	require.NoError(t, err)
+	assert.Equal(t, "user1", got.UserID)
+
+	// Отзыв другим пользователем невозможен
+	assert.ErrorIs(t, fs.RevokeAPIKey(ctx, "user2", "k1"), ErrAPIKeyNotFound)
+
+	// Отзыв сохраняется в файле
+	require.NoError(t, fs.RevokeAPIKey(ctx, "user1", "k1"))
+	fs = NewFileStorage(tmpFile.Name())
+	_, err = fs.GetAPIKeyByHash(ctx, "h1")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+
+	keys, err := fs.GetAPIKeysByUserID(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.True(t, keys[0].Revoked)
+}
+
+func TestFileStorage_WebhooksPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "storage.json")
+	ctx := context.Background()
+	fs := NewFileStorage(path)
+
+	hook := &objects.Webhook{ID: "h1", UserID: "user1", URL: "https://example.com", Secret: "s", Events: []string{"link.created"}, CreatedAt: time.Now()}
+	require.NoError(t, fs.InsertWebhook(ctx, hook))
+
+	delivery := &objects.WebhookDelivery{ID: "d1", WebhookID: "h1", UserID: "user1", Status: objects.DeliveryPending}
+	require.NoError(t, fs.SaveDelivery(ctx, delivery))
+	delivery.Status = objects.DeliveryDead
+	delivery.Attempts = 5
+	require.NoError(t, fs.SaveDelivery(ctx, delivery))
+
+	first, err := fs.MarkFirstClick(ctx, "abc")
+	require.NoError(t, err)
+	assert.True(t, first)
+
+	// Подписка, последнее состояние доставки и переходы доступны после перезагрузки
+	fs = NewFileStorage(path)
+	hooks, err := fs.GetWebhooksByUserID(ctx, "user1")
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.Equal(t, []string{"link.created"}, hooks[0].Events)
+
+	deliveries, err := fs.GetDeliveries(ctx, "user1", "h1")
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, objects.DeliveryDead, deliveries[0].Status)
+	assert.Equal(t, 5, deliveries[0].Attempts)
+
+	first, err = fs.MarkFirstClick(ctx, "abc")
+	require.NoError(t, err)
+	assert.False(t, first)
+
+	// Удаление сохраняется в файле
+	assert.ErrorIs(t, fs.DeleteWebhook(ctx, "user2", "h1"), ErrWebhookNotFound)
+	require.NoError(t, fs.DeleteWebhook(ctx, "user1", "h1"))
+	fs = NewFileStorage(path)
+	hooks, err = fs.GetWebhooksByUserID(ctx, "user1")
+	require.NoError(t, err)
+	assert.Empty(t, hooks)
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+)
+
+// Observer вызывается в начале каждой операции хранилища.
+// Возвращает контекст для вызова бэкенда и функцию, которую нужно
+// вызвать по завершении операции с ее ошибкой.
+//
+// Параметры:
+//   - ctx: контекст операции
+//   - method: имя метода хранилища, например "GetOriginal"
+type Observer func(ctx context.Context, method string) (context.Context, func(err error))
+
+// fullStorage объединяет основной интерфейс хранилища и все дополнительные
+// возможности; его реализуют все бэкенды этого пакета
+type fullStorage interface {
+	objects.Storage
+	objects.APIKeyStorage
+	objects.OwnershipStorage
+	objects.RevocationStorage
+	objects.AdminStorage
+	objects.WebhookStorage
+}
+
+// Instrumented оборачивает хранилище и сообщает о каждой операции наблюдателям
+// (метрики, трассировка). Реализует те же интерфейсы, что и бэкенды.
+type Instrumented struct {
+	inner     fullStorage
+	observers []Observer
+}
+
+// Instrument оборачивает хранилище наблюдателями.
+//
+// Параметры:
+//   - s: хранилище
+//   - observers: наблюдатели, вызываются в порядке передачи
+//
+// Возвращает:
+//   - objects.Storage: обертку; если s реализует не все дополнительные
+//     интерфейсы, возвращается s без изменений, чтобы проверки возможностей
+//     хранилища через приведение типов работали как прежде
+func Instrument(s objects.Storage, observers ...Observer) objects.Storage {
+	full, ok := s.(fullStorage)
+	if !ok || len(observers) == 0 {
+		return s
+	}
+	return &Instrumented{inner: full, observers: observers}
+}
+
+// Unwrap возвращает исходное хранилище.
+func (s *Instrumented) Unwrap() objects.Storage {
+	return s.inner
+}
+
+// start уведомляет наблюдателей о начале операции
+func (s *Instrumented) start(ctx context.Context, method string) (context.Context, func(error)) {
+	dones := make([]func(error), len(s.observers))
+	for i, o := range s.observers {
+		ctx, dones[i] = o(ctx, method)
+	}
+	return ctx, func(err error) {
+		for i := len(dones) - 1; i >= 0; i-- {
+			dones[i](err)
+		}
+	}
+}
+
+func (s *Instrumented) Insert(ctx context.Context, link *objects.Link) (err error) {
+	ctx, done := s.start(ctx, "Insert")
+	defer func() { done(err) }()
+	return s.inner.Insert(ctx, link)
+}
+
+func (s *Instrumented) InsertLinks(ctx context.Context, links []*objects.Link) (err error) {
+	ctx, done := s.start(ctx, "InsertLinks")
+	defer func() { done(err) }()
+	return s.inner.InsertLinks(ctx, links)
+}
+
+func (s *Instrumented) GetOriginal(ctx context.Context, short string) (link *objects.Link, err error) {
+	ctx, done := s.start(ctx, "GetOriginal")
+	defer func() { done(err) }()
+	return s.inner.GetOriginal(ctx, short)
+}
+
+func (s *Instrumented) GetShort(ctx context.Context, original string) (link *objects.Link, err error) {
+	ctx, done := s.start(ctx, "GetShort")
+	defer func() { done(err) }()
+	return s.inner.GetShort(ctx, original)
+}
+
+func (s *Instrumented) GetAllByUserID(ctx context.Context, userID string) (links []objects.Link, err error) {
+	ctx, done := s.start(ctx, "GetAllByUserID")
+	defer func() { done(err) }()
+	return s.inner.GetAllByUserID(ctx, userID)
+}
+
+func (s *Instrumented) MarkAsDeleted(ctx context.Context, userID string, short string) (err error) {
+	ctx, done := s.start(ctx, "MarkAsDeleted")
+	defer func() { done(err) }()
+	return s.inner.MarkAsDeleted(ctx, userID, short)
+}
+
+func (s *Instrumented) Ping(ctx context.Context) (err error) {
+	ctx, done := s.start(ctx, "Ping")
+	defer func() { done(err) }()
+	return s.inner.Ping(ctx)
+}
+
+func (s *Instrumented) InsertAPIKey(ctx context.Context, key *objects.APIKey) (err error) {
+	ctx, done := s.start(ctx, "InsertAPIKey")
+	defer func() { done(err) }()
+	return s.inner.InsertAPIKey(ctx, key)
+}
+
+func (s *Instrumented) GetAPIKeyByHash(ctx context.Context, hash string) (key *objects.APIKey, err error) {
+	ctx, done := s.start(ctx, "GetAPIKeyByHash")
+	defer func() { done(err) }()
+	return s.inner.GetAPIKeyByHash(ctx, hash)
+}
+
+func (s *Instrumented) GetAPIKeysByUserID(ctx context.Context, userID string) (keys []objects.APIKey, err error) {
+	ctx, done := s.start(ctx, "GetAPIKeysByUserID")
+	defer func() { done(err) }()
+	return s.inner.GetAPIKeysByUserID(ctx, userID)
+}
+
+func (s *Instrumented) RevokeAPIKey(ctx context.Context, userID string, id string) (err error) {
+	ctx, done := s.start(ctx, "RevokeAPIKey")
+	defer func() { done(err) }()
+	return s.inner.RevokeAPIKey(ctx, userID, id)
+}
+
+func (s *Instrumented) TransferLinks(ctx context.Context, fromUserID string, toUserID string) (n int, err error) {
+	ctx, done := s.start(ctx, "TransferLinks")
+	defer func() { done(err) }()
+	return s.inner.TransferLinks(ctx, fromUserID, toUserID)
+}
+
+func (s *Instrumented) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) (err error) {
+	ctx, done := s.start(ctx, "RevokeToken")
+	defer func() { done(err) }()
+	return s.inner.RevokeToken(ctx, jti, expiresAt)
+}
+
+func (s *Instrumented) IsTokenRevoked(ctx context.Context, jti string) (revoked bool, err error) {
+	ctx, done := s.start(ctx, "IsTokenRevoked")
+	defer func() { done(err) }()
+	return s.inner.IsTokenRevoked(ctx, jti)
+}
+
+func (s *Instrumented) ListLinks(ctx context.Context, filter objects.LinkFilter) (links []objects.Link, err error) {
+	ctx, done := s.start(ctx, "ListLinks")
+	defer func() { done(err) }()
+	return s.inner.ListLinks(ctx, filter)
+}
+
+func (s *Instrumented) SetLinkDisabled(ctx context.Context, short string, disabled bool) (err error) {
+	ctx, done := s.start(ctx, "SetLinkDisabled")
+	defer func() { done(err) }()
+	return s.inner.SetLinkDisabled(ctx, short, disabled)
+}
+
+func (s *Instrumented) ListUsers(ctx context.Context) (users []objects.UserStat, err error) {
+	ctx, done := s.start(ctx, "ListUsers")
+	defer func() { done(err) }()
+	return s.inner.ListUsers(ctx)
+}
+
+func (s *Instrumented) InsertWebhook(ctx context.Context, hook *objects.Webhook) (err error) {
+	ctx, done := s.start(ctx, "InsertWebhook")
+	defer func() { done(err) }()
+	return s.inner.InsertWebhook(ctx, hook)
+}
+
+func (s *Instrumented) GetWebhooksByUserID(ctx context.Context, userID string) (hooks []objects.Webhook, err error) {
+	ctx, done := s.start(ctx, "GetWebhooksByUserID")
+	defer func() { done(err) }()
+	return s.inner.GetWebhooksByUserID(ctx, userID)
+}
+
+func (s *Instrumented) DeleteWebhook(ctx context.Context, userID string, id string) (err error) {
+	ctx, done := s.start(ctx, "DeleteWebhook")
+	defer func() { done(err) }()
+	return s.inner.DeleteWebhook(ctx, userID, id)
+}
+
+func (s *Instrumented) SaveDelivery(ctx context.Context, delivery *objects.WebhookDelivery) (err error) {
+	ctx, done := s.start(ctx, "SaveDelivery")
+	defer func() { done(err) }()
+	return s.inner.SaveDelivery(ctx, delivery)
+}
+
+func (s *Instrumented) GetDeliveries(ctx context.Context, userID string, webhookID string) (deliveries []objects.WebhookDelivery, err error) {
+	ctx, done := s.start(ctx, "GetDeliveries")
+	defer func() { done(err) }()
+	return s.inner.GetDeliveries(ctx, userID, webhookID)
+}
+
+func (s *Instrumented) MarkFirstClick(ctx context.Context, short string) (first bool, err error) {
+	ctx, done := s.start(ctx, "MarkFirstClick")
+	defer func() { done(err) }()
+	return s.inner.MarkFirstClick(ctx, short)
+}
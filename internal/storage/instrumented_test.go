@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrument(t *testing.T) {
+	type call struct {
+		method string
+		err    error
+	}
+	var calls []call
+	observer := func(ctx context.Context, method string) (context.Context, func(error)) {
+		return ctx, func(err error) { calls = append(calls, call{method, err}) }
+	}
+
+	s := Instrument(NewInMemoryStorage(), observer)
+
+	// Обертка сохраняет дополнительные возможности бэкенда
+	_, ok := s.(objects.AdminStorage)
+	assert.True(t, ok)
+	_, ok = s.(objects.WebhookStorage)
+	assert.True(t, ok)
+
+	require.NoError(t, s.Insert(context.Background(), &objects.Link{Short: "abc", Original: "https://example.com"}))
+	_, err := s.GetOriginal(context.Background(), "missing")
+	require.Error(t, err)
+
+	require.Len(t, calls, 2)
+	assert.Equal(t, "Insert", calls[0].method)
+	assert.NoError(t, calls[0].err)
+	assert.Equal(t, "GetOriginal", calls[1].method)
+	assert.Equal(t, err, calls[1].err)
+
+	// Хранилище без всех возможностей не оборачивается
+	var plain objects.Storage = struct{ objects.Storage }{NewInMemoryStorage()}
+	assert.Equal(t, plain, Instrument(plain, observer))
+}
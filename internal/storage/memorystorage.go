@@ -5,15 +5,30 @@ package storage
 import (
 	"context"
 	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
 	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
 	"go.uber.org/zap"
 )
 
 // InMemoryStorage реализует хранилище ссылок в оперативной памяти
 type InMemoryStorage struct {
-	urls    map[string]string // short -> original
-	userIDs map[string]string // short -> userID
+	// mu защищает все мапы хранилища; читающие методы берут RLock
+	mu sync.RWMutex
+
+	urls       map[string]string                     // short -> original
+	userIDs    map[string]string                     // short -> userID
+	disabled   map[string]bool                       // short -> отключена администратором
+	keys       map[string]*objects.APIKey            // id -> API-ключ
+	revoked    map[string]time.Time                  // jti -> срок действия отозванного токена
+	webhooks   map[string]*objects.Webhook           // id -> подписка на вебхуки
+	deliveries map[string][]*objects.WebhookDelivery // webhookID -> доставки в порядке создания
+	clicked    map[string]bool                       // short -> был хотя бы один переход
 }
 
 // NewInMemoryStorage создает новое in-memory хранилище
@@ -22,8 +37,14 @@ type InMemoryStorage struct {
 //   - *InMemoryStorage: инициализированное хранилище с пустыми мапами
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		urls:    make(map[string]string),
-		userIDs: make(map[string]string),
+		urls:       make(map[string]string),
+		userIDs:    make(map[string]string),
+		disabled:   make(map[string]bool),
+		keys:       make(map[string]*objects.APIKey),
+		revoked:    make(map[string]time.Time),
+		webhooks:   make(map[string]*objects.Webhook),
+		deliveries: make(map[string][]*objects.WebhookDelivery),
+		clicked:    make(map[string]bool),
 	}
 }
 
@@ -36,6 +57,9 @@ func NewInMemoryStorage() *InMemoryStorage {
 //   - Полностью заменяет текущие данные
 //   - Не затрагивает информацию о пользователях
 func (s *InMemoryStorage) Load(data map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.urls = data
 }
 
@@ -51,14 +75,16 @@ func (s *InMemoryStorage) Load(data map[string]string) {
 // Логирует:
 //   - Информацию о добавляемой ссылке
 func (s *InMemoryStorage) Insert(ctx context.Context, link *objects.Link) error {
-	zap.L().Info("Inserting URL", zap.String("short", link.Short), zap.String("original", link.Original), zap.String("userID", link.UserID))
+	logg.FromContext(ctx).Info("Inserting URL", zap.String("short", link.Short), logg.URL("original", link.Original), logg.UserID(link.UserID))
 
+	s.mu.Lock()
 	s.urls[link.Short] = link.Original
 	s.userIDs[link.Short] = link.UserID
+	s.mu.Unlock()
 
-	zap.L().Debug("internal/storage/memorystorage.go Insert",
-		zap.String("userID", link.UserID),
-		zap.String("original", link.Original),
+	logg.FromContext(ctx).Debug("internal/storage/memorystorage.go Insert",
+		logg.UserID(link.UserID),
+		logg.URL("original", link.Original),
 	)
 
 	return nil
@@ -76,31 +102,39 @@ func (s *InMemoryStorage) Insert(ctx context.Context, link *objects.Link) error
 // Логирует:
 //   - Начало и завершение операции
 func (s *InMemoryStorage) InsertLinks(ctx context.Context, links []*objects.Link) error {
-	zap.L().Info("MEMORY Inserting multiple URLs", zap.Any("links", links))
+	logg.FromContext(ctx).Info("MEMORY Inserting multiple URLs", logg.Links("links", links))
 
+	s.mu.Lock()
 	for _, link := range links {
 		s.urls[link.Short] = link.Original
 		s.userIDs[link.Short] = link.UserID
 	}
+	s.mu.Unlock()
 
-	zap.L().Info("MEMORY URLs inserted successfully", zap.Any("links", links))
+	logg.FromContext(ctx).Info("MEMORY URLs inserted successfully", logg.Links("links", links))
 	return nil
 }
 
 // GetOriginal возвращает оригинальный URL по его сокращенной версии
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - short: сокращенный URL
 //
 // Возвращает:
 //   - *objects.Link: найденная ссылка с userID
 //   - error: "short URL not found" если ссылка не существует
-func (s *InMemoryStorage) GetOriginal(short string) (*objects.Link, error) {
+func (s *InMemoryStorage) GetOriginal(ctx context.Context, short string) (*objects.Link, error) {
+	s.mu.RLock()
 	original, exists := s.urls[short]
-	zap.L().Debug("internal/storage/memorystorage.go GetOriginal",
-		zap.String("userID", s.userIDs[short]),
-		zap.String("short", s.urls[short]),
-		zap.String("original", original),
+	userID := s.userIDs[short]
+	disabled := s.disabled[short]
+	s.mu.RUnlock()
+
+	logg.FromContext(ctx).Debug("internal/storage/memorystorage.go GetOriginal",
+		logg.UserID(userID),
+		zap.String("short", short),
+		logg.URL("original", original),
 	)
 
 	if !exists {
@@ -109,26 +143,30 @@ func (s *InMemoryStorage) GetOriginal(short string) (*objects.Link, error) {
 	return &objects.Link{
 		Short:    short,
 		Original: original,
-		UserID:   s.userIDs[short],
+		UserID:   userID,
+		Disabled: disabled,
 	}, nil
 }
 
 // GetShort возвращает сокращенный URL по оригинальному
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - original: оригинальный URL
 //
 // Возвращает:
 //   - *objects.Link: найденная ссылка с userID
 //   - error: "original URL not found" если ссылка не существует
-func (s *InMemoryStorage) GetShort(original string) (*objects.Link, error) {
-	for short, orig := range s.urls {
-		zap.L().Debug("internal/storage/memorystorage.go GetShort",
-			zap.String("userID", s.userIDs[short]),
-			zap.String("short", short),
-			zap.String("original", original),
-		)
+func (s *InMemoryStorage) GetShort(ctx context.Context, original string) (*objects.Link, error) {
+	// Одна запись на вызов, а не на каждую просмотренную ссылку
+	logg.FromContext(ctx).Debug("internal/storage/memorystorage.go GetShort",
+		logg.URL("original", original),
+	)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
+	for short, orig := range s.urls {
 		if orig == original {
 			return &objects.Link{
 				Short:    short,
@@ -143,20 +181,22 @@ func (s *InMemoryStorage) GetShort(original string) (*objects.Link, error) {
 // GetAllByUserID возвращает все ссылки принадлежащие указанному пользователю
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - userID: идентификатор пользователя
 //
 // Возвращает:
 //   - []objects.Link: массив ссылок пользователя (может быть пустым)
 //   - error: всегда nil
-func (s *InMemoryStorage) GetAllByUserID(userID string) ([]objects.Link, error) {
-	zap.L().Info("Getting URLs for user", zap.String("userID", userID))
-	zap.L().Debug("internal/storage/memorystorage.go GetAllByUserID",
-		zap.String("UserID", userID),
+func (s *InMemoryStorage) GetAllByUserID(ctx context.Context, userID string) ([]objects.Link, error) {
+	logg.FromContext(ctx).Info("Getting URLs for user", logg.UserID(userID))
+	logg.FromContext(ctx).Debug("internal/storage/memorystorage.go GetAllByUserID",
+		logg.UserID(userID),
 	)
 
 	var userLinks []objects.Link
 
 	// Проходим по всем URL и фильтруем по userID
+	s.mu.RLock()
 	for short, original := range s.urls {
 		if s.userIDs[short] == userID { // Проверяем, что URL принадлежит userID
 			userLinks = append(userLinks, objects.Link{
@@ -166,8 +206,9 @@ func (s *InMemoryStorage) GetAllByUserID(userID string) ([]objects.Link, error)
 			})
 		}
 	}
+	s.mu.RUnlock()
 
-	zap.L().Info("Retrieved URLs for user", zap.String("userID", userID), zap.Any("userLinks", userLinks))
+	logg.FromContext(ctx).Info("Retrieved URLs for user", logg.UserID(userID), logg.Links("userLinks", userLinks))
 
 	if len(userLinks) == 0 {
 		return nil, nil // Если URL не найдены, возвращаем nil
@@ -179,6 +220,7 @@ func (s *InMemoryStorage) GetAllByUserID(userID string) ([]objects.Link, error)
 // MarkAsDeleted помечает ссылку как удаленную
 //
 // Параметры:
+//   - ctx: контекст выполнения
 //   - userID: идентификатор пользователя
 //   - short: сокращенный URL
 //
@@ -190,7 +232,10 @@ func (s *InMemoryStorage) GetAllByUserID(userID string) ([]objects.Link, error)
 // Особенности:
 //   - Устанавливает original URL в пустую строку
 //   - Сохраняет userID
-func (s *InMemoryStorage) MarkAsDeleted(userID string, short string) error {
+func (s *InMemoryStorage) MarkAsDeleted(ctx context.Context, userID string, short string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.userIDs[short] == userID {
 		s.urls[short] = ""        // Помечаем URL как удаленный
 		s.userIDs[short] = userID // Сохраняем userID
@@ -199,7 +244,400 @@ func (s *InMemoryStorage) MarkAsDeleted(userID string, short string) error {
 	return errors.New("URL not found or user mismatch")
 }
 
+// TransferLinks переносит все ссылки одного пользователя другому
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - fromUserID: прежний владелец ссылок
+//   - toUserID: новый владелец ссылок
+//
+// Возвращает:
+//   - int: количество перенесенных ссылок
+//   - error: всегда nil
+func (s *InMemoryStorage) TransferLinks(ctx context.Context, fromUserID string, toUserID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	moved := 0
+	for short, owner := range s.userIDs {
+		if owner == fromUserID {
+			s.userIDs[short] = toUserID
+			moved++
+		}
+	}
+	return moved, nil
+}
+
 // Ping проверяет доступность хранилища
-func (s *InMemoryStorage) Ping() error {
+func (s *InMemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// InsertAPIKey сохраняет новый API-ключ
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - key: ключ для сохранения (хранится копия)
+//
+// Возвращает:
+//   - error: ErrConflict если ключ с таким ID или хешем уже существует
+func (s *InMemoryStorage) InsertAPIKey(ctx context.Context, key *objects.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, k := range s.keys {
+		if k.ID == key.ID || k.Hash == key.Hash {
+			return ErrConflict
+		}
+	}
+
+	k := *key
+	s.keys[k.ID] = &k
+	return nil
+}
+
+// GetAPIKeyByHash возвращает действующий API-ключ по его хешу
+//
+// Возвращает:
+//   - *objects.APIKey: найденный ключ
+//   - error: ErrAPIKeyNotFound если ключ не найден или отозван
+func (s *InMemoryStorage) GetAPIKeyByHash(ctx context.Context, hash string) (*objects.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.Hash == hash && !k.Revoked {
+			key := *k
+			return &key, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+// GetAPIKeysByUserID возвращает все API-ключи пользователя, включая отозванные
+//
+// Возвращает:
+//   - []objects.APIKey: ключи пользователя, отсортированные по времени создания
+//   - error: всегда nil
+func (s *InMemoryStorage) GetAPIKeysByUserID(ctx context.Context, userID string) ([]objects.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var keys []objects.APIKey
+	for _, k := range s.keys {
+		if k.UserID == userID {
+			keys = append(keys, *k)
+		}
+	}
+	sortAPIKeys(keys)
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает API-ключ пользователя
+//
+// Возвращает:
+//   - error: ErrAPIKeyNotFound если ключ не найден или принадлежит другому пользователю
+func (s *InMemoryStorage) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[id]
+	if !ok || k.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+	k.Revoked = true
 	return nil
 }
+
+// RevokeToken добавляет токен в список отозванных
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - jti: уникальный идентификатор токена
+//   - expiresAt: срок действия токена; после него запись удаляется
+//
+// Возвращает:
+//   - error: всегда nil
+func (s *InMemoryStorage) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purgeRevoked(time.Now())
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+// IsTokenRevoked проверяет, отозван ли токен
+//
+// Возвращает:
+//   - bool: true если токен отозван и его срок действия еще не истек
+//   - error: всегда nil
+func (s *InMemoryStorage) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exp, ok := s.revoked[jti]
+	return ok && time.Now().Before(exp), nil
+}
+
+// purgeRevoked удаляет записи об истекших токенах.
+// Вызывается под блокировкой mu.
+func (s *InMemoryStorage) purgeRevoked(now time.Time) {
+	for jti, exp := range s.revoked {
+		if !now.Before(exp) {
+			delete(s.revoked, jti)
+		}
+	}
+}
+
+// ListLinks возвращает ссылки всех пользователей, подходящие под фильтр
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - filter: условия выборки
+//
+// Возвращает:
+//   - []objects.Link: ссылки, упорядоченные по короткому URL
+//   - error: всегда nil
+func (s *InMemoryStorage) ListLinks(ctx context.Context, filter objects.LinkFilter) ([]objects.Link, error) {
+	s.mu.RLock()
+	links := make([]objects.Link, 0, len(s.urls))
+	for short, original := range s.urls {
+		links = append(links, objects.Link{
+			Short:       short,
+			Original:    original,
+			UserID:      s.userIDs[short],
+			DeletedFlag: original == "",
+			Disabled:    s.disabled[short],
+		})
+	}
+	s.mu.RUnlock()
+
+	return filterLinks(links, filter), nil
+}
+
+// SetLinkDisabled отключает ссылку или восстанавливает отключенную
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - short: сокращенный URL
+//   - disabled: true — отключить, false — восстановить
+//
+// Возвращает:
+//   - error: ErrLinkNotFound если ссылка не существует
+func (s *InMemoryStorage) SetLinkDisabled(ctx context.Context, short string, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.urls[short]; !ok {
+		return ErrLinkNotFound
+	}
+	if disabled {
+		s.disabled[short] = true
+	} else {
+		delete(s.disabled, short)
+	}
+	return nil
+}
+
+// ListUsers возвращает пользователей и количество их ссылок
+//
+// Возвращает:
+//   - []objects.UserStat: пользователи, упорядоченные по убыванию числа ссылок
+//   - error: всегда nil
+func (s *InMemoryStorage) ListUsers(ctx context.Context) ([]objects.UserStat, error) {
+	s.mu.RLock()
+	counts := make(map[string]int)
+	for short := range s.urls {
+		counts[s.userIDs[short]]++
+	}
+	s.mu.RUnlock()
+
+	return userStats(counts), nil
+}
+
+// InsertWebhook сохраняет новую подписку на вебхуки
+//
+// Параметры:
+//   - ctx: контекст выполнения
+//   - hook: подписка для сохранения (хранится копия)
+//
+// Возвращает:
+//   - error: ErrConflict если подписка с таким ID уже существует
+func (s *InMemoryStorage) InsertWebhook(ctx context.Context, hook *objects.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.webhooks[hook.ID]; ok {
+		return ErrConflict
+	}
+	h := *hook
+	h.Events = append([]string(nil), hook.Events...)
+	s.webhooks[h.ID] = &h
+	return nil
+}
+
+// GetWebhooksByUserID возвращает подписки пользователя
+//
+// Возвращает:
+//   - []objects.Webhook: подписки, отсортированные по времени создания
+//   - error: всегда nil
+func (s *InMemoryStorage) GetWebhooksByUserID(ctx context.Context, userID string) ([]objects.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var hooks []objects.Webhook
+	for _, h := range s.webhooks {
+		if h.UserID == userID {
+			hooks = append(hooks, *h)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		return hooks[i].CreatedAt.Before(hooks[j].CreatedAt)
+	})
+	return hooks, nil
+}
+
+// DeleteWebhook удаляет подписку пользователя вместе с историей доставок
+//
+// Возвращает:
+//   - error: ErrWebhookNotFound если подписка не найдена или принадлежит другому пользователю
+func (s *InMemoryStorage) DeleteWebhook(ctx context.Context, userID string, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.webhooks[id]
+	if !ok || h.UserID != userID {
+		return ErrWebhookNotFound
+	}
+	delete(s.webhooks, id)
+	delete(s.deliveries, id)
+	return nil
+}
+
+// SaveDelivery сохраняет доставку вебхука или обновляет существующую с тем же ID
+//
+// Возвращает:
+//   - error: всегда nil
+//
+// Особенности:
+//   - По каждой подписке хранится не больше objects.MaxDeliveriesPerWebhook
+//     доставок; при добавлении новой самая старая удаляется
+func (s *InMemoryStorage) SaveDelivery(ctx context.Context, delivery *objects.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := *delivery
+	list := s.deliveries[d.WebhookID]
+	for i, old := range list {
+		if old.ID == d.ID {
+			list[i] = &d
+			return nil
+		}
+	}
+	list = append(list, &d)
+	if len(list) > objects.MaxDeliveriesPerWebhook {
+		list = slices.Clone(list[len(list)-objects.MaxDeliveriesPerWebhook:])
+	}
+	s.deliveries[d.WebhookID] = list
+	return nil
+}
+
+// GetDeliveries возвращает историю доставок по подписке пользователя
+//
+// Возвращает:
+//   - []objects.WebhookDelivery: доставки, начиная с самых новых
+//   - error: ErrWebhookNotFound если подписка не найдена или принадлежит другому пользователю
+func (s *InMemoryStorage) GetDeliveries(ctx context.Context, userID string, webhookID string) ([]objects.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if h, ok := s.webhooks[webhookID]; !ok || h.UserID != userID {
+		return nil, ErrWebhookNotFound
+	}
+
+	var deliveries []objects.WebhookDelivery
+	for _, d := range s.deliveries[webhookID] {
+		deliveries = append(deliveries, *d)
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+// MarkFirstClick отмечает переход по ссылке
+//
+// Возвращает:
+//   - bool: true если это первый переход по ссылке
+//   - error: всегда nil
+func (s *InMemoryStorage) MarkFirstClick(ctx context.Context, short string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clicked[short] {
+		return false, nil
+	}
+	s.clicked[short] = true
+	return true, nil
+}
+
+// filterLinks применяет фильтр к ссылкам: поиск по подстроке,
+// владелец, сортировка по короткому URL, смещение и лимит
+func filterLinks(links []objects.Link, filter objects.LinkFilter) []objects.Link {
+	query := strings.ToLower(filter.Query)
+
+	result := links[:0]
+	for _, l := range links {
+		if filter.UserID != "" && l.UserID != filter.UserID {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(l.Short), query) &&
+			!strings.Contains(strings.ToLower(l.Original), query) {
+			continue
+		}
+		result = append(result, l)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Short < result[j].Short })
+
+	if filter.Offset >= len(result) {
+		return nil
+	}
+	result = result[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(result) {
+		result = result[:filter.Limit]
+	}
+	return result
+}
+
+// userStats преобразует маппинг userID→количество в отсортированный список
+func userStats(counts map[string]int) []objects.UserStat {
+	stats := make([]objects.UserStat, 0, len(counts))
+	for userID, n := range counts {
+		stats = append(stats, objects.UserStat{UserID: userID, Links: n})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Links != stats[j].Links {
+			return stats[i].Links > stats[j].Links
+		}
+		return stats[i].UserID < stats[j].UserID
+	})
+	return stats
+}
+
+// sortAPIKeys упорядочивает ключи по времени создания
+func sortAPIKeys(keys []objects.APIKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+}
+
+// sortDeliveries упорядочивает доставки от новых к старым
+func sortDeliveries(deliveries []objects.WebhookDelivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if !deliveries[i].CreatedAt.Equal(deliveries[j].CreatedAt) {
+			return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+		}
+		return deliveries[i].ID < deliveries[j].ID
+	})
+}
@@ -54,7 +54,7 @@ func BenchmarkGetOriginal(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = storage.GetOriginal(short)
+		_, _ = storage.GetOriginal(context.Background(), short)
 	}
 }
 
@@ -70,7 +70,7 @@ func BenchmarkGetShort(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = storage.GetShort(original)
+		_, _ = storage.GetShort(context.Background(), original)
 	}
 }
 
@@ -88,7 +88,7 @@ func BenchmarkGetAllByUserID(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = storage.GetAllByUserID(testUser)
+		_, _ = storage.GetAllByUserID(context.Background(), testUser)
 	}
 }
 
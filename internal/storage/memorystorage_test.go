@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Запись и чтение из разных горутин не должны приводить к
+// "concurrent map writes" (проверяется также с -race)
+func TestInMemoryStorage_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	s := NewInMemoryStorage()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				short := fmt.Sprintf("s%d-%d", i, j)
+				assert.NoError(t, s.Insert(ctx, &objects.Link{Short: short, Original: "https://example.com/" + short, UserID: "user"}))
+				assert.NoError(t, s.SetLinkDisabled(ctx, short, true))
+				_, _ = s.GetOriginal(ctx, short)
+				_, _ = s.ListLinks(ctx, objects.LinkFilter{Limit: 10})
+				_, _ = s.ListUsers(ctx)
+				_, _ = s.TransferLinks(ctx, "nobody", "user")
+				assert.NoError(t, s.MarkAsDeleted(ctx, "user", short))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	users, err := s.ListUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, 800, users[0].Links)
+}
+
+func TestInMemoryStorage_DeliveryHistoryLimit(t *testing.T) {
+	ctx := context.Background()
+	s := NewInMemoryStorage()
+	require.NoError(t, s.InsertWebhook(ctx, &objects.Webhook{ID: "h1", UserID: "u1"}))
+
+	start := time.Now()
+	for i := 0; i < objects.MaxDeliveriesPerWebhook+10; i++ {
+		require.NoError(t, s.SaveDelivery(ctx, &objects.WebhookDelivery{
+			ID: fmt.Sprintf("d%03d", i), WebhookID: "h1", UserID: "u1", CreatedAt: start.Add(time.Duration(i) * time.Second),
+		}))
+	}
+	// Обновление существующей записи не вытесняет другие
+	require.NoError(t, s.SaveDelivery(ctx, &objects.WebhookDelivery{
+		ID: "d109", WebhookID: "h1", UserID: "u1", Status: objects.DeliveryDelivered, CreatedAt: start.Add(109 * time.Second),
+	}))
+
+	deliveries, err := s.GetDeliveries(ctx, "u1", "h1")
+	require.NoError(t, err)
+	require.Len(t, deliveries, objects.MaxDeliveriesPerWebhook)
+	assert.Equal(t, "d109", deliveries[0].ID)
+	assert.Equal(t, objects.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, "d010", deliveries[len(deliveries)-1].ID, "самые старые доставки удалены")
+}
@@ -0,0 +1,376 @@
+// Package tlsconfig собирает настройки TLS сервера: минимальную версию
+// протокола, набор шифров и сертификат, который перечитывается с диска
+// при изменении файлов без перезапуска сервера. Для разработки умеет
+// выпускать и кэшировать самоподписанный сертификат.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Параметры самоподписанного сертификата
+const (
+	selfSignedValidity = 365 * 24 * time.Hour
+	// renewBefore — за сколько до истечения кэшированный сертификат перевыпускается
+	renewBefore = 30 * 24 * time.Hour
+	// selfSignedOrg — организация в сертификатах, которые выпускает EnsureSelfSigned
+	selfSignedOrg = "go-shortener self-signed"
+)
+
+// DefaultReloadInterval — период проверки файлов сертификата на изменение
+const DefaultReloadInterval = 10 * time.Second
+
+// Options описывает настройки TLS.
+type Options struct {
+	CertFile     string   // PEM-файл сертификата (цепочки)
+	KeyFile      string   // PEM-файл закрытого ключа
+	MinVersion   string   // минимальная версия: 1.0, 1.1, 1.2 или 1.3
+	CipherSuites []string // имена наборов шифров; пусто — набор Go по умолчанию
+	SelfSigned   bool     // выпустить самоподписанный сертификат, если файлов нет
+	Hosts        []string // имена и IP-адреса для самоподписанного сертификата
+}
+
+// versions сопоставляет строковые версии TLS константам crypto/tls
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseVersion разбирает минимальную версию TLS ("1.2", "1.3" и т.д.).
+func ParseVersion(s string) (uint16, error) {
+	v, ok := versions[strings.TrimSpace(s)]
+	if !ok {
+		return 0, errors.New("must be one of 1.0, 1.1, 1.2, 1.3")
+	}
+	return v, nil
+}
+
+// ParseCipherSuites разбирает имена наборов шифров, например
+// TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256. Небезопасные наборы
+// (tls.InsecureCipherSuites) не принимаются.
+//
+// Особенности:
+//   - Наборы шифров применяются только к TLS 1.0–1.2;
+//     шифры TLS 1.3 в Go не настраиваются
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, cs := range tls.CipherSuites() {
+		known[cs.Name] = cs.ID
+	}
+
+	var ids []uint16
+	var errs []error
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown or insecure cipher suite %q", name))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, errors.Join(errs...)
+}
+
+// New собирает *tls.Config и загрузчик сертификата.
+//
+// Возвращает:
+//   - *tls.Config: конфигурация, берущая сертификат из Reloader
+//   - *Reloader: загрузчик; для отслеживания изменений файлов нужно запустить Watch
+//   - error: ошибка разбора настроек, выпуска или загрузки сертификата
+func New(opts Options) (*tls.Config, *Reloader, error) {
+	minVersion, err := ParseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, nil, fmt.Errorf("tls min version %q: %w", opts.MinVersion, err)
+	}
+	suites, err := ParseCipherSuites(opts.CipherSuites)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.SelfSigned {
+		if err := EnsureSelfSigned(opts.CertFile, opts.KeyFile, opts.Hosts); err != nil {
+			return nil, nil, fmt.Errorf("self-signed certificate: %w", err)
+		}
+	}
+
+	reloader, err := NewReloader(opts.CertFile, opts.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   suites,
+		GetCertificate: reloader.GetCertificate,
+	}, reloader, nil
+}
+
+// Reloader хранит текущий сертификат и перечитывает его с диска,
+// когда меняется время модификации файлов сертификата или ключа.
+// При ошибке загрузки продолжает использоваться прежний сертификат.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // наибольшее время модификации из двух файлов
+}
+
+// NewReloader загружает сертификат и ключ.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate возвращает текущий сертификат; подходит для tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload перечитывает сертификат, если файлы изменились с прошлой загрузки.
+//
+// Возвращает:
+//   - bool: true если сертификат был загружен заново
+//   - error: ошибка чтения файлов или разбора пары сертификат/ключ
+func (r *Reloader) Reload() (bool, error) {
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("load certificate %s: %w", r.certFile, err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch проверяет файлы сертификата каждые interval до отмены ctx.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.Reload()
+			if err != nil {
+				zap.L().Error("Failed to reload TLS certificate, keeping the previous one",
+					zap.String("cert", r.certFile), zap.Error(err))
+				continue
+			}
+			if reloaded {
+				zap.L().Info("TLS certificate reloaded", zap.String("cert", r.certFile))
+			}
+		}
+	}
+}
+
+// latestModTime возвращает наибольшее время модификации файлов
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// EnsureSelfSigned выпускает самоподписанный сертификат для hosts и сохраняет
+// его в certFile и keyFile, если этих файлов нет.
+//
+// Параметры:
+//   - hosts: DNS-имена и IP-адреса; пусто — localhost, 127.0.0.1 и ::1
+//
+// Возвращает:
+//   - error: ошибка выпуска или записи; ошибка, если существует только один
+//     из файлов или пару не удается загрузить
+//
+// Особенности:
+//   - Существующие файлы перезаписываются, только если в них сертификат,
+//     ранее выпущенный этой функцией, и он не покрывает все hosts или
+//     истекает в ближайшие 30 дней. Любой другой сертификат (например,
+//     выданный удостоверяющим центром) используется как есть
+func EnsureSelfSigned(certFile, keyFile string, hosts []string) error {
+	if len(hosts) == 0 {
+		hosts = []string{"localhost", "127.0.0.1", "::1"}
+	}
+
+	certExists, err := fileExists(certFile)
+	if err != nil {
+		return err
+	}
+	keyExists, err := fileExists(keyFile)
+	if err != nil {
+		return err
+	}
+	if certExists || keyExists {
+		if !certExists || !keyExists {
+			return fmt.Errorf("only one of %s and %s exists, refusing to overwrite it", certFile, keyFile)
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("load certificate %s: %w", certFile, err)
+		}
+		if !generatedHere(cert) || coversHosts(cert, hosts) {
+			return nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: hosts[0], Organization: []string{selfSignedOrg}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.MkdirAll(filepath.Dir(f), 0o700); err != nil {
+			return err
+		}
+	}
+	// Ключ записывается первым: Reloader перечитывает пару по времени
+	// модификации, и сертификат без нового ключа не должен попасть в работу
+	if err := writePEM(keyFile, "EC PRIVATE KEY", keyDER, 0o600); err != nil {
+		return err
+	}
+	if err := writePEM(certFile, "CERTIFICATE", der, 0o644); err != nil {
+		return err
+	}
+
+	zap.L().Info("Generated self-signed TLS certificate",
+		zap.String("cert", certFile), zap.Strings("hosts", hosts), zap.Time("not_after", tmpl.NotAfter))
+	return nil
+}
+
+// fileExists проверяет, что файл существует
+func fileExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// generatedHere проверяет, что сертификат самоподписанный и выпущен EnsureSelfSigned
+func generatedHere(cert tls.Certificate) bool {
+	if len(cert.Certificate) != 1 {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false
+	}
+	return slices.Equal(leaf.Subject.Organization, []string{selfSignedOrg}) &&
+		leaf.CheckSignatureFrom(leaf) == nil
+}
+
+// coversHosts проверяет, что сертификат действует еще renewBefore
+// и выписан на все hosts
+func coversHosts(cert tls.Certificate, hosts []string) bool {
+	if len(cert.Certificate) == 0 {
+		return false
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if time.Now().Add(renewBefore).After(leaf.NotAfter) {
+		return false
+	}
+	for _, h := range hosts {
+		if leaf.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// writePEM атомарно записывает блок PEM в файл
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
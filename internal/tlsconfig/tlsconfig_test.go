@@ -0,0 +1,167 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func leaf(t *testing.T, cert *tls.Certificate) *x509.Certificate {
+	t.Helper()
+	l, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return l
+}
+
+func TestNew_SelfSignedIsCached(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{
+		CertFile:     filepath.Join(dir, "certs", "cert.pem"),
+		KeyFile:      filepath.Join(dir, "certs", "key.pem"),
+		MinVersion:   "1.3",
+		CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"},
+		SelfSigned:   true,
+		Hosts:        []string{"sho.rt", "127.0.0.1"},
+	}
+
+	cfg, r, err := New(opts)
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, cfg.CipherSuites)
+
+	cert, err := cfg.GetCertificate(nil)
+	require.NoError(t, err)
+	first := leaf(t, cert)
+	assert.NoError(t, first.VerifyHostname("sho.rt"))
+	assert.NoError(t, first.VerifyHostname("127.0.0.1"))
+
+	info, err := os.Stat(opts.KeyFile)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Повторный запуск использует кэшированный сертификат
+	_, r2, err := New(opts)
+	require.NoError(t, err)
+	cert2, _ := r2.GetCertificate(nil)
+	assert.Equal(t, first.SerialNumber, leaf(t, cert2).SerialNumber)
+
+	// Новый хост требует перевыпуска
+	opts.Hosts = append(opts.Hosts, "new.example.com")
+	require.NoError(t, EnsureSelfSigned(opts.CertFile, opts.KeyFile, opts.Hosts))
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	cert3, _ := r.GetCertificate(nil)
+	assert.NotEqual(t, first.SerialNumber, leaf(t, cert3).SerialNumber)
+	assert.NoError(t, leaf(t, cert3).VerifyHostname("new.example.com"))
+}
+
+// writeCASigned записывает сертификат для host, подписанный отдельным
+// удостоверяющим центром, как это было бы с сертификатом от настоящего УЦ
+func writeCASigned(t *testing.T, certFile, keyFile, host string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestEnsureSelfSigned_KeepsForeignCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeCASigned(t, certFile, keyFile, "sho.rt")
+	before, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+
+	// Сертификат не покрывает хост и скоро истекает, но выпущен не нами
+	require.NoError(t, EnsureSelfSigned(certFile, keyFile, []string{"0.0.0.0"}))
+	after, err := os.ReadFile(certFile)
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// Файл ключа без сертификата не перезаписывается
+	require.NoError(t, os.Remove(certFile))
+	assert.Error(t, EnsureSelfSigned(certFile, keyFile, nil))
+	_, err = os.Stat(certFile)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReloader_KeepsCertificateOnError(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, EnsureSelfSigned(certFile, keyFile, nil))
+
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+	before, _ := r.GetCertificate(nil)
+
+	// Без изменений файлы не перечитываются
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// Поврежденный файл не заменяет рабочий сертификат
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o644))
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	_, err = r.Reload()
+	assert.Error(t, err)
+	after, _ := r.GetCertificate(nil)
+	assert.Same(t, before, after)
+}
+
+func TestParseOptions(t *testing.T) {
+	_, err := ParseVersion("1.4")
+	assert.Error(t, err)
+
+	v, err := ParseVersion("1.2")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), v)
+
+	_, err = ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	assert.Error(t, err, "небезопасные наборы отклоняются")
+
+	ids, err := ParseCipherSuites([]string{"", " TLS_AES_128_GCM_SHA256 "})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_AES_128_GCM_SHA256}, ids)
+
+	_, _, err = New(Options{CertFile: "/nonexistent/cert.pem", KeyFile: "/nonexistent/key.pem", MinVersion: "1.2"})
+	assert.Error(t, err)
+}
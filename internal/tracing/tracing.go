@@ -0,0 +1,270 @@
+// Package tracing реализует распределенную трассировку на OpenTelemetry:
+// прием контекста из заголовка W3C traceparent, спаны для HTTP-запросов,
+// промежуточных обработчиков, обработчиков App, операций хранилища
+// и SQL-запросов к PostgreSQL. Спаны экспортируются по OTLP/HTTP или в stdout.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Экспортеры спанов
+const (
+	ExporterNone   = ""       // трассировка выключена, traceparent только пробрасывается
+	ExporterOTLP   = "otlp"   // OTLP/HTTP коллектор
+	ExporterStdout = "stdout" // JSON в стандартный вывод
+)
+
+// instrumentationName — имя трассировщика сервиса
+const instrumentationName = "github.com/GevorkovG/go-shortener-tlp"
+
+// tracer возвращает трассировщик текущего глобального провайдера;
+// до вызова Init провайдер не записывает спаны
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// propagator читает и записывает контекст трассировки в формате W3C
+var propagator = propagation.TraceContext{}
+
+// Options описывает настройки трассировки.
+type Options struct {
+	Exporter    string    // ExporterNone, ExporterOTLP или ExporterStdout
+	Endpoint    string    // URL коллектора OTLP/HTTP, например http://localhost:4318
+	SampleRatio float64   // доля трассируемых запросов без входящего traceparent
+	ServiceName string    // значение service.name
+	Output      io.Writer // приемник для ExporterStdout, nil — os.Stdout
+}
+
+// Init настраивает глобального провайдера трассировки и формат W3C traceparent.
+//
+// Возвращает:
+//   - func(context.Context) error: выгружает накопленные спаны и останавливает
+//     экспорт; вызывается при завершении работы
+//   - error: ошибка создания экспортера или неизвестный экспортер
+//
+// Особенности:
+//   - С ExporterNone спаны не записываются, но входящий traceparent
+//     передается дальше, например в вебхуки
+//   - Решение о записи трассировки принимается по родительскому спану,
+//     для новых трассировок — с вероятностью SampleRatio
+func Init(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagator)
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(opts.Endpoint))
+	case ExporterStdout:
+		w := opts.Output
+		if w == nil {
+			w = os.Stdout
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", opts.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(opts.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start создает дочерний спан с указанным именем.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// statusWriter запоминает код ответа
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(statusCode int) {
+	if w.status == 0 {
+		w.status = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// routePattern возвращает шаблон маршрута chi, совпавший с запросом
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// Middleware создает серверный спан на каждый запрос. Родительский контекст
+// берется из заголовка traceparent, если клиент его передал. Имя спана —
+// метод и шаблон маршрута chi ("GET /{id}"), ответы 5xx помечаются ошибкой.
+// Должен стоять первым в цепочке middleware, чтобы охватить остальные.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		if route := routePattern(r); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// Step оборачивает middleware спаном с указанным именем. Спан завершается,
+// когда middleware передает запрос дальше (или отвечает сам), поэтому его
+// длительность — время работы только этого middleware. Последующие спаны
+// остаются дочерними для спана запроса, а не для спана шага.
+//
+// Пример:
+//
+//	r.Use(tracing.Step("auth", auth.Middleware))
+func Step(name string, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		handoff := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if s, ok := ctx.Value(stepKey{}).(*step); ok {
+				s.span.End()
+				ctx = trace.ContextWithSpan(ctx, s.parent)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+		wrapped := mw(handoff)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent := trace.SpanFromContext(r.Context())
+			ctx, span := tracer().Start(r.Context(), name)
+			defer span.End()
+
+			ctx = context.WithValue(ctx, stepKey{}, &step{span: span, parent: parent})
+			wrapped.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// stepKey — ключ контекста для текущего шага Step
+type stepKey struct{}
+
+// step хранит спан шага и спан, который станет родительским после него
+type step struct {
+	span   trace.Span
+	parent trace.Span
+}
+
+// Handler создает спан обработчика App. Ставится последним в цепочке
+// middleware: спан охватывает только работу обработчика и получает имя
+// по шаблону маршрута ("handler GET /{id}").
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer().Start(r.Context(), "handler")
+		defer span.End()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		if route := routePattern(r); route != "" {
+			span.SetName("handler " + r.Method + " " + route)
+		}
+	})
+}
+
+// StorageObserver возвращает наблюдателя операций хранилища
+// (см. storage.Instrument), создающего спан на каждый вызов.
+func StorageObserver(backend string) func(ctx context.Context, method string) (context.Context, func(error)) {
+	return func(ctx context.Context, method string) (context.Context, func(error)) {
+		ctx, span := tracer().Start(ctx, "storage."+method, trace.WithAttributes(
+			attribute.String("storage.backend", backend),
+			attribute.String("storage.method", method),
+		))
+		return ctx, func(err error) {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	}
+}
+
+// QueryTracer создает клиентский спан на каждый SQL-запрос pgx с текстом
+// запроса в атрибуте db.query.text. Подключается через pgx.ConnConfig.Tracer.
+type QueryTracer struct{}
+
+// TraceQueryStart начинает спан запроса.
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(data.SQL), " ", 2)[0])
+	ctx, _ = tracer().Start(ctx, "db "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return ctx
+}
+
+// TraceQueryEnd завершает спан запроса.
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	span.End()
+}
@@ -0,0 +1,154 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record подключает провайдера, записывающего завершенные спаны
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return rec
+}
+
+// spanByName ищет завершенный спан по имени
+func spanByName(t *testing.T, rec *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, s := range rec.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	require.Failf(t, "span not found", "%q", name)
+	return nil
+}
+
+func TestMiddleware_ContinuesTraceparent(t *testing.T) {
+	rec := record(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware, Handler)
+	r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	server := spanByName(t, rec, "GET /{id}")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.True(t, server.Parent().IsRemote())
+
+	handler := spanByName(t, rec, "handler GET /{id}")
+	assert.Equal(t, server.SpanContext().SpanID(), handler.Parent().SpanID())
+}
+
+func TestMiddleware_MarksServerErrors(t *testing.T) {
+	rec := record(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	assert.Equal(t, codes.Error, spanByName(t, rec, "GET /ping").Status().Code)
+}
+
+func TestStep_EndsAtHandoff(t *testing.T) {
+	rec := record(t)
+
+	var stepEnded bool
+	r := chi.NewRouter()
+	r.Use(Middleware, Step("auth", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+	}), Handler)
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		stepEnded = len(rec.Ended()) == 1
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.True(t, stepEnded, "step span must end before the handler runs")
+	server := spanByName(t, rec, "GET /")
+	assert.Equal(t, server.SpanContext().SpanID(), spanByName(t, rec, "auth").Parent().SpanID())
+	assert.Equal(t, server.SpanContext().SpanID(), spanByName(t, rec, "handler GET /").Parent().SpanID())
+}
+
+func TestStep_RespondsItself(t *testing.T) {
+	rec := record(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware, Step("csrf", func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}))
+	r.Post("/", func(w http.ResponseWriter, r *http.Request) {})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	assert.Len(t, rec.Ended(), 2)
+	spanByName(t, rec, "csrf")
+}
+
+func TestStorageObserver(t *testing.T) {
+	rec := record(t)
+	observe := StorageObserver("memory")
+
+	ctx, parent := Start(context.Background(), "request")
+	_, done := observe(ctx, "GetOriginal")
+	done(errors.New("not found"))
+	parent.End()
+
+	span := spanByName(t, rec, "storage.GetOriginal")
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Attributes(), attribute.String("storage.backend", "memory"))
+	require.Len(t, span.Events(), 1)
+	assert.Equal(t, "exception", span.Events()[0].Name)
+}
+
+func TestInit(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	_, err := Init(context.Background(), Options{Exporter: "jaeger"})
+	require.Error(t, err)
+
+	var out bytes.Buffer
+	shutdown, err := Init(context.Background(), Options{
+		Exporter:    ExporterStdout,
+		SampleRatio: 1,
+		ServiceName: "shortener",
+		Output:      &out,
+	})
+	require.NoError(t, err)
+
+	_, span := Start(context.Background(), "exported")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Contains(t, out.String(), `"Name":"exported"`)
+	assert.Contains(t, out.String(), "shortener")
+}
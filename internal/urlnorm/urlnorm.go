@@ -0,0 +1,232 @@
+// Package urlnorm проверяет URL перед сокращением и приводит его
+// к канонической форме, чтобы одинаковые адреса, записанные по-разному,
+// сохранялись и находились как один.
+package urlnorm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Ошибки проверки. Тексты ошибок не содержат внутренних данных
+// и передаются клиенту как есть.
+var (
+	ErrEmpty     = errors.New("url is empty")
+	ErrTooLong   = errors.New("url is too long")
+	ErrMalformed = errors.New("url is malformed")
+	ErrScheme    = errors.New("url scheme must be http or https")
+	ErrHost      = errors.New("url must have a valid host")
+)
+
+// Options — настройки канонизации.
+//
+// Шаблоны имен параметров запроса сравниваются без учета регистра
+// по правилам path.Match: utm_* совпадает с utm_source и UTM_Medium.
+// Чтобы оставлять только перечисленные параметры, укажите StripParams: ["*"]
+// и нужные имена в KeepParams.
+type Options struct {
+	MaxLength    int      // максимальная длина URL до и после канонизации, 0 — без ограничения
+	DropFragment bool     // удалять фрагмент (#...)
+	StripParams  []string // шаблоны имен удаляемых параметров запроса
+	KeepParams   []string // шаблоны имен параметров, которые сохраняются, даже если совпадают со StripParams
+	SortQuery    bool     // упорядочивать параметры запроса по имени
+}
+
+// TrackingParams — шаблоны распространенных параметров отслеживания
+var TrackingParams = []string{"utm_*", "fbclid", "gclid", "dclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_hsenc", "_hsmi"}
+
+// ParsePatterns разбирает список шаблонов имен параметров через запятую.
+//
+// Параметры:
+//   - s: шаблоны через запятую, например "utm_*, fbclid"
+//
+// Возвращает:
+//   - []string: шаблоны без пробелов и пустых элементов
+//   - error: шаблон с некорректным синтаксисом path.Match
+func ParsePatterns(s string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("parameter pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+// defaultPorts — порты, которые не указываются в канонической форме
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// Canonicalize проверяет URL и возвращает его каноническую форму.
+//
+// Параметры:
+//   - raw: URL, присланный клиентом
+//   - opts: настройки канонизации
+//
+// Возвращает:
+//   - string: каноническая форма URL
+//   - error: одна из ошибок Err*, дополненная пояснением
+//
+// Особенности:
+//   - Пробельные символы в начале и конце удаляются
+//   - Допускаются только абсолютные URL со схемой http или https и хостом
+//   - Схема и хост приводятся к нижнему регистру, IDN-хост — к punycode
+//   - Порт по умолчанию для схемы (80, 443) удаляется
+//   - Пустой путь заменяется на "/", завершающая точка хоста удаляется
+//   - Параметры запроса удаляются и упорядочиваются по StripParams,
+//     KeepParams и SortQuery (см. rewriteQuery); без этих настроек строка
+//     запроса не меняется
+//   - Путь и учетные данные не меняются
+//
+// Пример:
+//
+//	Canonicalize("  HTTPS://Пример.РФ:443/Path?q=1&utm_source=x#top ", Options{
+//	    DropFragment: true,
+//	    StripParams:  TrackingParams,
+//	})
+//	// "https://xn--e1afmkfd.xn--p1ai/Path?q=1"
+func Canonicalize(raw string, opts Options) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ErrEmpty
+	}
+	if opts.MaxLength > 0 && len(raw) > opts.MaxLength {
+		return "", fmt.Errorf("%w: limit is %d bytes", ErrTooLong, opts.MaxLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", ErrScheme
+	}
+	if u.Opaque != "" {
+		return "", ErrMalformed
+	}
+
+	host, err := canonicalHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	u.Host = host
+
+	if u.Path == "" {
+		u.Path, u.RawPath = "/", ""
+	}
+	if opts.DropFragment {
+		u.Fragment, u.RawFragment = "", ""
+	}
+	if len(opts.StripParams) > 0 || opts.SortQuery {
+		u.RawQuery = rewriteQuery(u.RawQuery, opts)
+		u.ForceQuery = false
+	}
+
+	canonical := u.String()
+	if opts.MaxLength > 0 && len(canonical) > opts.MaxLength {
+		return "", fmt.Errorf("%w: limit is %d bytes", ErrTooLong, opts.MaxLength)
+	}
+	return canonical, nil
+}
+
+// rewriteQuery удаляет параметры, совпадающие со StripParams и не совпадающие
+// с KeepParams, и при SortQuery упорядочивает оставшиеся по имени.
+// Пары "имя=значение" переносятся без перекодирования; порядок значений
+// одного параметра сохраняется, пустые пары удаляются.
+func rewriteQuery(rawQuery string, opts Options) string {
+	type param struct {
+		name string // имя в нижнем регистре после раскодирования
+		raw  string // пара в исходной записи
+	}
+	var params []param
+	for _, raw := range strings.Split(rawQuery, "&") {
+		if raw == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(raw, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		name = strings.ToLower(name)
+		if matchAny(opts.StripParams, name) && !matchAny(opts.KeepParams, name) {
+			continue
+		}
+		params = append(params, param{name: name, raw: raw})
+	}
+	if opts.SortQuery {
+		sort.SliceStable(params, func(i, j int) bool { return params[i].name < params[j].name })
+	}
+
+	pairs := make([]string, len(params))
+	for i, p := range params {
+		pairs[i] = p.raw
+	}
+	return strings.Join(pairs, "&")
+}
+
+// matchAny проверяет имя параметра по шаблонам без учета регистра
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hostProfile — профиль IDNA для имен хостов. В отличие от idna.Lookup
+// не применяет правила STD3, которые запрещают подчеркивание: имена вида
+// my_host.example.com встречаются на практике и разрешаются DNS.
+// Допустимые символы проверяет ToASCIIHost.
+var hostProfile = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.StrictDomainName(false))
+
+// ToASCIIHost приводит имя хоста к punycode в нижнем регистре.
+//
+// Возвращает:
+//   - string: имя хоста из букв, цифр, '-', '_' и '.'
+//   - error: ErrHost для пустого или некорректного имени
+func ToASCIIHost(host string) (string, error) {
+	ascii, err := hostProfile.ToASCII(strings.TrimSuffix(host, "."))
+	if err != nil || ascii == "" {
+		return "", ErrHost
+	}
+	for _, c := range ascii {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return "", ErrHost
+		}
+	}
+	return strings.ToLower(ascii), nil
+}
+
+// canonicalHost приводит имя хоста к нижнему регистру и punycode;
+// IP-адреса возвращаются в стандартной записи
+func canonicalHost(host string) (string, error) {
+	if host == "" {
+		return "", ErrHost
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	return ToASCIIHost(host)
+}
@@ -0,0 +1,123 @@
+package urlnorm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		opts Options
+		want string
+	}{
+		{"already canonical", "https://example.com/path?q=1", Options{}, "https://example.com/path?q=1"},
+		{"whitespace", " \thttps://example.com/a\n", Options{}, "https://example.com/a"},
+		{"case of scheme and host", "HTTP://WWW.Example.COM/Path", Options{}, "http://www.example.com/Path"},
+		{"default http port", "http://example.com:80/a", Options{}, "http://example.com/a"},
+		{"default https port", "https://example.com:443/a", Options{}, "https://example.com/a"},
+		{"other port", "https://example.com:8443/a", Options{}, "https://example.com:8443/a"},
+		{"http port on https", "https://example.com:80/a", Options{}, "https://example.com:80/a"},
+		{"empty path", "https://example.com", Options{}, "https://example.com/"},
+		{"trailing dot", "https://example.com./a", Options{}, "https://example.com/a"},
+		{"underscore in host", "https://My_Host.example.com/a", Options{}, "https://my_host.example.com/a"},
+		{"idn", "https://Пример.РФ/путь", Options{}, "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{"ipv4", "http://127.0.0.1:80/", Options{}, "http://127.0.0.1/"},
+		{"ipv6", "http://[2001:DB8::1]:80/", Options{}, "http://[2001:db8::1]/"},
+		{"ipv6 with port", "http://[2001:db8::1]:8080/", Options{}, "http://[2001:db8::1]:8080/"},
+		{"fragment kept", "https://example.com/a#top", Options{}, "https://example.com/a#top"},
+		{"fragment dropped", "https://example.com/a#top", Options{DropFragment: true}, "https://example.com/a"},
+		{"query untouched", "https://example.com/?b=2&a=1", Options{}, "https://example.com/?b=2&a=1"},
+		{"tracking stripped", "https://example.com/p?id=7&utm_source=x&UTM_Medium=y&fbclid=z&gclid=w",
+			Options{StripParams: TrackingParams}, "https://example.com/p?id=7"},
+		{"only tracking", "https://example.com/p?utm_source=x#top", Options{StripParams: TrackingParams}, "https://example.com/p#top"},
+		{"keep overrides strip", "https://example.com/?utm_campaign=a&utm_source=b",
+			Options{StripParams: []string{"utm_*"}, KeepParams: []string{"utm_campaign"}}, "https://example.com/?utm_campaign=a"},
+		{"allowlist", "https://example.com/watch?v=1&t=30&feature=share&si=abc",
+			Options{StripParams: []string{"*"}, KeepParams: []string{"v", "t"}}, "https://example.com/watch?v=1&t=30"},
+		{"sorted", "https://example.com/?b=2&a=1&b=1&c", Options{SortQuery: true}, "https://example.com/?a=1&b=2&b=1&c"},
+		{"encoding preserved", "https://example.com/?q=a%20b+c&utm%5Fsource=x&&", Options{StripParams: TrackingParams, SortQuery: true},
+			"https://example.com/?q=a%20b+c"},
+		{"empty query dropped", "https://example.com/?", Options{SortQuery: true}, "https://example.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(tt.raw, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			again, err := Canonicalize(got, tt.opts)
+			require.NoError(t, err)
+			assert.Equal(t, got, again, "canonical form must be stable")
+		})
+	}
+}
+
+func TestCanonicalize_SameForm(t *testing.T) {
+	a, err := Canonicalize("HTTPS://Example.com:443", Options{})
+	require.NoError(t, err)
+	b, err := Canonicalize(" https://example.com/ ", Options{})
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+}
+
+func TestCanonicalize_SameFormAfterRewrite(t *testing.T) {
+	opts := Options{StripParams: TrackingParams, SortQuery: true}
+	a, err := Canonicalize("https://example.com/page?b=2&a=1&utm_source=newsletter", opts)
+	require.NoError(t, err)
+	b, err := Canonicalize("https://example.com/page?fbclid=abc&a=1&b=2", opts)
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+}
+
+func TestParsePatterns(t *testing.T) {
+	patterns, err := ParsePatterns(strings.Join(TrackingParams, ","))
+	require.NoError(t, err)
+	assert.Equal(t, TrackingParams, patterns)
+
+	patterns, err = ParsePatterns(" utm_* , ,fbclid,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"utm_*", "fbclid"}, patterns)
+
+	patterns, err = ParsePatterns("")
+	require.NoError(t, err)
+	assert.Empty(t, patterns)
+
+	_, err = ParsePatterns("x,utm_[")
+	assert.ErrorContains(t, err, `"utm_["`)
+}
+
+func TestCanonicalize_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		opts Options
+		want error
+	}{
+		{"empty", "", Options{}, ErrEmpty},
+		{"spaces", "   ", Options{}, ErrEmpty},
+		{"javascript", "javascript:alert(1)", Options{}, ErrScheme},
+		{"data", "data:text/html,<script>", Options{}, ErrScheme},
+		{"ftp", "ftp://example.com/file", Options{}, ErrScheme},
+		{"relative path", "/some/path", Options{}, ErrScheme},
+		{"no scheme", "example.com/page", Options{}, ErrScheme},
+		{"opaque", "http:example.com", Options{}, ErrMalformed},
+		{"no host", "https:///path", Options{}, ErrHost},
+		{"bad host", "https://exa mple.com/", Options{}, ErrMalformed},
+		{"invalid idn", "https://xn--a.com/", Options{}, ErrHost},
+		{"punctuation in host", "https://ex!ample.com/", Options{}, ErrHost},
+		{"control character", "https://example.com/\x7f", Options{}, ErrMalformed},
+		{"too long", "https://example.com/" + strings.Repeat("a", 100), Options{MaxLength: 50}, ErrTooLong},
+		{"too long after punycode", "https://пример.рф/", Options{MaxLength: 28}, ErrTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Canonicalize(tt.raw, tt.opts)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
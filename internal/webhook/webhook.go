@@ -0,0 +1,589 @@
+// Package webhook реализует исходящие вебхуки: подписанные POST-запросы
+// на адреса, указанные пользователем, при создании, удалении ссылок
+// и первом переходе по ним.
+//
+// Доставка выполняется асинхронно с повторными попытками и экспоненциальной
+// задержкой. Каждая попытка сохраняется в истории доставок (по каждой подписке
+// хранятся последние objects.MaxDeliveriesPerWebhook); событие, которое
+// не удалось доставить за все попытки, остается в хранилище со статусом
+// objects.DeliveryDead (dead-letter) и может быть отправлено повторно.
+// Повторные попытки для удаленной подписки не выполняются.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	logg "github.com/GevorkovG/go-shortener-tlp/internal/log"
+	"github.com/GevorkovG/go-shortener-tlp/internal/netguard"
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Типы событий
+const (
+	EventLinkCreated      = "link.created"
+	EventLinkDeleted      = "link.deleted"
+	EventLinkFirstClicked = "link.first_clicked"
+)
+
+// Events — все поддерживаемые типы событий
+var Events = []string{EventLinkCreated, EventLinkDeleted, EventLinkFirstClicked}
+
+// Заголовки запроса доставки
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// SecretPrefix — префикс секрета подписи
+const SecretPrefix = "whsec_"
+
+// Настройки доставки по умолчанию
+const (
+	defaultQueueSize   = 1024
+	defaultWorkers     = 4
+	defaultMaxAttempts = 5
+	defaultBackoff     = time.Second
+	maxBackoff         = time.Minute
+	requestTimeout     = 10 * time.Second
+	maxClicked         = 100000
+)
+
+// ErrInvalidURL возвращается для адреса подписки, не являющегося абсолютным http(s) URL
+var ErrInvalidURL = errors.New("webhook url must be an absolute http or https url")
+
+// ErrPrivateURL возвращается для адреса подписки, указывающего во внутреннюю сеть
+var ErrPrivateURL = errors.New("webhook url must not point to a loopback, private or link-local address")
+
+// ErrUnknownEvent возвращается для неподдерживаемого типа события
+var ErrUnknownEvent = errors.New("unknown webhook event")
+
+// Link описывает ссылку в теле события.
+type Link struct {
+	Short    string `json:"short_url"`
+	Original string `json:"original_url,omitempty"`
+}
+
+// Payload — тело запроса доставки.
+type Payload struct {
+	ID        string    `json:"id"`         //Идентификатор доставки
+	Event     string    `json:"event"`      //Тип события
+	CreatedAt time.Time `json:"created_at"` //Время события
+	Links     []Link    `json:"links"`      //Ссылки, к которым относится событие
+}
+
+// NewSecret генерирует секрет подписи для новой подписки.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return SecretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign возвращает подпись тела запроса в формате "sha256=<hex HMAC-SHA256>".
+// Получатель проверяет заголовок X-Webhook-Signature, вычисляя ту же подпись
+// с секретом подписки.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateURL проверяет адрес подписки.
+//
+// Параметры:
+//   - ctx: контекст запроса; ограничивает время разрешения имени хоста
+//   - raw: адрес подписки
+//   - allowPrivate: разрешить адреса внутренних сетей
+//
+// Возвращает:
+//   - error: ErrInvalidURL для некорректного адреса или хоста, который
+//     не удалось разрешить; ErrPrivateURL, если хост разрешается
+//     в loopback, частный, link-local или CGNAT адрес
+//
+// Особенности:
+//   - Тот же запрет действует при каждой доставке (см. WithPrivateNetworks),
+//     поэтому смена DNS-записи после регистрации его не обходит
+func ValidateURL(ctx context.Context, raw string, allowPrivate bool) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if allowPrivate {
+		return nil
+	}
+	if err := netguard.CheckHost(ctx, u.Hostname()); err != nil {
+		if errors.Is(err, netguard.ErrPrivate) {
+			return ErrPrivateURL
+		}
+		return fmt.Errorf("%w: cannot resolve host %s", ErrInvalidURL, u.Hostname())
+	}
+	return nil
+}
+
+// NormalizeEvents проверяет список событий подписки.
+// Пустой список означает подписку на все события.
+func NormalizeEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return slices.Clone(Events), nil
+	}
+	result := make([]string, 0, len(events))
+	for _, e := range events {
+		if !slices.Contains(Events, e) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, e)
+		}
+		if !slices.Contains(result, e) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// job — единица работы очереди: публикация события, повторная отправка
+// из истории (Redeliver) или очередная попытка доставки после задержки
+type job struct {
+	userID   string
+	event    string
+	links    []Link
+	redo     *objects.WebhookDelivery
+	redoHook *objects.Webhook
+	retry    bool          // продолжение доставки: счетчик попыток не сбрасывается
+	backoff  time.Duration // задержка перед следующей попыткой
+}
+
+// Dispatcher публикует события и доставляет их подписчикам.
+//
+// Нулевой указатель *Dispatcher допустим: Publish и Close ничего не делают.
+type Dispatcher struct {
+	store        objects.WebhookStorage
+	client       *http.Client
+	allowPrivate bool
+	queue        chan job
+	workers      int
+	maxAttempts  int
+	backoff      time.Duration
+	done         chan struct{}
+	wg           sync.WaitGroup // рабочие горутины
+	pending      sync.WaitGroup // задачи в очереди и запланированные попытки
+
+	mu     sync.RWMutex // защищает closed
+	closed bool
+
+	clickMu sync.Mutex          // защищает clicked
+	clicked map[string]struct{} // ссылки, для которых уже опубликован первый переход
+}
+
+// Option настраивает Dispatcher
+type Option func(*Dispatcher)
+
+// WithRetry задает количество попыток доставки и начальную задержку между ними.
+// Задержка удваивается после каждой неудачи (но не больше минуты).
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = attempts
+		d.backoff = backoff
+	}
+}
+
+// WithClient задает HTTP-клиент для доставки. Запрет адресов внутренних
+// сетей обеспечивает клиент по умолчанию; для переданного клиента
+// он не действует.
+func WithClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithPrivateNetworks разрешает доставку на адреса внутренних сетей.
+// По умолчанию соединения с loopback, частными, link-local и CGNAT
+// адресами отклоняются.
+func WithPrivateNetworks() Option {
+	return func(d *Dispatcher) {
+		d.allowPrivate = true
+	}
+}
+
+// newClient создает HTTP-клиент доставки. Без allowPrivate соединение
+// с внутренним адресом отклоняется после разрешения имени, в том числе
+// при перенаправлениях.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowPrivate {
+		dialer.Control = netguard.Control
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// Через прокси проверялся бы адрес прокси, а не получателя
+	transport.Proxy = nil
+	return &http.Client{Timeout: requestTimeout, Transport: transport}
+}
+
+// NewDispatcher создает Dispatcher и запускает горутины доставки.
+//
+// Параметры:
+//   - store: хранилище подписок и истории доставок
+//   - opts: дополнительные настройки
+func NewDispatcher(store objects.WebhookStorage, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		queue:       make(chan job, defaultQueueSize),
+		workers:     defaultWorkers,
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultBackoff,
+		done:        make(chan struct{}),
+		clicked:     make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.client == nil {
+		d.client = newClient(d.allowPrivate)
+	}
+
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for {
+				select {
+				case j := <-d.queue:
+					d.process(j)
+					d.pending.Done()
+				case <-d.done:
+					return
+				}
+			}
+		}()
+	}
+	return d
+}
+
+// Publish ставит событие в очередь на доставку подписчикам пользователя.
+// Никогда не блокирует: при переполненной очереди событие отбрасывается
+// с записью в лог.
+//
+// Для EventLinkFirstClicked событие публикуется только для ссылок,
+// по которым еще не было переходов. Повторные переходы отсекаются
+// в памяти до постановки в очередь, поэтому частые переходы по одной
+// ссылке не занимают очередь и не вытесняют другие события.
+func (d *Dispatcher) Publish(userID string, event string, links []Link) {
+	if d == nil || userID == "" || len(links) == 0 {
+		return
+	}
+	if event == EventLinkFirstClicked {
+		if links = d.claimClicks(links); len(links) == 0 {
+			return
+		}
+	}
+	if !d.enqueue(job{userID: userID, event: event, links: links}) && event == EventLinkFirstClicked {
+		d.releaseClicks(links)
+	}
+}
+
+// claimClicks оставляет ссылки, для которых первый переход еще не публиковался,
+// и запоминает их. Окончательное решение принимает MarkFirstClick хранилища;
+// набор в памяти ограничен maxClicked и при переполнении очищается.
+func (d *Dispatcher) claimClicks(links []Link) []Link {
+	d.clickMu.Lock()
+	defer d.clickMu.Unlock()
+
+	if len(d.clicked) >= maxClicked {
+		clear(d.clicked)
+	}
+	result := links[:0:0]
+	for _, l := range links {
+		if _, ok := d.clicked[l.Short]; ok {
+			continue
+		}
+		d.clicked[l.Short] = struct{}{}
+		result = append(result, l)
+	}
+	return result
+}
+
+// releaseClicks забывает ссылки, событие для которых не удалось обработать,
+// чтобы следующий переход опубликовал его снова
+func (d *Dispatcher) releaseClicks(links []Link) {
+	d.clickMu.Lock()
+	defer d.clickMu.Unlock()
+
+	for _, l := range links {
+		delete(d.clicked, l.Short)
+	}
+}
+
+// Redeliver повторно отправляет сохраненную доставку (например, из dead-letter).
+// Счетчик попыток начинается заново, история обновляется в той же записи.
+func (d *Dispatcher) Redeliver(hook objects.Webhook, delivery objects.WebhookDelivery) {
+	if d == nil {
+		return
+	}
+	d.enqueue(job{redo: &delivery, redoHook: &hook})
+}
+
+// enqueue добавляет задачу в очередь без блокировки.
+// Возвращает false, если задача отброшена.
+func (d *Dispatcher) enqueue(j job) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return false
+	}
+
+	if !d.push(j) {
+		zap.L().Warn("Webhook queue is full, event dropped",
+			logg.UserID(j.userID),
+			zap.String("event", j.event))
+		return false
+	}
+	return true
+}
+
+// push кладет задачу в очередь без блокировки и учитывает ее в pending
+func (d *Dispatcher) push(j job) bool {
+	d.pending.Add(1)
+	select {
+	case d.queue <- j:
+		return true
+	default:
+		d.pending.Done()
+		return false
+	}
+}
+
+// schedule ставит задачу в очередь через delay. Обработчик не ждет задержку,
+// поэтому недоступные получатели не задерживают доставку остальным.
+// При переполненной очереди попытка откладывается еще на delay.
+func (d *Dispatcher) schedule(j job, delay time.Duration) {
+	d.pending.Add(1)
+	time.AfterFunc(delay, func() {
+		defer d.pending.Done()
+		select {
+		case <-d.done:
+			return
+		default:
+		}
+		if !d.push(j) {
+			d.schedule(j, delay)
+		}
+	})
+}
+
+// Close прекращает прием событий и дожидается доставки очереди, включая
+// запланированные повторные попытки (не дольше, чем позволяет ctx).
+// Недоставленные к этому моменту события остаются в истории со статусом pending.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	d.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		close(d.done)
+		d.wg.Wait()
+		return nil
+	case <-ctx.Done():
+		close(d.done)
+		return ctx.Err()
+	}
+}
+
+// process выполняет одну задачу очереди
+func (d *Dispatcher) process(j job) {
+	ctx := context.Background()
+
+	if j.redo != nil {
+		hook := *j.redoHook
+		if j.retry {
+			// Подписку могли удалить или изменить, пока попытка ждала в очереди
+			current, ok := d.currentHook(ctx, hook)
+			if !ok {
+				return
+			}
+			hook = current
+		} else {
+			j.redo.Status = objects.DeliveryPending
+			j.redo.Attempts = 0
+			j.backoff = d.backoff
+		}
+		d.deliver(ctx, hook, j.redo, j.backoff)
+		return
+	}
+
+	if j.event == EventLinkFirstClicked {
+		first := j.links[:0:0]
+		for _, l := range j.links {
+			ok, err := d.store.MarkFirstClick(ctx, l.Short)
+			if err != nil {
+				zap.L().Error("Failed to mark first click", zap.String("short", l.Short), zap.Error(err))
+				d.releaseClicks([]Link{l})
+				continue
+			}
+			if ok {
+				first = append(first, l)
+			}
+		}
+		if len(first) == 0 {
+			return
+		}
+		j.links = first
+	}
+
+	hooks, err := d.store.GetWebhooksByUserID(ctx, j.userID)
+	if err != nil {
+		zap.L().Error("Failed to get webhooks", logg.UserID(j.userID), zap.Error(err))
+		return
+	}
+
+	for _, hook := range hooks {
+		if !slices.Contains(hook.Events, j.event) {
+			continue
+		}
+
+		now := time.Now().UTC()
+		delivery := &objects.WebhookDelivery{
+			ID:        uuid.New().String(),
+			WebhookID: hook.ID,
+			UserID:    hook.UserID,
+			Event:     j.event,
+			Status:    objects.DeliveryPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		body, err := json.Marshal(Payload{ID: delivery.ID, Event: j.event, CreatedAt: now, Links: j.links})
+		if err != nil {
+			zap.L().Error("Failed to encode webhook payload", zap.Error(err))
+			continue
+		}
+		delivery.Payload = string(body)
+
+		d.save(ctx, delivery)
+		d.deliver(ctx, hook, delivery, d.backoff)
+	}
+}
+
+// currentHook возвращает актуальное состояние подписки из хранилища.
+// Возвращает false, если подписка удалена: повторная попытка в этом случае
+// не выполняется. При ошибке хранилища используется прежнее состояние.
+func (d *Dispatcher) currentHook(ctx context.Context, hook objects.Webhook) (objects.Webhook, bool) {
+	hooks, err := d.store.GetWebhooksByUserID(ctx, hook.UserID)
+	if err != nil {
+		zap.L().Error("Failed to get webhooks", logg.UserID(hook.UserID), zap.Error(err))
+		return hook, true
+	}
+	for _, h := range hooks {
+		if h.ID == hook.ID {
+			return h, true
+		}
+	}
+	zap.L().Info("Webhook deleted, retry dropped", zap.String("webhookID", hook.ID))
+	return objects.Webhook{}, false
+}
+
+// deliver выполняет очередную попытку доставки и сохраняет состояние в историю.
+// При неудаче следующая попытка планируется через backoff, после чего
+// задержка удваивается (но не больше maxBackoff).
+func (d *Dispatcher) deliver(ctx context.Context, hook objects.Webhook, delivery *objects.WebhookDelivery, backoff time.Duration) {
+	delivery.Attempts++
+	code, err := d.send(ctx, hook, delivery)
+	delivery.ResponseCode = code
+	delivery.UpdatedAt = time.Now().UTC()
+
+	if err == nil {
+		delivery.Status = objects.DeliveryDelivered
+		delivery.LastError = ""
+		d.save(ctx, delivery)
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = objects.DeliveryDead
+		d.save(ctx, delivery)
+		zap.L().Error("Webhook delivery moved to dead-letter",
+			zap.String("webhookID", hook.ID),
+			zap.String("deliveryID", delivery.ID),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Error(err))
+		return
+	}
+	d.save(ctx, delivery)
+
+	next := min(backoff*2, maxBackoff)
+	d.schedule(job{userID: hook.UserID, event: delivery.Event, redo: delivery, redoHook: &hook, retry: true, backoff: next}, backoff)
+}
+
+// send выполняет одну попытку доставки
+//
+// Возвращает:
+//   - int: HTTP-код ответа (0, если ответ не получен)
+//   - error: ошибка сети или ответ с кодом, отличным от 2xx
+func (d *Dispatcher) send(ctx context.Context, hook objects.Webhook, delivery *objects.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// save сохраняет состояние доставки, ошибки только логируются
+func (d *Dispatcher) save(ctx context.Context, delivery *objects.WebhookDelivery) {
+	if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+		zap.L().Error("Failed to save webhook delivery",
+			zap.String("deliveryID", delivery.ID),
+			zap.Error(err))
+	}
+}
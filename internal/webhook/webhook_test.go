@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GevorkovG/go-shortener-tlp/internal/objects"
+	"github.com/GevorkovG/go-shortener-tlp/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitDeliveries ждет, пока у подписки появится доставка в одном из конечных статусов
+func waitDeliveries(t *testing.T, store objects.WebhookStorage, userID, hookID string) []objects.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		deliveries, err := store.GetDeliveries(context.Background(), userID, hookID)
+		require.NoError(t, err)
+		if len(deliveries) > 0 && deliveries[0].Status != objects.DeliveryPending {
+			return deliveries
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("доставка не завершилась")
+	return nil
+}
+
+func TestDispatcher_SignedDelivery(t *testing.T) {
+	const secret = "whsec_test"
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	store := storage.NewInMemoryStorage()
+	hook := &objects.Webhook{ID: "h1", UserID: "u1", URL: srv.URL, Secret: secret, Events: []string{EventLinkCreated}}
+	require.NoError(t, store.InsertWebhook(context.Background(), hook))
+
+	d := NewDispatcher(store, WithPrivateNetworks())
+	defer d.Close(context.Background())
+
+	// Событие без подписки не отправляется
+	d.Publish("u1", EventLinkDeleted, []Link{{Short: "http://localhost/x"}})
+	d.Publish("u1", EventLinkCreated, []Link{{Short: "http://localhost/abc", Original: "https://example.com"}})
+
+	r := <-received
+	body := <-bodies
+	assert.Equal(t, EventLinkCreated, r.Header.Get(HeaderEvent))
+	assert.Equal(t, Sign(secret, body), r.Header.Get(HeaderSignature))
+
+	var p Payload
+	require.NoError(t, json.Unmarshal(body, &p))
+	assert.Equal(t, r.Header.Get(HeaderDelivery), p.ID)
+	assert.Equal(t, []Link{{Short: "http://localhost/abc", Original: "https://example.com"}}, p.Links)
+
+	deliveries := waitDeliveries(t, store, "u1", "h1")
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, objects.DeliveryDelivered, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].ResponseCode)
+}
+
+func TestDispatcher_DeadLetter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	store := storage.NewInMemoryStorage()
+	hook := &objects.Webhook{ID: "h1", UserID: "u1", URL: srv.URL, Secret: "s", Events: Events}
+	require.NoError(t, store.InsertWebhook(context.Background(), hook))
+
+	d := NewDispatcher(store, WithPrivateNetworks(), WithRetry(3, time.Millisecond))
+	defer d.Close(context.Background())
+
+	d.Publish("u1", EventLinkDeleted, []Link{{Short: "http://localhost/abc"}})
+
+	deliveries := waitDeliveries(t, store, "u1", "h1")
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, objects.DeliveryDead, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].ResponseCode)
+	assert.NotEmpty(t, deliveries[0].LastError)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestDispatcher_RetryStopsAfterDelete(t *testing.T) {
+	store := storage.NewInMemoryStorage()
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Подписку удаляют, пока доставка ждет повторной попытки
+		if calls.Add(1) == 1 {
+			assert.NoError(t, store.DeleteWebhook(ctx, "u1", "h1"))
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, store.InsertWebhook(ctx, &objects.Webhook{ID: "h1", UserID: "u1", URL: srv.URL, Secret: "s", Events: Events}))
+
+	d := NewDispatcher(store, WithPrivateNetworks(), WithRetry(5, time.Millisecond))
+	d.Publish("u1", EventLinkDeleted, []Link{{Short: "http://localhost/abc"}})
+
+	closeCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, d.Close(closeCtx))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDispatcher_RetryDoesNotBlockWorkers(t *testing.T) {
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dead.Close()
+	received := make(chan struct{}, 1)
+	live := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer live.Close()
+
+	store := storage.NewInMemoryStorage()
+	ctx := context.Background()
+	require.NoError(t, store.InsertWebhook(ctx, &objects.Webhook{ID: "h1", UserID: "u1", URL: dead.URL, Secret: "s", Events: Events}))
+	require.NoError(t, store.InsertWebhook(ctx, &objects.Webhook{ID: "h2", UserID: "u2", URL: live.URL, Secret: "s", Events: Events}))
+
+	d := NewDispatcher(store, WithPrivateNetworks(), WithRetry(5, time.Hour))
+	// Больше событий, чем обработчиков: задержка перед повтором не должна их занимать
+	for i := 0; i < 2*defaultWorkers; i++ {
+		d.Publish("u1", EventLinkCreated, []Link{{Short: "http://localhost/abc"}})
+	}
+	d.Publish("u2", EventLinkCreated, []Link{{Short: "http://localhost/xyz"}})
+
+	select {
+	case <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("доставка заблокирована повторными попытками")
+	}
+
+	// Запланированные попытки не дают завершить Close раньше срока
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.Close(closeCtx), context.DeadlineExceeded)
+}
+
+func TestDispatcher_FirstClickOnce(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	store := storage.NewInMemoryStorage()
+	hook := &objects.Webhook{ID: "h1", UserID: "u1", URL: srv.URL, Secret: "s", Events: []string{EventLinkFirstClicked}}
+	require.NoError(t, store.InsertWebhook(context.Background(), hook))
+
+	d := NewDispatcher(store, WithPrivateNetworks())
+	for i := 0; i < 3; i++ {
+		d.Publish("u1", EventLinkFirstClicked, []Link{{Short: "http://localhost/abc"}})
+	}
+	require.NoError(t, d.Close(context.Background()))
+
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestDispatcher_ClaimClicks(t *testing.T) {
+	d := &Dispatcher{clicked: make(map[string]struct{})}
+	abc := Link{Short: "http://localhost/abc"}
+	xyz := Link{Short: "http://localhost/xyz"}
+
+	assert.Equal(t, []Link{abc}, d.claimClicks([]Link{abc}))
+	// Повторный переход не ставится в очередь
+	assert.Empty(t, d.claimClicks([]Link{abc}))
+	assert.Equal(t, []Link{xyz}, d.claimClicks([]Link{abc, xyz}))
+
+	// После неудачной обработки следующий переход публикуется снова
+	d.releaseClicks([]Link{abc})
+	assert.Equal(t, []Link{abc}, d.claimClicks([]Link{abc}))
+}
+
+func TestNormalizeEvents(t *testing.T) {
+	events, err := NormalizeEvents(nil)
+	require.NoError(t, err)
+	assert.Equal(t, Events, events)
+
+	events, err = NormalizeEvents([]string{EventLinkDeleted, EventLinkDeleted})
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventLinkDeleted}, events)
+
+	_, err = NormalizeEvents([]string{"link.updated"})
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+}
+
+func TestValidateURL(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, ValidateURL(ctx, "https://93.184.215.14/hook", false))
+	assert.ErrorIs(t, ValidateURL(ctx, "ftp://example.com", false), ErrInvalidURL)
+	assert.ErrorIs(t, ValidateURL(ctx, "/relative", false), ErrInvalidURL)
+
+	for _, raw := range []string{"http://127.0.0.1:8080/", "http://169.254.169.254/latest", "http://[::1]/", "http://10.0.0.5/", "http://localhost/"} {
+		assert.ErrorIs(t, ValidateURL(ctx, raw, false), ErrPrivateURL, raw)
+		assert.NoError(t, ValidateURL(ctx, raw, true), raw)
+	}
+}
+
+// Доставка на внутренний адрес отклоняется при соединении, даже если
+// подписка уже сохранена (например, DNS-запись изменилась после регистрации)
+func TestDispatcher_RefusesPrivateAddress(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	store := storage.NewInMemoryStorage()
+	hook := &objects.Webhook{ID: "h1", UserID: "u1", URL: srv.URL, Secret: "s", Events: Events}
+	require.NoError(t, store.InsertWebhook(context.Background(), hook))
+
+	d := NewDispatcher(store, WithRetry(1, time.Millisecond))
+	defer d.Close(context.Background())
+
+	d.Publish("u1", EventLinkCreated, []Link{{Short: "http://localhost/abc"}})
+
+	deliveries := waitDeliveries(t, store, "u1", "h1")
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, objects.DeliveryDead, deliveries[0].Status)
+	assert.Contains(t, deliveries[0].LastError, "private")
+	assert.Zero(t, calls.Load())
+}